@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"text/tabwriter"
+
+	"github.com/manifoldco/promptui"
+	"github.com/mateopresacastro/qstnnr/pkg/api"
+	"github.com/mateopresacastro/qstnnr/pkg/report"
+	"github.com/mateopresacastro/qstnnr/pkg/store"
+)
+
+// adaptiveOutput is the result of an adaptive quiz, with the answers in the
+// order the questions were asked.
+type adaptiveOutput struct {
+	Correct int32 `json:"correct" yaml:"correct"`
+	Total   int32 `json:"total" yaml:"total"`
+	// Ability is on the scale of the difficulties, with 0 for medium questions
+	// and one point for every difficulty level. Level rounds it to a level.
+	Ability       float64         `json:"ability" yaml:"ability"`
+	StandardError float64         `json:"standard_error" yaml:"standard_error"`
+	Level         int             `json:"level" yaml:"level"`
+	Answers       []report.Answer `json:"answers" yaml:"answers"`
+}
+
+// takeAdaptive takes an adaptive quiz: the server chooses every question after
+// the answer to the previous one, and estimates the ability of the caller
+// instead of just counting the correct answers.
+func (c *CLI) takeAdaptive(ctx context.Context, maxQuestions int) error {
+	res, err := c.client.StartAdaptiveQuiz(ctx, &api.StartAdaptiveQuizRequest{MaxQuestions: int32(maxQuestions)})
+	if err != nil {
+		return describeError(err)
+	}
+
+	asked := make(map[int32]*api.Question)
+	for res.Question != nil {
+		q := res.Question
+		asked[q.Id] = q
+		items := make([]string, 0, len(q.Options))
+		for _, opt := range q.Options {
+			items = append(items, opt.Text)
+		}
+
+		header := fmt.Sprintf("\nQuestion %d of %d", res.Asked, res.Total)
+		if q.Difficulty > 0 {
+			header += fmt.Sprintf(", difficulty %d of %d", q.Difficulty, store.MaxDifficulty)
+		}
+		fmt.Println(header)
+		index, err := c.ui.choose(choice{
+			label:    q.Text,
+			items:    items,
+			current:  -1,
+			selected: fmt.Sprintf("Question %d: {{ . }}", res.Asked),
+		})
+		if errors.Is(err, promptui.ErrInterrupt) || errors.Is(err, promptui.ErrEOF) {
+			return errors.New("adaptive quiz interrupted, run `qstnnr take --adaptive` to start a new one")
+		}
+		if err != nil {
+			return fmt.Errorf("prompt failed: %v", err)
+		}
+
+		res, err = c.client.AnswerAdaptiveQuestion(ctx, &api.AnswerAdaptiveQuestionRequest{
+			AttemptId: res.AttemptId,
+			Answer:    &api.Answer{QuestionId: q.Id, OptionId: q.Options[index].Id},
+		})
+		if err != nil {
+			return describeError(err)
+		}
+	}
+
+	out := newAdaptiveOutput(asked, res)
+	if c.output != outputTable {
+		return render(os.Stdout, c.output, out, adaptiveTable(out, asked, c.ui))
+	}
+	fmt.Println()
+	if err := render(os.Stdout, c.output, out, adaptiveTable(out, asked, c.ui)); err != nil {
+		return err
+	}
+	hist, err := c.scoreHistogram(ctx, "adaptive", res.Correct)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: couldn't get the distribution of the scores: %v\n", err)
+		return nil
+	}
+	fmt.Printf("\n%s", hist)
+	return nil
+}
+
+func newAdaptiveOutput(asked map[int32]*api.Question, res *api.AdaptiveQuizResponse) adaptiveOutput {
+	out := adaptiveOutput{
+		Correct:       res.Correct,
+		Total:         res.Total,
+		Ability:       res.Ability,
+		StandardError: res.StandardError,
+		Level:         abilityLevel(res.Ability),
+	}
+	solutions := make(map[int32]*api.Solution, len(res.Solutions))
+	for _, s := range res.Solutions {
+		solutions[s.Question.Id] = s
+	}
+	for _, a := range res.Answers {
+		s := solutions[a.QuestionId]
+		answer := report.Answer{
+			QuestionID: a.QuestionId,
+			OptionID:   a.OptionId,
+			Answer:     "Unknown option",
+		}
+		if q := asked[a.QuestionId]; q != nil {
+			answer.Question = q.Text
+			answer.Answer = findOptionText(q.Options, a.OptionId)
+		}
+		if s != nil {
+			answer.CorrectOptionID = s.CorrectOptionId
+			answer.CorrectAnswer = s.CorrectOptionText
+			answer.IsCorrect = a.OptionId == s.CorrectOptionId
+			answer.Explanation = s.Explanation
+		}
+		out.Answers = append(out.Answers, answer)
+	}
+	return out
+}
+
+// abilityLevel returns the difficulty level closest to an ability.
+func abilityLevel(ability float64) int {
+	level := int(math.Round(ability)) + int(store.MediumDifficulty)
+	return min(max(level, int(store.MinDifficulty)), int(store.MaxDifficulty))
+}
+
+func adaptiveTable(out adaptiveOutput, asked map[int32]*api.Question, u *ui) func(w *tabwriter.Writer) {
+	return func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "You got %d of %d correct.\n", out.Correct, out.Total)
+		fmt.Fprintf(w, "Your estimated level is %d of %d (ability %.2f ± %.2f).\n\n", out.Level, store.MaxDifficulty, out.Ability, out.StandardError)
+		fmt.Fprintln(w, "QUESTION\tDIFFICULTY\tRESULT\tYOUR ANSWER\tCORRECT ANSWER")
+		for _, a := range out.Answers {
+			difficulty := "-"
+			if q := asked[a.QuestionID]; q != nil && q.Difficulty > 0 {
+				difficulty = fmt.Sprint(q.Difficulty)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", a.QuestionID, difficulty, u.mark(a.IsCorrect), a.Answer, a.CorrectAnswer)
+		}
+	}
+}
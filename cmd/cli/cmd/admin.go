@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mateopresacastro/qstnnr/pkg/api"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// itemStatsOutput is the item analysis of the quiz, with the shares from 0 to 1.
+type itemStatsOutput struct {
+	Attempts int32            `json:"attempts" yaml:"attempts"`
+	Items    []itemStatOutput `json:"items" yaml:"items"`
+}
+
+type itemStatOutput struct {
+	QuestionID        int32              `json:"question_id" yaml:"question_id"`
+	Question          string             `json:"question" yaml:"question"`
+	Responses         int32              `json:"responses" yaml:"responses"`
+	Correct           float64            `json:"correct" yaml:"correct"`
+	Discrimination    float64            `json:"discrimination" yaml:"discrimination"`
+	Options           []optionStatOutput `json:"options" yaml:"options"`
+	UnusedDistractors []int32            `json:"unused_distractors" yaml:"unused_distractors"`
+	Flags             []string           `json:"flags" yaml:"flags"`
+}
+
+type optionStatOutput struct {
+	ID      int32   `json:"id" yaml:"id"`
+	Text    string  `json:"text" yaml:"text"`
+	Correct bool    `json:"correct" yaml:"correct"`
+	Count   int32   `json:"count" yaml:"count"`
+	Share   float64 `json:"share" yaml:"share"`
+}
+
+func (c *CLI) newAdminCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "admin",
+		Short: "Commands for the authors of the quiz",
+		Long:  `Commands for the authors of the quiz. Set QSTNNR_ADMIN_TOKEN to one of the ADMIN_TOKENS of the server.`,
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "item-stats",
+		Short: "Show how every question performs",
+		Long: `Show how every question performs in the submitted attempts: the share of correct answers,
+how often every option is chosen and the discrimination, the correlation between answering the
+question correctly and the score in the rest of the quiz. Questions answered correctly by almost
+everyone or almost nobody, or with a wrong option chosen more than the correct one or a negative
+discrimination, are flagged for review.`,
+		RunE: c.runItemStats,
+	})
+	return cmd
+}
+
+func (c *CLI) runItemStats(cmd *cobra.Command, args []string) error {
+	res, err := c.client.GetItemStats(cmd.Context(), &emptypb.Empty{})
+	if err != nil {
+		return describeError(err)
+	}
+	out := newItemStatsOutput(res)
+	return render(os.Stdout, c.output, out, itemStatsTable(out))
+}
+
+func newItemStatsOutput(res *api.GetItemStatsResponse) itemStatsOutput {
+	out := itemStatsOutput{Attempts: res.Attempts, Items: make([]itemStatOutput, 0, len(res.Items))}
+	for _, item := range res.Items {
+		io := itemStatOutput{
+			QuestionID:        item.QuestionId,
+			Question:          item.QuestionText,
+			Responses:         item.Responses,
+			Correct:           item.Correct,
+			Discrimination:    item.Discrimination,
+			UnusedDistractors: item.UnusedDistractors,
+			Flags:             item.Flags,
+		}
+		if io.UnusedDistractors == nil {
+			io.UnusedDistractors = []int32{}
+		}
+		if io.Flags == nil {
+			io.Flags = []string{}
+		}
+		for _, opt := range item.Options {
+			io.Options = append(io.Options, optionStatOutput{
+				ID:      opt.OptionId,
+				Text:    opt.Text,
+				Correct: opt.Correct,
+				Count:   opt.Count,
+				Share:   opt.Share,
+			})
+		}
+		out.Items = append(out.Items, io)
+	}
+	return out
+}
+
+func itemStatsTable(out itemStatsOutput) func(w *tabwriter.Writer) {
+	return func(w *tabwriter.Writer) {
+		if out.Attempts == 0 {
+			fmt.Fprintln(w, "No attempts yet.")
+			return
+		}
+		fmt.Fprintf(w, "%d attempts.\n\n", out.Attempts)
+		fmt.Fprintln(w, "QUESTION\tOPTION\tTEXT\tCHOSEN\tDISCRIMINATION\tFLAGS")
+		for _, item := range out.Items {
+			fmt.Fprintf(w, "%d\t\t%s\t%.0f%% correct\t%.2f\t%s\n", item.QuestionID, item.Question, item.Correct*100, item.Discrimination, strings.Join(item.Flags, ", "))
+			for _, opt := range item.Options {
+				marker := ""
+				switch {
+				case opt.Correct:
+					marker = "correct"
+				case opt.Count == 0:
+					marker = "unused"
+				}
+				fmt.Fprintf(w, "\t%d\t%s\t%d (%.0f%%)\t\t%s\n", opt.ID, opt.Text, opt.Count, opt.Share*100, marker)
+			}
+		}
+	}
+}
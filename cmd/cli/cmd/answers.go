@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/mateopresacastro/qstnnr/pkg/api"
+	"github.com/mateopresacastro/qstnnr/pkg/decode"
+	"github.com/mateopresacastro/qstnnr/pkg/store"
+)
+
+// readAnswersFile reads answers from a JSON or YAML file mapping question IDs to
+// option IDs, e.g. {"1": 2, "2": 2}. The path - reads them from stdin.
+func readAnswersFile(path string) (map[store.QuestionID]store.OptionID, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading answers: %w", err)
+	}
+
+	var raw map[string]int
+	if err := decode.File(data, &raw); err != nil {
+		return nil, fmt.Errorf("reading answers from %s: %w", path, err)
+	}
+	answers := make(map[store.QuestionID]store.OptionID, len(raw))
+	for q, o := range raw {
+		qID, err := strconv.Atoi(q)
+		if err != nil {
+			return nil, fmt.Errorf("reading answers from %s: invalid question ID %q", path, q)
+		}
+		answers[store.QuestionID(qID)] = store.OptionID(o)
+	}
+	return answers, nil
+}
+
+// parseAnswerFlags parses answers given as <question ID>=<option ID> and adds
+// them to answers, replacing the ones for the same questions.
+func parseAnswerFlags(values []string, answers map[store.QuestionID]store.OptionID) error {
+	for _, v := range values {
+		q, o, ok := strings.Cut(v, "=")
+		qID, qErr := strconv.Atoi(strings.TrimSpace(q))
+		oID, oErr := strconv.Atoi(strings.TrimSpace(o))
+		if !ok || qErr != nil || oErr != nil {
+			return fmt.Errorf("invalid answer %q, use <question ID>=<option ID>, e.g. 1=2", v)
+		}
+		answers[store.QuestionID(qID)] = store.OptionID(oID)
+	}
+	return nil
+}
+
+// checkAnswers catches typos before submitting: every answer must be one of the
+// options of an existing question. The server checks that all questions are answered.
+func checkAnswers(questions []*api.Question, answers map[store.QuestionID]store.OptionID) error {
+	for qID, oID := range answers {
+		q := findQuestion(questions, int32(qID))
+		if q == nil {
+			return fmt.Errorf("there is no question %d", qID)
+		}
+		found := false
+		for _, opt := range q.Options {
+			if opt.Id == int32(oID) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("question %d has no option %d", qID, oID)
+		}
+	}
+	return nil
+}
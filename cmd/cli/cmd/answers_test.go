@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mateopresacastro/qstnnr/pkg/api"
+	"github.com/mateopresacastro/qstnnr/pkg/store"
+)
+
+func TestAnswers(t *testing.T) {
+	t.Run("should read answers files", func(t *testing.T) {
+		cases := []struct {
+			name string
+			file string
+			want map[store.QuestionID]store.OptionID
+			err  string
+		}{
+			{name: "answers.json", file: `{"1": 2, "2": 3}`, want: map[store.QuestionID]store.OptionID{1: 2, 2: 3}},
+			{name: "answers.yaml", file: "1: 2\n2: 3\n", want: map[store.QuestionID]store.OptionID{1: 2, 2: 3}},
+			{name: "empty.json", file: `{}`, want: map[store.QuestionID]store.OptionID{}},
+			{name: "words.json", file: `{"one": 2}`, err: `invalid question ID "one"`},
+			{name: "list.json", file: `[1, 2]`, err: "reading answers from"},
+			{name: "text.json", file: `{"1": "two"}`, err: "reading answers from"},
+		}
+		for _, c := range cases {
+			path := filepath.Join(t.TempDir(), c.name)
+			if err := os.WriteFile(path, []byte(c.file), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			got, err := readAnswersFile(path)
+			if c.err != "" {
+				if err == nil || !strings.Contains(err.Error(), c.err) {
+					t.Errorf("%s: expected an error about %s, got %v", c.name, c.err, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("%s: %v", c.name, err)
+				continue
+			}
+			if !maps.Equal(got, c.want) {
+				t.Errorf("%s: expected %v, got %v", c.name, c.want, got)
+			}
+		}
+	})
+
+	t.Run("should fail to read missing answers files", func(t *testing.T) {
+		if _, err := readAnswersFile(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+			t.Error("expected an error")
+		}
+	})
+
+	t.Run("should parse answer flags over the answers given", func(t *testing.T) {
+		cases := []struct {
+			values []string
+			want   map[store.QuestionID]store.OptionID
+			err    bool
+		}{
+			{values: nil, want: map[store.QuestionID]store.OptionID{1: 1}},
+			{values: []string{"2=3"}, want: map[store.QuestionID]store.OptionID{1: 1, 2: 3}},
+			{values: []string{"1=4"}, want: map[store.QuestionID]store.OptionID{1: 4}},
+			{values: []string{" 2 = 3 ", "1=2"}, want: map[store.QuestionID]store.OptionID{1: 2, 2: 3}},
+			{values: []string{"2"}, err: true},
+			{values: []string{"2=b"}, err: true},
+			{values: []string{"a=2"}, err: true},
+			{values: []string{"=2"}, err: true},
+		}
+		for _, c := range cases {
+			answers := map[store.QuestionID]store.OptionID{1: 1}
+			err := parseAnswerFlags(c.values, answers)
+			if c.err {
+				if err == nil || !strings.Contains(err.Error(), "<question ID>=<option ID>") {
+					t.Errorf("%q: expected an error telling the format, got %v", c.values, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("%q: %v", c.values, err)
+				continue
+			}
+			if !maps.Equal(answers, c.want) {
+				t.Errorf("%q: expected %v, got %v", c.values, c.want, answers)
+			}
+		}
+	})
+
+	t.Run("should check the answers against the questions", func(t *testing.T) {
+		questions := []*api.Question{
+			{Id: 1, Options: []*api.Option{{Id: 1}, {Id: 2}}},
+			{Id: 2, Options: []*api.Option{{Id: 1}, {Id: 2}, {Id: 3}}},
+		}
+		cases := []struct {
+			answers map[store.QuestionID]store.OptionID
+			err     string
+		}{
+			{answers: map[store.QuestionID]store.OptionID{1: 2, 2: 3}},
+			// The server checks that every question is answered.
+			{answers: map[store.QuestionID]store.OptionID{1: 1}},
+			{answers: map[store.QuestionID]store.OptionID{}},
+			{answers: map[store.QuestionID]store.OptionID{3: 1}, err: "there is no question 3"},
+			{answers: map[store.QuestionID]store.OptionID{1: 3}, err: "question 1 has no option 3"},
+		}
+		for _, c := range cases {
+			err := checkAnswers(questions, c.answers)
+			if c.err == "" && err != nil {
+				t.Errorf("%v: %v", c.answers, err)
+			}
+			if c.err != "" && (err == nil || err.Error() != c.err) {
+				t.Errorf("%v: expected %q, got %v", c.answers, c.err, err)
+			}
+		}
+	})
+}
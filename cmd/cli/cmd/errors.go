@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// serverError is an error returned by the server, described with the details
+// it sent along.
+type serverError struct {
+	err  error
+	desc string
+}
+
+func (e *serverError) Error() string {
+	return e.desc
+}
+
+func (e *serverError) Unwrap() error {
+	return e.err
+}
+
+// retryAfter returns how long the server asked to wait before trying again, if
+// it did.
+func retryAfter(err error) (time.Duration, bool) {
+	st, ok := status.FromError(err)
+	if !ok {
+		return 0, false
+	}
+	for _, detail := range st.Details() {
+		if d, ok := detail.(*errdetails.RetryInfo); ok {
+			return d.RetryDelay.AsDuration(), true
+		}
+	}
+	return 0, false
+}
+
+// describeError decodes the details of a gRPC status error: the reason, the
+// invalid fields, when to retry and the request ID to mention when reporting a problem.
+func describeError(err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	if st.Code() == codes.Unavailable {
+		return fmt.Errorf("Something went wrong when trying to connect to the server. Did you run `qstnnr server start`?: %w", err)
+	}
+
+	var b strings.Builder
+	b.WriteString(st.Message())
+	var requestID string
+	var retryAfter time.Duration
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.ErrorInfo:
+			fmt.Fprintf(&b, " (%s)", d.Reason)
+		case *errdetails.BadRequest:
+			for _, v := range d.FieldViolations {
+				fmt.Fprintf(&b, "\n  - %s: %s", v.Field, v.Description)
+			}
+		case *errdetails.RequestInfo:
+			requestID = d.RequestId
+		case *errdetails.RetryInfo:
+			retryAfter = d.RetryDelay.AsDuration()
+		}
+	}
+	if retryAfter > 0 {
+		fmt.Fprintf(&b, "\nTry again in %s.", retryAfter.Round(time.Second))
+	}
+	if requestID != "" {
+		fmt.Fprintf(&b, "\nRequest ID: %s", requestID)
+	}
+	return &serverError{err: err, desc: b.String()}
+}
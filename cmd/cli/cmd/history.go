@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mateopresacastro/qstnnr/pkg/report"
+	"github.com/spf13/cobra"
+)
+
+// historyFile keeps the results of the quizzes taken on this machine.
+const historyFile = "history.json"
+
+func loadHistory() ([]report.Result, error) {
+	var history []report.Result
+	if err := readState(historyFile, &history); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return history, nil
+}
+
+func appendHistory(result report.Result) error {
+	history, err := loadHistory()
+	if err != nil {
+		return err
+	}
+	return writeState(historyFile, append(history, result))
+}
+
+// reportOptions tell where to write the report of a quiz and in which format.
+type reportOptions struct {
+	path   string
+	format string
+}
+
+// newReportOptions checks the options before the quiz is taken, so a typo
+// doesn't show up only after answering every question. Without a format, the
+// extension of the file tells it.
+func newReportOptions(path, format string) (reportOptions, error) {
+	if format != "" {
+		if err := report.ValidateFormat(format); err != nil {
+			return reportOptions{}, err
+		}
+		return reportOptions{path: path, format: format}, nil
+	}
+	if path == "" || path == "-" {
+		return reportOptions{path: path, format: report.JSON}, nil
+	}
+	format, err := report.FormatFromPath(path)
+	if err != nil {
+		return reportOptions{}, err
+	}
+	return reportOptions{path: path, format: format}, nil
+}
+
+// write writes the report to the file, or to stdout if the path is - or empty.
+func (o reportOptions) write(results []report.Result) error {
+	if o.path == "" || o.path == "-" {
+		return report.Write(os.Stdout, o.format, results)
+	}
+	f, err := os.Create(o.path)
+	if err != nil {
+		return fmt.Errorf("writing the report: %w", err)
+	}
+	if err := report.Write(f, o.format, results); err != nil {
+		f.Close()
+		return fmt.Errorf("writing the report to %s: %w", o.path, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("writing the report to %s: %w", o.path, err)
+	}
+	return nil
+}
+
+func (c *CLI) newHistoryCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "Show the results of your past quizzes",
+		Long:  `Show the results of the quizzes taken on this machine`,
+		RunE:  c.runHistory,
+	}
+
+	export := &cobra.Command{
+		Use:   "export [file]",
+		Short: "Export the results of your past quizzes",
+		Long: `Export the results of the quizzes taken on this machine as JSON, CSV, Markdown or JUnit XML.
+Without a file the report is written to stdout. Without --format, the extension of the file
+tells the format: .json, .csv, .md or .xml.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: c.runHistoryExport,
+	}
+	export.Flags().String("format", "", "Report format: json, csv, markdown or junit")
+	cmd.AddCommand(export)
+	return cmd
+}
+
+func (c *CLI) runHistory(cmd *cobra.Command, args []string) error {
+	history, err := loadHistory()
+	if err != nil {
+		return err
+	}
+	if history == nil {
+		history = []report.Result{}
+	}
+	return render(os.Stdout, c.output, history, historyTable(history))
+}
+
+func historyTable(history []report.Result) func(w *tabwriter.Writer) {
+	return func(w *tabwriter.Writer) {
+		if len(history) == 0 {
+			fmt.Fprintln(w, "No quizzes yet, run `qstnnr take` to take the quiz.")
+			return
+		}
+		fmt.Fprintln(w, "SUBMITTED AT\tCORRECT\tPERCENTILE")
+		for _, r := range history {
+			fmt.Fprintf(w, "%s\t%d/%d\t%d\n", r.SubmittedAt.Local().Format(time.DateTime), r.Correct, r.Total, r.BetterThan)
+		}
+	}
+}
+
+func (c *CLI) runHistoryExport(cmd *cobra.Command, args []string) error {
+	format, err := cmd.Flags().GetString("format")
+	if err != nil {
+		return err
+	}
+	var path string
+	if len(args) > 0 {
+		path = args[0]
+	}
+	opts, err := newReportOptions(path, format)
+	if err != nil {
+		return err
+	}
+	history, err := loadHistory()
+	if err != nil {
+		return err
+	}
+	if err := opts.write(history); err != nil {
+		return err
+	}
+	if opts.path != "" && opts.path != "-" {
+		fmt.Fprintf(os.Stderr, "Exported %d results to %s.\n", len(history), opts.path)
+	}
+	return nil
+}
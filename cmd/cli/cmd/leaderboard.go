@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mateopresacastro/qstnnr/pkg/api"
+	"github.com/spf13/cobra"
+)
+
+// leaderboardOutput is the leaderboard of a quiz, overall or of a cohort.
+type leaderboardOutput struct {
+	Quiz         string             `json:"quiz" yaml:"quiz"`
+	Cohort       string             `json:"cohort,omitempty" yaml:"cohort,omitempty"`
+	Participants int32              `json:"participants" yaml:"participants"`
+	Entries      []leaderboardEntry `json:"entries" yaml:"entries"`
+}
+
+type leaderboardEntry struct {
+	Rank             int32     `json:"rank" yaml:"rank"`
+	DisplayName      string    `json:"display_name" yaml:"display_name"`
+	You              bool      `json:"you,omitempty" yaml:"you,omitempty"`
+	Correct          int32     `json:"correct" yaml:"correct"`
+	Total            int32     `json:"total" yaml:"total"`
+	SubmittedAt      time.Time `json:"submitted_at" yaml:"submitted_at"`
+	BetterThan       int32     `json:"better_than" yaml:"better_than"`
+	CohortBetterThan int32     `json:"cohort_better_than,omitempty" yaml:"cohort_better_than,omitempty"`
+}
+
+func (c *CLI) newLeaderboardCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "leaderboard",
+		Short: "Show the participants with the best scores",
+		Long: `Show the participants with the best scores, by the best of their attempts, and how they
+rank among all the participants. With --cohort, only the attempts of the members of the cohort are
+ranked, and they are also ranked within it. --quiz ranks the attempts of an event, the last one that
+opened by default, or of the default quiz. Only the participants who took the quiz with
+--display-name are listed, under that name.`,
+		RunE: c.runLeaderboard,
+	}
+	cmd.Flags().String("quiz", "", "Quiz to rank: default or the name of an event, the last event that opened if empty")
+	cmd.Flags().String("cohort", "", "Only rank the attempts of the members of this cohort")
+	cmd.Flags().Int("limit", 10, "Number of participants to show, up to 100")
+	return cmd
+}
+
+func (c *CLI) runLeaderboard(cmd *cobra.Command, args []string) error {
+	quiz, _ := cmd.Flags().GetString("quiz")
+	cohort, _ := cmd.Flags().GetString("cohort")
+	limit, _ := cmd.Flags().GetInt("limit")
+	res, err := c.client.GetLeaderboard(cmd.Context(), &api.GetLeaderboardRequest{Quiz: quiz, Cohort: cohort, Limit: int32(limit)})
+	if err != nil {
+		return describeError(err)
+	}
+
+	out := leaderboardOutput{Quiz: res.Quiz, Cohort: res.Cohort, Participants: res.Participants, Entries: []leaderboardEntry{}}
+	for _, e := range res.Entries {
+		out.Entries = append(out.Entries, leaderboardEntry{
+			Rank:             e.Rank,
+			DisplayName:      e.DisplayName,
+			You:              e.You,
+			Correct:          e.Score,
+			Total:            e.Total,
+			SubmittedAt:      e.SubmittedAt.AsTime(),
+			BetterThan:       e.BetterThan,
+			CohortBetterThan: e.CohortBetterThan,
+		})
+	}
+	return render(os.Stdout, c.output, out, leaderboardTable(out))
+}
+
+// leaderboardTable marks the caller's row, if they are on the leaderboard.
+func leaderboardTable(out leaderboardOutput) func(w *tabwriter.Writer) {
+	return func(w *tabwriter.Writer) {
+		if len(out.Entries) == 0 {
+			fmt.Fprintln(w, "No participants yet.")
+			return
+		}
+		of := count(out.Participants, "participant")
+		if out.Quiz != "default" {
+			of += " of " + out.Quiz
+		}
+		if out.Cohort != "" {
+			fmt.Fprintf(w, "Top %d of %s in %s.\n\n", len(out.Entries), of, out.Cohort)
+			fmt.Fprintf(w, "RANK\tNAME\tCORRECT\tPERCENTILE IN %s\tPERCENTILE\tSUBMITTED AT\n", out.Cohort)
+		} else {
+			fmt.Fprintf(w, "Top %d of %s.\n\n", len(out.Entries), of)
+			fmt.Fprintln(w, "RANK\tNAME\tCORRECT\tPERCENTILE\tSUBMITTED AT")
+		}
+		for _, e := range out.Entries {
+			name := e.DisplayName
+			if e.You {
+				name += " (you)"
+			}
+			submittedAt := e.SubmittedAt.Local().Format(time.DateTime)
+			if out.Cohort != "" {
+				fmt.Fprintf(w, "%d\t%s\t%d/%d\t%d\t%d\t%s\n", e.Rank, name, e.Correct, e.Total, e.CohortBetterThan, e.BetterThan, submittedAt)
+			} else {
+				fmt.Fprintf(w, "%d\t%s\t%d/%d\t%d\t%s\n", e.Rank, name, e.Correct, e.Total, e.BetterThan, submittedAt)
+			}
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mateopresacastro/qstnnr/pkg/api"
+	"github.com/mateopresacastro/qstnnr/pkg/report"
+	"github.com/mateopresacastro/qstnnr/pkg/store"
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats of the --output flag.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+func validateOutput(format string) error {
+	switch format {
+	case outputTable, outputJSON, outputYAML:
+		return nil
+	default:
+		return fmt.Errorf("invalid output format %q, use table, json or yaml", format)
+	}
+}
+
+// render writes v in the given format. Tables are written by the table function,
+// as each command lays them out differently.
+func render(w io.Writer, format string, v any, table func(w *tabwriter.Writer)) error {
+	switch format {
+	case outputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case outputYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		return enc.Close()
+	default:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		table(tw)
+		return tw.Flush()
+	}
+}
+
+type questionOutput struct {
+	ID         int32          `json:"id" yaml:"id"`
+	Text       string         `json:"text" yaml:"text"`
+	Difficulty int32          `json:"difficulty,omitempty" yaml:"difficulty,omitempty"`
+	Options    []optionOutput `json:"options" yaml:"options"`
+}
+
+type optionOutput struct {
+	ID   int32  `json:"id" yaml:"id"`
+	Text string `json:"text" yaml:"text"`
+}
+
+func newQuestionsOutput(questions []*api.Question) []questionOutput {
+	out := make([]questionOutput, 0, len(questions))
+	for _, q := range questions {
+		qo := questionOutput{ID: q.Id, Text: q.Text, Difficulty: q.Difficulty}
+		for _, opt := range q.Options {
+			qo.Options = append(qo.Options, optionOutput{ID: opt.Id, Text: opt.Text})
+		}
+		out = append(out, qo)
+	}
+	slices.SortFunc(out, func(a, b questionOutput) int { return cmp.Compare(a.ID, b.ID) })
+	return out
+}
+
+func questionsTable(questions []questionOutput) func(w *tabwriter.Writer) {
+	return func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "QUESTION\tOPTION\tTEXT")
+		for _, q := range questions {
+			fmt.Fprintf(w, "%d\t\t%s\n", q.ID, q.Text)
+			for _, opt := range q.Options {
+				fmt.Fprintf(w, "\t%d\t%s\n", opt.ID, opt.Text)
+			}
+		}
+	}
+}
+
+// newResult builds the result of a submission, with every answer next to the
+// correct one.
+func newResult(questions []*api.Question, answers map[store.QuestionID]store.OptionID, res *api.SubmitAnswersResponse) report.Result {
+	out := report.Result{
+		Correct:           res.Correct,
+		Total:             len(questions),
+		BetterThan:        res.BetterThan,
+		Cohort:            res.Cohort,
+		CohortBetterThan:  res.CohortBetterThan,
+		SolutionsWithheld: res.SolutionsWithheld,
+		Answers:           []report.Answer{},
+	}
+	if res.SolutionsPublishedAt != nil {
+		t := res.SolutionsPublishedAt.AsTime()
+		out.SolutionsAt = &t
+	}
+	for _, solution := range res.Solutions {
+		qID := solution.Question.Id
+		oID := int32(answers[store.QuestionID(qID)])
+		answer := "Unknown option"
+		if q := findQuestion(questions, qID); q != nil {
+			answer = findOptionText(q.Options, oID)
+		}
+		out.Answers = append(out.Answers, report.Answer{
+			QuestionID:      qID,
+			Question:        solution.Question.Text,
+			OptionID:        oID,
+			Answer:          answer,
+			CorrectOptionID: solution.CorrectOptionId,
+			CorrectAnswer:   solution.CorrectOptionText,
+			IsCorrect:       oID == solution.CorrectOptionId,
+			Explanation:     solution.Explanation,
+		})
+	}
+	slices.SortFunc(out.Answers, func(a, b report.Answer) int { return cmp.Compare(a.QuestionID, b.QuestionID) })
+	return out
+}
+
+func resultTable(result report.Result, u *ui) func(w *tabwriter.Writer) {
+	return func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "You got %d of %d correct, %s.\n\n", result.Correct, result.Total, result.Standing())
+		if result.SolutionsWithheld {
+			fmt.Fprintln(w, solutionsNotice(result.SolutionsAt))
+			return
+		}
+		fmt.Fprintln(w, "QUESTION\tRESULT\tYOUR ANSWER\tCORRECT ANSWER")
+		for _, a := range result.Answers {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", a.QuestionID, u.mark(a.IsCorrect), a.Answer, a.CorrectAnswer)
+		}
+	}
+}
+
+// solutionsNotice tells when withheld solutions are published, at if the event
+// ever closes.
+func solutionsNotice(at *time.Time) string {
+	if at == nil {
+		return "The solutions are published when the event closes."
+	}
+	return fmt.Sprintf("The solutions are published when the event closes, at %s.", at.Local().Format(time.DateTime))
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/mateopresacastro/qstnnr/pkg/api"
+	"github.com/mateopresacastro/qstnnr/pkg/store"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// pendingFile holds the answers that couldn't be submitted.
+const pendingFile = "pending.json"
+
+// pendingSubmission holds answers that couldn't be submitted, so they can be
+// submitted later with `qstnnr take --resume`. The idempotency key is kept so
+// that a submission the server did score before the failure isn't scored twice.
+// The questions are kept to show the results without fetching them again.
+type pendingSubmission struct {
+	IdempotencyKey string                              `json:"idempotency_key"`
+	Answers        map[store.QuestionID]store.OptionID `json:"answers"`
+	DisplayName    string                              `json:"display_name,omitempty"`
+	Cohort         string                              `json:"cohort,omitempty"`
+	Questions      []pendingQuestion                   `json:"questions,omitempty"`
+	SavedAt        time.Time                           `json:"saved_at"`
+}
+
+// pendingQuestion is a question kept with the pending answers. It is copied
+// from the API message instead of encoding it, as the JSON of the generated
+// protobuf types isn't stable across versions.
+type pendingQuestion struct {
+	ID         int32           `json:"id"`
+	Text       string          `json:"text"`
+	Options    []pendingOption `json:"options"`
+	Difficulty int32           `json:"difficulty,omitempty"`
+}
+
+type pendingOption struct {
+	ID   int32  `json:"id"`
+	Text string `json:"text"`
+}
+
+// newPendingQuestions copies the questions to keep them with pending answers.
+func newPendingQuestions(questions []*api.Question) []pendingQuestion {
+	pqs := make([]pendingQuestion, 0, len(questions))
+	for _, q := range questions {
+		pq := pendingQuestion{ID: q.Id, Text: q.Text, Difficulty: q.Difficulty}
+		for _, opt := range q.Options {
+			pq.Options = append(pq.Options, pendingOption{ID: opt.Id, Text: opt.Text})
+		}
+		pqs = append(pqs, pq)
+	}
+	return pqs
+}
+
+// apiQuestions converts the kept questions back to API messages.
+func apiQuestions(pqs []pendingQuestion) []*api.Question {
+	questions := make([]*api.Question, 0, len(pqs))
+	for _, pq := range pqs {
+		q := &api.Question{Id: pq.ID, Text: pq.Text, Difficulty: pq.Difficulty}
+		for _, opt := range pq.Options {
+			q.Options = append(q.Options, &api.Option{Id: opt.ID, Text: opt.Text})
+		}
+		questions = append(questions, q)
+	}
+	return questions
+}
+
+// savePending writes the pending submission, replacing any previous one.
+func savePending(p pendingSubmission) error {
+	return writeState(pendingFile, p)
+}
+
+// loadPending reads the pending submission. It returns an error wrapping
+// os.ErrNotExist if there is none.
+func loadPending() (pendingSubmission, error) {
+	var p pendingSubmission
+	err := readState(pendingFile, &p)
+	return p, err
+}
+
+func removePending() error {
+	return removeState(pendingFile)
+}
+
+// rejectsAnswers reports whether the server found the answers invalid, so
+// submitting them again would fail the same way. After any other error, like
+// an unreachable server, a rate limit or a closed quiz, the same answers can be
+// submitted again later.
+func rejectsAnswers(err error) bool {
+	return status.Code(err) == codes.InvalidArgument
+}
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mateopresacastro/qstnnr/pkg/api"
+	"github.com/mateopresacastro/qstnnr/pkg/store"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestSubmitAnswers(t *testing.T) {
+	ctx := context.Background()
+	answers := map[store.QuestionID]store.OptionID{1: 2, 2: 1}
+	pending := pendingSubmission{IdempotencyKey: "key-1", Answers: answers}
+
+	rateLimited := func(delay time.Duration) error {
+		st, err := status.New(codes.ResourceExhausted, "too many requests").
+			WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(delay)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return st.Err()
+	}
+	setup := func(t *testing.T, errs ...error) (*CLI, *submitClient) {
+		t.Helper()
+		t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+		client := &submitClient{errs: errs}
+		return &CLI{client: client}, client
+	}
+
+	t.Run("should submit again once the rate limit lets it through", func(t *testing.T) {
+		c, client := setup(t, rateLimited(10*time.Millisecond))
+		res, err := c.submitAnswers(ctx, pending)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Correct != 1 || client.calls != 2 {
+			t.Errorf("expected the second submission to be scored, got %v after %d calls", res, client.calls)
+		}
+		if _, err := loadPending(); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("expected no pending answers, got %v", err)
+		}
+	})
+
+	for _, tt := range []struct {
+		name string
+		err  error
+	}{
+		{"a long rate limit", rateLimited(time.Hour)},
+		{"a closed quiz", status.Error(codes.FailedPrecondition, "the quiz is closed")},
+		{"an unreachable server", status.Error(codes.Unavailable, "connection refused")},
+	} {
+		t.Run("should keep the answers after "+tt.name, func(t *testing.T) {
+			c, client := setup(t, tt.err)
+			if _, err := c.submitAnswers(ctx, pending); err == nil {
+				t.Fatal("expected an error")
+			}
+			if client.calls != 1 {
+				t.Errorf("expected 1 submission, got %d", client.calls)
+			}
+			got, err := loadPending()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.IdempotencyKey != "key-1" || !maps.Equal(got.Answers, answers) {
+				t.Errorf("expected the pending answers, got %+v", got)
+			}
+		})
+	}
+
+	t.Run("should restore the quiz in progress when the answers are rejected", func(t *testing.T) {
+		c, _ := setup(t, status.Error(codes.InvalidArgument, "unknown question"))
+		if err := savePending(pending); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.submitAnswers(ctx, pending); err == nil {
+			t.Fatal("expected an error")
+		}
+		if _, err := loadPending(); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("expected the pending answers to be removed, got %v", err)
+		}
+		var progress quizProgress
+		if err := readState(progressFile, &progress); err != nil {
+			t.Fatal(err)
+		}
+		if !maps.Equal(progress.Answers, answers) {
+			t.Errorf("expected the answers back in progress, got %v", progress.Answers)
+		}
+	})
+}
+
+func TestPendingQuestions(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	questions := []*api.Question{
+		{Id: 1, Text: "What is 2 + 2?", Difficulty: 1, Options: []*api.Option{{Id: 1, Text: "3"}, {Id: 2, Text: "4"}}},
+		{Id: 2, Text: "What is nil?", Options: []*api.Option{{Id: 1, Text: "zero"}}},
+	}
+
+	t.Run("should keep the questions with the pending answers", func(t *testing.T) {
+		if err := savePending(pendingSubmission{Questions: newPendingQuestions(questions)}); err != nil {
+			t.Fatal(err)
+		}
+		got, err := loadPending()
+		if err != nil {
+			t.Fatal(err)
+		}
+		back := apiQuestions(got.Questions)
+		if len(back) != len(questions) {
+			t.Fatalf("expected %d questions, got %d", len(questions), len(back))
+		}
+		for i := range questions {
+			if !proto.Equal(back[i], questions[i]) {
+				t.Errorf("expected %v, got %v", questions[i], back[i])
+			}
+		}
+	})
+}
+
+// submitClient fails the first submissions with errs and scores the rest.
+type submitClient struct {
+	api.QuestionnaireClient
+	errs  []error
+	calls int
+}
+
+func (c *submitClient) SubmitAnswers(ctx context.Context, in *api.SubmitAnswersRequest, opts ...grpc.CallOption) (*api.SubmitAnswersResponse, error) {
+	c.calls++
+	if len(c.errs) > 0 {
+		err := c.errs[0]
+		c.errs = c.errs[1:]
+		return nil, err
+	}
+	return &api.SubmitAnswersResponse{Correct: 1}, nil
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/manifoldco/promptui"
+	"github.com/mateopresacastro/qstnnr/pkg/bank"
+	"github.com/mateopresacastro/qstnnr/pkg/qservice"
+	"github.com/mateopresacastro/qstnnr/pkg/reqctx"
+	"github.com/mateopresacastro/qstnnr/pkg/store"
+	"github.com/spf13/cobra"
+)
+
+func (c *CLI) newPracticeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "practice",
+		Short: "Practice offline, without a server",
+		Long: `Take the quiz without a server, with the embedded question bank or the questions of a quiz file.
+Every answer is checked right away and explained.`,
+		RunE: c.runPractice,
+	}
+	cmd.Flags().StringP("file", "f", "", "Practice with a YAML or JSON quiz file instead of the embedded question bank")
+	cmd.Flags().Bool("numbered", false, "Choose answers by typing their number instead of with the arrow keys")
+	return cmd
+}
+
+// runPractice runs the quiz in-process, with the same service the server uses
+// over its own in-memory store. Practice answers are not saved anywhere.
+func (c *CLI) runPractice(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	path, err := cmd.Flags().GetString("file")
+	if err != nil {
+		return err
+	}
+	numbered, err := cmd.Flags().GetBool("numbered")
+	if err != nil {
+		return err
+	}
+	c.ui.numbered = c.ui.numbered || numbered
+
+	data, err := loadBank(path)
+	if err != nil {
+		return err
+	}
+	s, err := store.NewInMemory(data)
+	if err != nil {
+		return err
+	}
+	service := qservice.New(s)
+
+	questions, err := service.Questions(ctx)
+	if err != nil {
+		return err
+	}
+	solutions, err := service.Solutions(ctx, qservice.DefaultQuiz)
+	if err != nil {
+		return err
+	}
+
+	ids := slices.Sorted(maps.Keys(questions))
+	answers := make(map[store.QuestionID]store.OptionID, len(ids))
+	correct := 0
+	for i, qID := range ids {
+		q := questions[qID]
+		options := slices.Sorted(maps.Keys(q.Options))
+		items := make([]string, 0, len(options))
+		for _, oID := range options {
+			items = append(items, q.Options[oID].Text)
+		}
+
+		fmt.Printf("\nQuestion %d of %d\n", i+1, len(ids))
+		index, err := c.ui.choose(choice{
+			label:    q.Text,
+			items:    items,
+			current:  -1,
+			selected: fmt.Sprintf("Question %d: {{ . }}", i+1),
+		})
+		if errors.Is(err, promptui.ErrInterrupt) || errors.Is(err, promptui.ErrEOF) {
+			fmt.Printf("Practice stopped, you got %d of %d correct.\n", correct, i)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("prompt failed: %v", err)
+		}
+
+		answers[qID] = options[index]
+		solution := q.Options[solutions[qID]].Text
+		if answers[qID] == solutions[qID] {
+			correct++
+		}
+		c.printFeedback(answers[qID] == solutions[qID], solution, q.Explanation)
+	}
+
+	// Without a caller in the context the submission is scored without keeping
+	// any attempt.
+	res, err := service.SubmitAnswers(ctx, answers, reqctx.NewID(), "", "")
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\nYou got %d of %d correct.\n", res.Correct, len(ids))
+	return nil
+}
+
+// loadBank returns the questions of the quiz file at path, or of the embedded
+// bank if path is empty.
+func loadBank(path string) (store.InitialData, error) {
+	if path == "" {
+		return bank.Default()
+	}
+	return bank.Load(path)
+}
+
+// printFeedback tells right after answering whether the answer was correct,
+// with the explanation.
+func (c *CLI) printFeedback(correct bool, solution, explanation string) {
+	switch {
+	case c.ui.color && correct:
+		fmt.Println(c.ui.good("✓ Correct!"))
+	case c.ui.color:
+		fmt.Println(c.ui.bad("✗ Wrong, the answer is: " + solution))
+	case correct:
+		fmt.Println("[correct] Your answer is correct.")
+	default:
+		fmt.Printf("[wrong] The correct answer is: %s\n", solution)
+	}
+	if explanation == "" {
+		return
+	}
+	if c.ui.color {
+		fmt.Println(c.ui.faint(explanation))
+	} else {
+		fmt.Printf("Explanation: %s\n", explanation)
+	}
+}
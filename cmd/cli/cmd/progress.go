@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/mateopresacastro/qstnnr/pkg/api"
+	"github.com/mateopresacastro/qstnnr/pkg/reqctx"
+	"github.com/mateopresacastro/qstnnr/pkg/store"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// progressFile holds the answers of the quiz in progress.
+const progressFile = "progress.json"
+
+// progressTimeout bounds saving the progress on the server, so an unavailable
+// server doesn't hold the next question back.
+const progressTimeout = 2 * time.Second
+
+// quizProgress holds the answers given so far in an unfinished quiz, and the
+// questions flagged for review.
+type quizProgress struct {
+	Answers   map[store.QuestionID]store.OptionID `json:"answers"`
+	Flagged   []store.QuestionID                  `json:"flagged,omitempty"`
+	UpdatedAt time.Time                           `json:"updated_at"`
+}
+
+// saveProgress keeps the answers given so far, locally and on the server, so
+// that an interrupted quiz can be resumed with `qstnnr take --resume`, even
+// from another machine. The flags are only kept locally. Saving it is best
+// effort: the quiz goes on if it fails.
+func (c *CLI) saveProgress(ctx context.Context, answers map[store.QuestionID]store.OptionID, flagged []store.QuestionID) {
+	progress := quizProgress{Answers: answers, Flagged: flagged, UpdatedAt: time.Now()}
+	if err := writeState(progressFile, progress); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
+	// The server only keeps the progress of identified users.
+	if c.caller == reqctx.Anonymous {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, progressTimeout)
+	defer cancel()
+	req := &api.SaveProgressRequest{}
+	for _, qID := range slices.Sorted(maps.Keys(answers)) {
+		req.Answers = append(req.Answers, &api.Answer{
+			QuestionId: int32(qID),
+			OptionId:   int32(answers[qID]),
+		})
+	}
+	// The local copy is enough to resume on this machine.
+	_, _ = c.client.SaveProgress(ctx, req)
+}
+
+// loadProgress returns the most recent of the local and the server progress.
+// It returns false if there is no quiz in progress.
+func (c *CLI) loadProgress(ctx context.Context) (quizProgress, bool, error) {
+	var local quizProgress
+	if err := readState(progressFile, &local); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return quizProgress{}, false, err
+	}
+
+	progress := local
+	if c.caller != reqctx.Anonymous {
+		res, err := c.client.GetProgress(ctx, &emptypb.Empty{})
+		// Without the server progress the local one is still worth resuming.
+		if err == nil && res.UpdatedAt.AsTime().After(local.UpdatedAt) {
+			progress = quizProgress{
+				Answers:   make(map[store.QuestionID]store.OptionID),
+				UpdatedAt: res.UpdatedAt.AsTime(),
+			}
+			for _, a := range res.Answers {
+				progress.Answers[store.QuestionID(a.QuestionId)] = store.OptionID(a.OptionId)
+			}
+		}
+	}
+	return progress, len(progress.Answers) > 0, nil
+}
+
+func removeProgress() error {
+	return removeState(progressFile)
+}
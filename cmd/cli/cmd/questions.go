@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func (c *CLI) newQuestionsCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "questions",
+		Short: "List the questions of the quiz",
+		Long:  `Print the questions of the quiz with the IDs of their options, to write the answers of "qstnnr take --answers"`,
+		RunE:  c.runQuestions,
+	}
+}
+
+func (c *CLI) runQuestions(cmd *cobra.Command, args []string) error {
+	res, err := c.client.GetQuestions(cmd.Context(), &emptypb.Empty{})
+	if err != nil {
+		return describeError(err)
+	}
+	questions := newQuestionsOutput(res.Questions)
+	return render(os.Stdout, c.output, questions, questionsTable(questions))
+}
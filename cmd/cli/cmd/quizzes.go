@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// eventOutput is an upcoming or open event. The times are nil when that end of
+// the window is open.
+type eventOutput struct {
+	Name     string     `json:"name" yaml:"name"`
+	Title    string     `json:"title,omitempty" yaml:"title,omitempty"`
+	Status   string     `json:"status" yaml:"status"`
+	OpensAt  *time.Time `json:"opens_at,omitempty" yaml:"opens_at,omitempty"`
+	ClosesAt *time.Time `json:"closes_at,omitempty" yaml:"closes_at,omitempty"`
+}
+
+func (c *CLI) newQuizzesCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "quizzes",
+		Short: "Show the upcoming and open quiz events",
+		Long: `Show the upcoming and open quiz events, the windows of time in which the questionnaire can
+be taken. Every event asks its own questions, and their solutions are published when it closes. A
+server without events is always open.`,
+		RunE: c.runQuizzes,
+	}
+}
+
+func (c *CLI) runQuizzes(cmd *cobra.Command, args []string) error {
+	res, err := c.client.ListEvents(cmd.Context(), &emptypb.Empty{})
+	if err != nil {
+		return describeError(err)
+	}
+	out := []eventOutput{}
+	for _, e := range res.Events {
+		event := eventOutput{Name: e.Name, Title: e.Title, Status: e.Status}
+		if e.OpensAt != nil {
+			t := e.OpensAt.AsTime()
+			event.OpensAt = &t
+		}
+		if e.ClosesAt != nil {
+			t := e.ClosesAt.AsTime()
+			event.ClosesAt = &t
+		}
+		out = append(out, event)
+	}
+	return render(os.Stdout, c.output, out, eventsTable(out))
+}
+
+func eventsTable(events []eventOutput) func(w *tabwriter.Writer) {
+	return func(w *tabwriter.Writer) {
+		if len(events) == 0 {
+			fmt.Fprintln(w, "No upcoming events.")
+			return
+		}
+		fmt.Fprintln(w, "EVENT\tTITLE\tSTATUS\tOPENS AT\tCLOSES AT")
+		for _, e := range events {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", e.Name, e.Title, e.Status, eventTime(e.OpensAt), eventTime(e.ClosesAt))
+		}
+	}
+}
+
+// eventTime formats an end of the window of an event in the local time zone.
+func eventTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Local().Format(time.DateTime)
+}
@@ -1,21 +1,40 @@
 package cmd
 
 import (
+	"cmp"
+	"context"
 	"fmt"
 	"os"
+	"os/user"
+	"time"
 
+	"github.com/mateopresacastro/qstnnr"
 	"github.com/mateopresacastro/qstnnr/cmd/cli/cmd/server"
 	"github.com/mateopresacastro/qstnnr/pkg/api"
+	"github.com/mateopresacastro/qstnnr/pkg/reqctx"
+	"github.com/mateopresacastro/qstnnr/pkg/tracing"
 	"github.com/spf13/cobra"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
 )
 
 type CLI struct {
-	conn    *grpc.ClientConn
-	client  api.QuestionnaireClient
-	rootCmd *cobra.Command
-	port    string
+	conn        *grpc.ClientConn
+	client      api.QuestionnaireClient
+	rootCmd     *cobra.Command
+	port        string
+	caller      string
+	displayName string
+	cohort      string
+	output      string
+	noColor     bool
+	ui          *ui
+	span        trace.Span
 }
 
 var cli *CLI
@@ -28,9 +47,18 @@ func init() {
 			Short: "A simple Go quiz CLI",
 			Long:  `A CLI application to check you Go knowledge.`,
 			PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+				if err := validateOutput(cli.output); err != nil {
+					return err
+				}
+				cli.ui = newUI(cli.noColor)
 				if cmd.Parent() != nil && cmd.Parent().Name() == "server" {
 					return nil
 				}
+				// The span of the command is the parent of the RPC spans, so the
+				// server spans are part of the same trace.
+				ctx, span := otel.Tracer("github.com/mateopresacastro/qstnnr/cmd/cli").Start(cmd.Context(), cmd.CommandPath())
+				cmd.SetContext(ctx)
+				cli.span = span
 				return cli.connect()
 			},
 			PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
@@ -41,18 +69,56 @@ func init() {
 			},
 		},
 	}
+	cli.rootCmd.PersistentFlags().StringVarP(&cli.output, "output", "o", outputTable, "Output format: table, json or yaml")
+	cli.rootCmd.PersistentFlags().BoolVar(&cli.noColor, "no-color", false, "Disable colors and symbols, also disabled by NO_COLOR or when the output isn't a terminal")
 	cli.addCommands()
 }
 
+// serviceConfig sets a deadline for every RPC and retries them with exponential
+// backoff while the server is unavailable. All the RPCs are safe to retry:
+// SubmitAnswers carries an idempotency key so it is only scored once, a retried
+// AnswerAdaptiveQuestion gets the same result and a retried StartAdaptiveQuiz
+// only leaves an unused attempt behind.
+const serviceConfig = `{
+	"methodConfig": [
+		{
+			"name": [{"service": "api.Questionnaire"}],
+			"timeout": "5s",
+			"retryPolicy": {
+				"maxAttempts": 4,
+				"initialBackoff": "0.2s",
+				"maxBackoff": "2s",
+				"backoffMultiplier": 2,
+				"retryableStatusCodes": ["UNAVAILABLE"]
+			}
+		},
+		{
+			"name": [{"service": "api.Questionnaire", "method": "SubmitAnswers"}],
+			"timeout": "10s",
+			"retryPolicy": {
+				"maxAttempts": 5,
+				"initialBackoff": "0.5s",
+				"maxBackoff": "4s",
+				"backoffMultiplier": 2,
+				"retryableStatusCodes": ["UNAVAILABLE"]
+			}
+		}
+	]
+}`
+
 func (c *CLI) connect() error {
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = c.port
 	}
+	c.caller = callerIdentity()
 
 	conn, err := grpc.NewClient(
 		fmt.Sprintf("localhost:%s", port),
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+		grpc.WithUnaryInterceptor(c.requestScopeInterceptor),
+		grpc.WithDefaultServiceConfig(serviceConfig),
 	)
 	if err != nil {
 		return fmt.Errorf("Something went wrong when trying to connect to the server. Did you run `qstnnr server start`?: %w", err)
@@ -62,6 +128,37 @@ func (c *CLI) connect() error {
 	return nil
 }
 
+// requestScopeInterceptor sends the identity of the user and a new request ID
+// with every RPC, and the admin or participant token if any.
+func (c *CLI) requestScopeInterceptor(
+	ctx context.Context,
+	method string,
+	req, reply any,
+	cc *grpc.ClientConn,
+	invoker grpc.UnaryInvoker,
+	opts ...grpc.CallOption,
+) error {
+	ctx = metadata.AppendToOutgoingContext(ctx,
+		reqctx.CallerKey, c.caller,
+		reqctx.RequestIDKey, reqctx.NewID(),
+	)
+	if token := cmp.Or(os.Getenv("QSTNNR_ADMIN_TOKEN"), os.Getenv("QSTNNR_TOKEN")); token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, reqctx.AuthorizationKey, "Bearer "+token)
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+// callerIdentity returns QSTNNR_USER if set, or the name of the OS user.
+func callerIdentity() string {
+	if name := os.Getenv("QSTNNR_USER"); name != "" {
+		return name
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return reqctx.Anonymous
+}
+
 func (c *CLI) close() error {
 	if c.conn != nil {
 		return c.conn.Close()
@@ -70,13 +167,54 @@ func (c *CLI) close() error {
 }
 
 func Execute() {
-	if err := cli.rootCmd.Execute(); err != nil {
+	ctx := context.Background()
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Config{
+		ServiceName: "qstnnr-cli",
+		Version:     qstnnr.Version,
+		Exporter:    os.Getenv("OTEL_TRACES_EXPORTER"),
+		Endpoint:    os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"),
+		Output:      os.Stderr,
+	})
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+
+	err = cli.rootCmd.ExecuteContext(ctx)
+	cli.endSpan(err)
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: exporting traces: %v\n", err)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func (c *CLI) endSpan(err error) {
+	if c.span == nil {
+		return
+	}
+	if err != nil {
+		c.span.RecordError(err)
+		c.span.SetStatus(codes.Error, err.Error())
+	}
+	c.span.End()
 }
 
 func (c *CLI) addCommands() {
 	c.rootCmd.AddCommand(c.newTakeCommand())
+	c.rootCmd.AddCommand(c.newQuizzesCommand())
+	c.rootCmd.AddCommand(c.newQuestionsCommand())
+	c.rootCmd.AddCommand(c.newHistoryCommand())
+	c.rootCmd.AddCommand(c.newStatsCommand())
+	c.rootCmd.AddCommand(c.newLeaderboardCommand())
+	c.rootCmd.AddCommand(c.newPracticeCommand())
+	c.rootCmd.AddCommand(c.newStudyCommand())
+	c.rootCmd.AddCommand(c.newAdminCommand())
 	c.rootCmd.AddCommand(server.NewServerCommand())
 }
@@ -1,37 +1,82 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
+	"time"
 
+	"github.com/mateopresacastro/qstnnr"
+	"github.com/mateopresacastro/qstnnr/pkg/api"
 	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/protobuf/types/known/emptypb"
 )
 
+// statusTimeout is how long we wait for the server to answer the health check.
+const statusTimeout = 3 * time.Second
+
 func NewServerStatusCommand() *cobra.Command {
 	return &cobra.Command{
 		Use:   "status",
 		Short: "Check the status of the qstnnr server",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if !isServerRunning() {
-				fmt.Println("Server is not running")
-				return nil
+			port := os.Getenv("PORT")
+			if port == "" {
+				port = qstnnr.DefaultPort
 			}
+			addr := fmt.Sprintf("localhost:%s", port)
 
-			pidFile := filepath.Join(os.TempDir(), "qstnnr-server.pid")
-			pidBytes, err := os.ReadFile(pidFile)
+			conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
 			if err != nil {
-				return fmt.Errorf("failed to read PID file: %v", err)
+				return fmt.Errorf("failed to create client: %v", err)
+			}
+			defer conn.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), statusTimeout)
+			defer cancel()
+
+			health, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+			if err != nil {
+				if !isServerRunning() {
+					fmt.Println("Server is not running")
+					return nil
+				}
+				fmt.Printf("Server process is running but not answering on %s: %v\n", addr, err)
+				return nil
 			}
 
-			pid, err := strconv.Atoi(string(pidBytes))
+			info, err := api.NewQuestionnaireClient(conn).GetServerInfo(ctx, &emptypb.Empty{})
 			if err != nil {
-				return fmt.Errorf("invalid PID in file: %v", err)
+				return fmt.Errorf("failed to get server info: %v", err)
 			}
 
-			fmt.Printf("Server is running (PID: %d)\n", pid)
+			fmt.Printf("Status:  %s\n", health.Status)
+			fmt.Printf("Address: %s\n", addr)
+			if pid, err := readPID(); err == nil {
+				fmt.Printf("PID:     %d\n", pid)
+			}
+			fmt.Printf("Version: %s\n", info.Version)
+			fmt.Printf("Uptime:  %s\n", time.Since(info.StartedAt.AsTime()).Round(time.Second))
 			return nil
 		},
 	}
 }
+
+func readPID() (int, error) {
+	pidFile := filepath.Join(os.TempDir(), "qstnnr-server.pid")
+	pidBytes, err := os.ReadFile(pidFile)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read PID file: %v", err)
+	}
+
+	pid, err := strconv.Atoi(string(pidBytes))
+	if err != nil {
+		return 0, fmt.Errorf("invalid PID in file: %v", err)
+	}
+	return pid, nil
+}
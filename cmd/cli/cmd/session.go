@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/mateopresacastro/qstnnr/pkg/api"
+	"github.com/mateopresacastro/qstnnr/pkg/store"
+)
+
+// action is what the user chose to do on a question.
+type action int
+
+const (
+	actionAnswer action = iota
+	actionPrevious
+	actionSkip
+	actionFlag
+	actionReview
+)
+
+// errQuit is returned when the user leaves the quiz to continue it later.
+var errQuit = errors.New("quit")
+
+// reviewScreen is the position of the review screen, after the last question.
+const reviewScreen = -1
+
+// Navigation entries listed after the options of every question.
+const (
+	itemPrevious = "← Previous question"
+	itemSkip     = "↷ Skip for now"
+	itemFlag     = "⚑ Flag for review"
+	itemUnflag   = "⚐ Remove flag"
+	itemReview   = "☰ Review all answers"
+	itemSubmit   = "✔ Submit answers"
+	itemQuit     = "✕ Quit and continue later"
+)
+
+// quizSession holds the state of a quiz being taken. Questions can be answered
+// in any order, skipped, flagged for review and changed until the answers are
+// submitted.
+type quizSession struct {
+	ui        *ui
+	questions []*api.Question
+	answers   map[store.QuestionID]store.OptionID
+	flagged   map[store.QuestionID]bool
+}
+
+func newQuizSession(u *ui, questions []*api.Question, answers map[store.QuestionID]store.OptionID, flagged []store.QuestionID) *quizSession {
+	s := &quizSession{
+		ui:        u,
+		questions: questions,
+		answers:   answers,
+		flagged:   make(map[store.QuestionID]bool),
+	}
+	for _, qID := range flagged {
+		s.flagged[qID] = true
+	}
+	return s
+}
+
+// nextUnanswered returns the position of the first unanswered question from the
+// given one, or the review screen if they are all answered.
+func (s *quizSession) nextUnanswered(from int) int {
+	for i := from; i < len(s.questions); i++ {
+		if _, ok := s.answers[store.QuestionID(s.questions[i].Id)]; !ok {
+			return i
+		}
+	}
+	return reviewScreen
+}
+
+// unanswered returns the number of questions without an answer.
+func (s *quizSession) unanswered() int {
+	return len(s.questions) - len(s.answers)
+}
+
+// flaggedIDs returns the IDs of the flagged questions, in order.
+func (s *quizSession) flaggedIDs() []store.QuestionID {
+	var ids []store.QuestionID
+	for qID, flagged := range s.flagged {
+		if flagged {
+			ids = append(ids, qID)
+		}
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// ask shows the question at position i with its options followed by the
+// navigation entries. The cursor starts on the current answer, if any.
+func (s *quizSession) ask(i int) (action, error) {
+	q := s.questions[i]
+	qID := store.QuestionID(q.Id)
+
+	items := make([]string, 0, len(q.Options)+4)
+	current := -1
+	for j, opt := range q.Options {
+		items = append(items, opt.Text)
+		if oID, ok := s.answers[qID]; ok && oID == store.OptionID(opt.Id) {
+			current = j
+		}
+	}
+	nav := []string{}
+	if i > 0 {
+		nav = append(nav, itemPrevious)
+	}
+	nav = append(nav, itemSkip)
+	if s.flagged[qID] {
+		nav = append(nav, itemUnflag)
+	} else {
+		nav = append(nav, itemFlag)
+	}
+	nav = append(nav, itemReview)
+	for _, item := range nav {
+		items = append(items, s.ui.item(item))
+	}
+
+	label := q.Text
+	if s.flagged[qID] {
+		label = s.ui.flag() + " " + label
+	}
+	fmt.Printf("Question %d of %d\n", i+1, len(s.questions))
+	index, err := s.ui.choose(choice{
+		label:    label,
+		items:    items,
+		cursor:   max(current, 0),
+		current:  current,
+		selected: fmt.Sprintf("Question %d: {{ . }}", i+1),
+	})
+	if err != nil {
+		return 0, err
+	}
+	if index < len(q.Options) {
+		s.answers[qID] = store.OptionID(q.Options[index].Id)
+		return actionAnswer, nil
+	}
+	switch nav[index-len(q.Options)] {
+	case itemPrevious:
+		return actionPrevious, nil
+	case itemSkip:
+		return actionSkip, nil
+	case itemFlag, itemUnflag:
+		s.flagged[qID] = !s.flagged[qID]
+		return actionFlag, nil
+	default:
+		return actionReview, nil
+	}
+}
+
+// review lists all the questions with their answers. It returns the position of
+// the question to change, or whether the user wants to submit or quit.
+func (s *quizSession) review() (i int, submit bool, err error) {
+	items := make([]string, 0, len(s.questions)+2)
+	for n, q := range s.questions {
+		qID := store.QuestionID(q.Id)
+		answer := "unanswered"
+		if oID, ok := s.answers[qID]; ok {
+			answer = findOptionText(q.Options, int32(oID))
+		}
+		if s.ui.color {
+			mark := " "
+			if s.flagged[qID] {
+				mark = s.ui.flag()
+			}
+			items = append(items, fmt.Sprintf("%s %2d. %s → %s", mark, n+1, q.Text, answer))
+			continue
+		}
+		item := fmt.Sprintf("%d. %s Answer: %s", n+1, q.Text, answer)
+		if s.flagged[qID] {
+			item += " " + s.ui.flag()
+		}
+		items = append(items, item)
+	}
+	items = append(items, s.ui.item(itemSubmit), s.ui.item(itemQuit))
+
+	label := "Review your answers, choose a question to change it"
+	if n := s.unanswered(); n > 0 {
+		label = fmt.Sprintf("Review your answers, %d still unanswered", n)
+	}
+	if flagged := len(s.flaggedIDs()); flagged > 0 {
+		label += fmt.Sprintf(", %d flagged", flagged)
+	}
+
+	// Start on submit, the most likely choice once everything is answered.
+	index, err := s.ui.choose(choice{
+		label:   label,
+		items:   items,
+		cursor:  len(s.questions),
+		current: -1,
+		size:    12,
+		search:  true,
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	switch {
+	case index < len(s.questions):
+		return index, false, nil
+	case index == len(s.questions):
+		return 0, true, nil
+	default:
+		return 0, false, errQuit
+	}
+}
@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mateopresacastro/qstnnr/pkg/api"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// histogramWidth is the width of the longest bar of a histogram.
+const histogramWidth = 30
+
+// statsOutput is the distribution of the scores of a quiz. Histogram has the
+// number of attempts for every score, from 0 to Total.
+type statsOutput struct {
+	Quiz              string       `json:"quiz" yaml:"quiz"`
+	Attempts          int32        `json:"attempts" yaml:"attempts"`
+	Participants      int32        `json:"participants" yaml:"participants"`
+	Total             int32        `json:"total" yaml:"total"`
+	Histogram         []int32      `json:"histogram" yaml:"histogram"`
+	Mean              float64      `json:"mean" yaml:"mean"`
+	Median            float64      `json:"median" yaml:"median"`
+	StandardDeviation float64      `json:"standard_deviation" yaml:"standard_deviation"`
+	You               *scoreOutput `json:"you,omitempty" yaml:"you,omitempty"`
+}
+
+type scoreOutput struct {
+	Score      int32 `json:"score" yaml:"score"`
+	BetterThan int32 `json:"better_than" yaml:"better_than"`
+}
+
+func (c *CLI) newStatsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Show the distribution of the scores",
+		Long: `Show how the participants scored: a histogram of the scores, the mean, the median, the
+standard deviation and where your last attempt landed. --since and --until take a date, such as
+2026-10-01, or a time, such as 2026-10-01T18:00:00Z. A date in --until includes the whole day.`,
+		RunE: c.runStats,
+	}
+	cmd.Flags().String("quiz", "", "Quiz to describe: default, adaptive or the name of an event, the last event that opened if empty")
+	cmd.Flags().String("since", "", "Only count the attempts submitted from this date or time")
+	cmd.Flags().String("until", "", "Only count the attempts submitted until this date or time")
+	return cmd
+}
+
+func (c *CLI) runStats(cmd *cobra.Command, args []string) error {
+	quiz, _ := cmd.Flags().GetString("quiz")
+	sinceFlag, _ := cmd.Flags().GetString("since")
+	untilFlag, _ := cmd.Flags().GetString("until")
+
+	req := &api.GetStatsRequest{Quiz: quiz}
+	if sinceFlag != "" {
+		since, _, err := parseTimeFlag(sinceFlag)
+		if err != nil {
+			return fmt.Errorf("invalid --since: %w", err)
+		}
+		req.Since = timestamppb.New(since)
+	}
+	if untilFlag != "" {
+		until, dateOnly, err := parseTimeFlag(untilFlag)
+		if err != nil {
+			return fmt.Errorf("invalid --until: %w", err)
+		}
+		if dateOnly {
+			until = until.AddDate(0, 0, 1)
+		}
+		req.Until = timestamppb.New(until)
+	}
+
+	res, err := c.client.GetStats(cmd.Context(), req)
+	if err != nil {
+		return describeError(err)
+	}
+	out := newStatsOutput(res)
+	return render(os.Stdout, c.output, out, statsTable(out))
+}
+
+// parseTimeFlag parses a date, in the local time zone, or an RFC 3339 time.
+// It reports whether it was a date.
+func parseTimeFlag(v string) (time.Time, bool, error) {
+	if t, err := time.ParseInLocation(time.DateOnly, v, time.Local); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("%q is neither a date like 2026-10-01 nor a time like 2026-10-01T18:00:00Z", v)
+	}
+	return t, false, nil
+}
+
+func newStatsOutput(res *api.GetStatsResponse) statsOutput {
+	out := statsOutput{
+		Quiz:              res.Quiz,
+		Attempts:          res.Attempts,
+		Participants:      res.Participants,
+		Total:             res.Total,
+		Histogram:         res.Histogram,
+		Mean:              res.Mean,
+		Median:            res.Median,
+		StandardDeviation: res.StandardDeviation,
+	}
+	if out.Histogram == nil {
+		out.Histogram = []int32{}
+	}
+	if res.Caller != nil {
+		out.You = &scoreOutput{Score: res.Caller.Score, BetterThan: res.Caller.BetterThan}
+	}
+	return out
+}
+
+func statsTable(out statsOutput) func(w *tabwriter.Writer) {
+	return func(w *tabwriter.Writer) {
+		if out.Attempts == 0 {
+			fmt.Fprintln(w, "No attempts yet.")
+			return
+		}
+		you := int32(-1)
+		if out.You != nil {
+			you = out.You.Score
+		}
+		fmt.Fprint(w, histogram(out, you))
+		if out.You != nil {
+			fmt.Fprintf(w, "\nYour last score of %d has percentile rank %d among the attempts.\n", out.You.Score, out.You.BetterThan)
+		}
+	}
+}
+
+// histogram draws a bar for every score with the number of attempts, marking
+// the given score, and none if it's negative.
+func histogram(out statsOutput, mark int32) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s by %s. Mean %.1f, median %g, standard deviation %.1f.\n\n",
+		count(out.Attempts, "attempt"), count(out.Participants, "participant"), out.Mean, out.Median, out.StandardDeviation)
+
+	highest := int32(0)
+	for _, n := range out.Histogram {
+		highest = max(highest, n)
+	}
+	labelWidth := len(fmt.Sprint(len(out.Histogram) - 1))
+	for score, n := range out.Histogram {
+		width := 0
+		if highest > 0 {
+			width = int(n) * histogramWidth / int(highest)
+		}
+		if n > 0 {
+			width = max(width, 1)
+		}
+		line := fmt.Sprintf("%*d | %-*s %d", labelWidth, score, histogramWidth, strings.Repeat("#", width), n)
+		if int32(score) == mark {
+			line += "  <- you"
+		}
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
+
+// scoreHistogram draws the distribution of the scores of a quiz, marking the
+// score of the caller.
+func (c *CLI) scoreHistogram(ctx context.Context, quiz string, score int32) (string, error) {
+	res, err := c.client.GetStats(ctx, &api.GetStatsRequest{Quiz: quiz})
+	if err != nil {
+		return "", describeError(err)
+	}
+	return histogram(newStatsOutput(res), score), nil
+}
+
+// count writes n followed by noun, in plural unless n is 1.
+func count(n int32, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
@@ -0,0 +1,231 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/manifoldco/promptui"
+	"github.com/mateopresacastro/qstnnr/pkg/srs"
+	"github.com/mateopresacastro/qstnnr/pkg/store"
+	"github.com/spf13/cobra"
+)
+
+// studyFile keeps the review schedule of every question studied, by question
+// bank: the embedded one or the path of a quiz file.
+const studyFile = "study.json"
+
+// defaultDeck is the name of the deck of the embedded question bank.
+const defaultDeck = "default"
+
+func (c *CLI) newStudyCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "study",
+		Short: "Study the questions with spaced repetition",
+		Long: `Study the questions due today. Questions come back after a few days when you
+answer them, sooner if they were hard, and the next day when you get them wrong.
+Wrong answers are asked again until you get them right.`,
+		RunE: c.runStudy,
+	}
+	cmd.PersistentFlags().StringP("file", "f", "", "Study a YAML or JSON quiz file instead of the embedded question bank")
+	cmd.Flags().Int("new", 5, "Maximum number of new questions a day")
+	cmd.Flags().Bool("numbered", false, "Choose answers by typing their number instead of with the arrow keys")
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "stats",
+		Short: "Show how well you know the questions",
+		RunE:  c.runStudyStats,
+	})
+	return cmd
+}
+
+// studyDeck loads the questions to study and their review schedule.
+func studyDeck(cmd *cobra.Command) (store.InitialData, srs.Deck, map[string]srs.Deck, error) {
+	path, err := cmd.Flags().GetString("file")
+	if err != nil {
+		return store.InitialData{}, nil, nil, err
+	}
+	data, err := loadBank(path)
+	if err != nil {
+		return store.InitialData{}, nil, nil, err
+	}
+	name := defaultDeck
+	if path != "" {
+		if name, err = filepath.Abs(path); err != nil {
+			return store.InitialData{}, nil, nil, err
+		}
+	}
+
+	decks := make(map[string]srs.Deck)
+	if err := readState(studyFile, &decks); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return store.InitialData{}, nil, nil, err
+	}
+	if decks[name] == nil {
+		decks[name] = make(srs.Deck)
+	}
+	return data, decks[name], decks, nil
+}
+
+func questionIDs(data store.InitialData) []int {
+	ids := make([]int, 0, len(data.Questions))
+	for qID := range data.Questions {
+		ids = append(ids, int(qID))
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func (c *CLI) runStudy(cmd *cobra.Command, args []string) error {
+	newPerDay, err := cmd.Flags().GetInt("new")
+	if err != nil {
+		return err
+	}
+	numbered, err := cmd.Flags().GetBool("numbered")
+	if err != nil {
+		return err
+	}
+	c.ui.numbered = c.ui.numbered || numbered
+
+	data, deck, decks, err := studyDeck(cmd)
+	if err != nil {
+		return err
+	}
+	ids := questionIDs(data)
+	now := time.Now()
+	queue := deck.Queue(ids, now, newPerDay)
+	if len(queue) == 0 {
+		fmt.Println("Nothing to study today.")
+		if next := deck.Stats(ids, now).NextDue; next != nil {
+			fmt.Printf("Next review on %s.\n", next.Format(time.DateOnly))
+		}
+		return nil
+	}
+	fmt.Printf("%d questions to study today.\n", len(queue))
+
+	// Only the first answer of the day schedules a question. Wrong answers are
+	// asked again at the end, as SM-2 repeats failed recalls the same day.
+	scheduled := make(map[int]bool)
+	studied := 0
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		q := data.Questions[store.QuestionID(id)]
+		options := slices.Sorted(maps.Keys(q.Options))
+		items := make([]string, 0, len(options))
+		for _, oID := range options {
+			items = append(items, q.Options[oID].Text)
+		}
+
+		fmt.Println()
+		index, err := c.ui.choose(choice{
+			label:    q.Text,
+			items:    items,
+			current:  -1,
+			selected: "{{ . }}",
+		})
+		if err != nil {
+			return studyError(err, unscheduled(queue, id, scheduled))
+		}
+
+		solution := data.Solutions[q.ID]
+		correct := options[index] == solution
+		c.printFeedback(correct, q.Options[solution].Text, q.Explanation)
+		grade := srs.Again
+		if correct {
+			if grade, err = c.askGrade(); err != nil {
+				return studyError(err, unscheduled(queue, id, scheduled))
+			}
+		} else {
+			queue = append(queue, id)
+		}
+
+		if !scheduled[id] {
+			card, ok := deck[id]
+			if !ok {
+				card = srs.NewCard(now)
+			}
+			deck[id] = card.Review(grade, now)
+			scheduled[id] = true
+			studied++
+			// Save after every answer, so stopping keeps what was studied.
+			if err := writeState(studyFile, decks); err != nil {
+				return err
+			}
+		}
+	}
+
+	fmt.Printf("\nDone for today, you studied %d questions.\n", studied)
+	if next := deck.Stats(ids, now).NextDue; next != nil {
+		fmt.Printf("Next review on %s.\n", next.Format(time.DateOnly))
+	}
+	return nil
+}
+
+// askGrade asks how hard it was to recall a correct answer.
+func (c *CLI) askGrade() (srs.Grade, error) {
+	grades := []srs.Grade{srs.Hard, srs.Good, srs.Easy}
+	index, err := c.ui.choose(choice{
+		label:   "How hard was it to remember?",
+		items:   []string{"Hard", "Good", "Easy"},
+		cursor:  1,
+		current: -1,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return grades[index], nil
+}
+
+// unscheduled returns how many of the current question and the queued ones
+// were not answered yet today. The others are only asked again to relearn them.
+func unscheduled(queue []int, current int, scheduled map[int]bool) int {
+	n := 0
+	for _, id := range append([]int{current}, queue...) {
+		if !scheduled[id] {
+			n++
+		}
+	}
+	return n
+}
+
+// studyError tells what is left for today when a study session is stopped.
+func studyError(err error, left int) error {
+	if errors.Is(err, promptui.ErrInterrupt) || errors.Is(err, promptui.ErrEOF) {
+		if left == 0 {
+			fmt.Println("Study session stopped, you studied every question due today.")
+		} else {
+			fmt.Printf("Study session stopped, %d questions left for today. Run `qstnnr study` to continue.\n", left)
+		}
+		return nil
+	}
+	return fmt.Errorf("prompt failed: %v", err)
+}
+
+func (c *CLI) runStudyStats(cmd *cobra.Command, args []string) error {
+	data, deck, _, err := studyDeck(cmd)
+	if err != nil {
+		return err
+	}
+	stats := deck.Stats(questionIDs(data), time.Now())
+	return render(os.Stdout, c.output, stats, studyStatsTable(stats))
+}
+
+func studyStatsTable(stats srs.Stats) func(w *tabwriter.Writer) {
+	return func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "Questions\t%d\n", stats.Questions)
+		fmt.Fprintf(w, "New\t%d\n", stats.New)
+		fmt.Fprintf(w, "Learning\t%d\n", stats.Learning)
+		fmt.Fprintf(w, "Mature\t%d\n", stats.Mature)
+		fmt.Fprintf(w, "Due today\t%d\n", stats.DueToday)
+		fmt.Fprintf(w, "Reviews\t%d\n", stats.Reviews)
+		fmt.Fprintf(w, "Retention\t%.0f%%\n", stats.Retention*100)
+		if stats.NextDue != nil {
+			fmt.Fprintf(w, "Next review\t%s\n", stats.NextDue.Format(time.DateOnly))
+		}
+	}
+}
@@ -2,130 +2,439 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
+	"time"
 
 	"github.com/manifoldco/promptui"
 	"github.com/mateopresacastro/qstnnr/pkg/api"
+	"github.com/mateopresacastro/qstnnr/pkg/report"
+	"github.com/mateopresacastro/qstnnr/pkg/reqctx"
 	"github.com/mateopresacastro/qstnnr/pkg/store"
 	"github.com/spf13/cobra"
 	"google.golang.org/protobuf/types/known/emptypb"
 )
 
 func (c *CLI) newTakeCommand() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "take",
 		Short: "Take the quiz",
 		Long:  `Start a new quiz session and answer questions`,
 		RunE:  c.runTakeQuiz,
 	}
+	cmd.Flags().Bool("resume", false, "Continue an interrupted quiz, or submit the answers saved after a failed submission")
+	cmd.Flags().String("answers", "", "Submit the answers in a JSON or YAML file mapping question IDs to option IDs, - for stdin")
+	cmd.Flags().StringArray("answer", nil, "Submit an answer as <question ID>=<option ID>, can be repeated")
+	cmd.MarkFlagsMutuallyExclusive("resume", "answers")
+	cmd.MarkFlagsMutuallyExclusive("resume", "answer")
+	cmd.Flags().Bool("numbered", false, "Choose answers by typing their number instead of with the arrow keys")
+	cmd.Flags().Bool("tui", false, "Take the quiz in a full-screen terminal UI, if the terminal supports it")
+	cmd.MarkFlagsMutuallyExclusive("tui", "answers")
+	cmd.MarkFlagsMutuallyExclusive("tui", "answer")
+	cmd.MarkFlagsMutuallyExclusive("tui", "numbered")
+	cmd.Flags().Bool("adaptive", false, "Take an adaptive quiz, with harder questions after correct answers and easier ones after wrong answers")
+	cmd.Flags().Int("max-questions", 0, "Number of questions of the adaptive quiz (default: all)")
+	cmd.Flags().StringVar(&c.displayName, "display-name", os.Getenv("QSTNNR_DISPLAY_NAME"), "Name to be listed under on the leaderboard, which only lists the participants who give one")
+	cmd.Flags().StringVar(&c.cohort, "cohort", os.Getenv("QSTNNR_COHORT"), "Cohort to be ranked in, one of yours when the server has you in several (needs QSTNNR_TOKEN)")
+	cmd.Flags().String("report", "", "Write the results to a file, - for stdout")
+	cmd.Flags().String("report-format", "", "Report format: json, csv, markdown or junit (default: from the extension of the report file)")
+	for _, flag := range []string{"resume", "answers", "answer", "tui", "display-name", "cohort", "report"} {
+		cmd.MarkFlagsMutuallyExclusive("adaptive", flag)
+	}
+	return cmd
 }
 
 func (c *CLI) runTakeQuiz(cmd *cobra.Command, args []string) error {
-	ctx := context.Background()
+	ctx := cmd.Context()
+	reportPath, err := cmd.Flags().GetString("report")
+	if err != nil {
+		return err
+	}
+	reportFormat, err := cmd.Flags().GetString("report-format")
+	if err != nil {
+		return err
+	}
+	rep, err := newReportOptions(reportPath, reportFormat)
+	if err != nil {
+		return err
+	}
+
+	adaptive, err := cmd.Flags().GetBool("adaptive")
+	if err != nil {
+		return err
+	}
+	if adaptive {
+		maxQuestions, err := cmd.Flags().GetInt("max-questions")
+		if err != nil {
+			return err
+		}
+		numbered, err := cmd.Flags().GetBool("numbered")
+		if err != nil {
+			return err
+		}
+		c.ui.numbered = c.ui.numbered || numbered
+		return c.takeAdaptive(ctx, maxQuestions)
+	}
+
+	resume, err := cmd.Flags().GetBool("resume")
+	if err != nil {
+		return err
+	}
+	if resume {
+		pending, err := loadPending()
+		if err == nil {
+			return c.submitPending(ctx, pending, rep)
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
 	questions, err := c.client.GetQuestions(ctx, &emptypb.Empty{})
 	if err != nil {
-		return fmt.Errorf("Something went wrong when trying to connect to the server. Did you run `qstnnr server start`?: %w", err)
+		return describeError(err)
+	}
+
+	answersFile, err := cmd.Flags().GetString("answers")
+	if err != nil {
+		return err
+	}
+	answerFlags, err := cmd.Flags().GetStringArray("answer")
+	if err != nil {
+		return err
+	}
+	if answersFile != "" || len(answerFlags) > 0 {
+		return c.submitNonInteractive(ctx, questions.Questions, answersFile, answerFlags, rep)
 	}
 
 	answers := make(map[store.QuestionID]store.OptionID)
-	for i, q := range questions.Questions {
-		fmt.Printf("Question %d of %d\n", i+1, len(questions.Questions))
-		// Create options slice for the select prompt
-		options := make([]string, len(q.Options))
-		for j, opt := range q.Options {
-			options[j] = opt.Text
-		}
-
-		prompt := promptui.Select{
-			Label: q.Text,
-			Items: options,
-			Templates: &promptui.SelectTemplates{
-				Label:    "{{ . }}",
-				Selected: fmt.Sprintf(`✔ Question %d: {{ . }}`, i+1),
-				Active:   "➜ {{ . | cyan }}",
-				Inactive: "  {{ . }}",
-			},
-		}
-
-		index, _, err := prompt.Run()
+	var flagged []store.QuestionID
+	if resume {
+		progress, ok, err := c.loadProgress(ctx)
 		if err != nil {
-			return fmt.Errorf("prompt failed: %v", err)
+			return err
 		}
+		if !ok {
+			return errors.New("there is no quiz to resume, run `qstnnr take` to start the quiz")
+		}
+		// Answers to questions that are gone from the quiz are dropped.
+		for _, q := range questions.Questions {
+			if oID, ok := progress.Answers[store.QuestionID(q.Id)]; ok {
+				answers[store.QuestionID(q.Id)] = oID
+			}
+		}
+		flagged = progress.Flagged
+		fmt.Printf("Resuming your quiz, %d of %d questions answered.\n", len(answers), len(questions.Questions))
+	} else {
+		// Starting over replaces any quiz in progress.
+		c.saveProgress(ctx, answers, nil)
+	}
 
-		// Store the answer
-		answers[store.QuestionID(q.Id)] = store.OptionID(q.Options[index].Id)
+	numbered, err := cmd.Flags().GetBool("numbered")
+	if err != nil {
+		return err
+	}
+	c.ui.numbered = c.ui.numbered || numbered
+	session := newQuizSession(c.ui, questions.Questions, answers, flagged)
+	useTUI, err := cmd.Flags().GetBool("tui")
+	if err != nil {
+		return err
+	}
+	if useTUI {
+		if tuiSupported() && !c.ui.numbered {
+			return c.takeQuizTUI(ctx, session, rep)
+		}
+		fmt.Fprintln(os.Stderr, "This terminal doesn't support the full-screen UI, using prompts instead.")
 	}
 
-	if len(answers) <= 0 {
-		return nil
+	i := session.nextUnanswered(0)
+	for {
+		if i == reviewScreen {
+			choice, submit, err := session.review()
+			if err != nil {
+				return promptError(err)
+			}
+			if !submit {
+				i = choice
+				continue
+			}
+			if n := session.unanswered(); n > 0 {
+				fmt.Printf("Answer the %d remaining questions before submitting.\n", n)
+				i = session.nextUnanswered(0)
+				continue
+			}
+			break
+		}
+
+		act, err := session.ask(i)
+		if err != nil {
+			return promptError(err)
+		}
+		switch act {
+		case actionAnswer:
+			// Keep the answer in case the quiz is interrupted
+			c.saveProgress(ctx, session.answers, session.flaggedIDs())
+			i = session.nextUnanswered(i + 1)
+		case actionPrevious:
+			i--
+		case actionSkip:
+			i = session.nextUnanswered(i + 1)
+		case actionFlag:
+			c.saveProgress(ctx, session.answers, session.flaggedIDs())
+		case actionReview:
+			i = reviewScreen
+		}
+	}
+
+	fmt.Fprintln(os.Stderr, "\nSubmitting answers...")
+	// The key makes retrying the submission safe: the server scores it only once.
+	res, err := c.submitAnswers(ctx, pendingSubmission{
+		IdempotencyKey: reqctx.NewID(),
+		Answers:        answers,
+		DisplayName:    c.displayName,
+		Cohort:         c.cohort,
+		Questions:      newPendingQuestions(questions.Questions),
+	})
+	if err != nil {
+		return err
 	}
+	return c.showResults(ctx, questions.Questions, answers, res, true, rep)
+}
 
-	confirm := promptui.Prompt{
-		Label:     "Submit your answers",
-		IsConfirm: true,
+// submitPending submits the answers saved after a failed submission. The
+// questions are the ones they were saved with, unless they were saved by a
+// version that didn't keep them.
+func (c *CLI) submitPending(ctx context.Context, pending pendingSubmission, rep reportOptions) error {
+	questions := apiQuestions(pending.Questions)
+	if len(questions) == 0 {
+		res, err := c.client.GetQuestions(ctx, &emptypb.Empty{})
+		if err != nil {
+			return describeError(err)
+		}
+		questions = res.Questions
 	}
+	fmt.Fprintf(os.Stderr, "Submitting your answers saved on %s...\n", pending.SavedAt.Local().Format(time.DateTime))
+	res, err := c.submitAnswers(ctx, pending)
+	if err != nil {
+		return err
+	}
+	return c.showResults(ctx, questions, pending.Answers, res, true, rep)
+}
 
-	result, err := confirm.Run()
+// takeQuizTUI takes the quiz in the full-screen UI, which also shows the
+// results and the solutions.
+func (c *CLI) takeQuizTUI(ctx context.Context, session *quizSession, rep reportOptions) error {
+	result, err := c.runQuizTUI(ctx, session)
+	if errors.Is(err, errQuit) {
+		return promptError(err)
+	}
 	if err != nil {
-		return fmt.Errorf("prompt failed: %v", err)
+		return err
+	}
+	if c.output != outputTable {
+		if err := render(os.Stdout, c.output, result, resultTable(result, c.ui)); err != nil {
+			return err
+		}
 	}
+	return c.keepResult(result, rep)
+}
 
-	if result != "y" && result != "Y" && result != "" {
-		return nil
+// submitNonInteractive submits the answers given in a file or with flags,
+// without any prompt, for scripts and CI.
+func (c *CLI) submitNonInteractive(ctx context.Context, questions []*api.Question, answersFile string, answerFlags []string, rep reportOptions) error {
+	answers := make(map[store.QuestionID]store.OptionID)
+	if answersFile != "" {
+		var err error
+		if answers, err = readAnswersFile(answersFile); err != nil {
+			return err
+		}
+	}
+	if err := parseAnswerFlags(answerFlags, answers); err != nil {
+		return err
+	}
+	if err := checkAnswers(questions, answers); err != nil {
+		return err
+	}
+
+	// Scripts have the answers at hand to submit them again, so they are not
+	// saved as pending, and a quiz in progress is left alone.
+	res, err := c.sendAnswers(ctx, pendingSubmission{
+		IdempotencyKey: reqctx.NewID(),
+		Answers:        answers,
+		DisplayName:    c.displayName,
+		Cohort:         c.cohort,
+	})
+	if err != nil {
+		return describeError(err)
 	}
+	return c.showResults(ctx, questions, answers, res, false, rep)
+}
 
-	fmt.Println("\nSubmitting answers...")
+// submitAnswers submits the answers. A submission over a rate limit is sent
+// again once the server lets it through, if that is soon enough. If it still
+// fails, the answers are saved so they can be submitted again with
+// `qstnnr take --resume`, unless the server found them invalid.
+func (c *CLI) submitAnswers(ctx context.Context, p pendingSubmission) (*api.SubmitAnswersResponse, error) {
+	submitRes, err := c.sendAnswers(ctx, p)
+	if delay, ok := retryAfter(err); ok && delay <= maxRetryWait {
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+			submitRes, err = c.sendAnswers(ctx, p)
+		case <-ctx.Done():
+			timer.Stop()
+		}
+	}
+	if err != nil {
+		if rejectsAnswers(err) {
+			// Submitting the same answers again would fail the same way. They
+			// go back to the quiz in progress, to be resumed and changed.
+			progress := quizProgress{Answers: p.Answers, UpdatedAt: time.Now()}
+			if saveErr := writeState(progressFile, progress); saveErr != nil {
+				return nil, fmt.Errorf("%w\nYour answers couldn't be restored to the quiz in progress: %v", describeError(err), saveErr)
+			}
+			if err := removePending(); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+			return nil, fmt.Errorf("%w\nRun `qstnnr take --resume` to change your answers.", describeError(err))
+		}
+		p.SavedAt = time.Now()
+		if saveErr := savePending(p); saveErr != nil {
+			return nil, fmt.Errorf("%w\nYour answers couldn't be saved: %v", describeError(err), saveErr)
+		}
+		// The pending answers take over from the quiz in progress.
+		removeLocalProgress()
+		return nil, fmt.Errorf("%w\nYour answers were saved. Run `qstnnr take --resume` to submit them again.", describeError(err))
+	}
+	removeLocalProgress()
+	if err := removePending(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	return submitRes, nil
+}
+
+// maxRetryWait is the longest a submission over a rate limit waits to be sent
+// again, instead of being saved for `qstnnr take --resume`.
+const maxRetryWait = 10 * time.Second
+
+// sendAnswers submits the answers, without saving them if it fails.
+func (c *CLI) sendAnswers(ctx context.Context, p pendingSubmission) (*api.SubmitAnswersResponse, error) {
 	var fmtAnswers []*api.Answer
-	for qID, oID := range answers {
+	for qID, oID := range p.Answers {
 		fmtAnswers = append(fmtAnswers, &api.Answer{
 			QuestionId: int32(qID),
 			OptionId:   int32(oID),
 		})
 	}
+	req := &api.SubmitAnswersRequest{
+		Answers:        fmtAnswers,
+		IdempotencyKey: p.IdempotencyKey,
+		DisplayName:    p.DisplayName,
+		Cohort:         p.Cohort,
+	}
+	return c.client.SubmitAnswers(ctx, req)
+}
+
+// removeLocalProgress forgets the quiz in progress once its answers are
+// submitted or saved as pending.
+func removeLocalProgress() {
+	if err := removeProgress(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
 
-	req := &api.SubmitAnswersRequest{Answers: fmtAnswers}
-	submitRes, err := c.client.SubmitAnswers(ctx, req)
-	if err != nil {
+// showResults prints the results of a submission in the --output format. In a
+// table, interactive sessions see the distribution of the scores and are asked
+// whether to check the solutions. The results are kept in the history and
+// written to the report, if any.
+func (c *CLI) showResults(ctx context.Context, questions []*api.Question, answers map[store.QuestionID]store.OptionID, submitRes *api.SubmitAnswersResponse, interactive bool, rep reportOptions) error {
+	result := newResult(questions, answers, submitRes)
+	result.User = c.caller
+	result.SubmittedAt = time.Now()
+	if err := c.printResults(ctx, questions, answers, submitRes, result, interactive); err != nil {
 		return err
 	}
+	return c.keepResult(result, rep)
+}
 
-	fmt.Printf("\nYou got %d correct!\n", submitRes.Correct)
-	fmt.Printf("That's better than %d%% of participants! 🌱\n", submitRes.BetterThan)
+// keepResult adds the result to the history and writes the report, if any.
+func (c *CLI) keepResult(result report.Result, rep reportOptions) error {
+	if err := appendHistory(result); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	if rep.path == "" {
+		return nil
+	}
+	return rep.write([]report.Result{result})
+}
 
-	reviewPrompt := promptui.Prompt{
-		Label:     "Would you like to check the solutions",
-		IsConfirm: true,
+func (c *CLI) printResults(ctx context.Context, questions []*api.Question, answers map[store.QuestionID]store.OptionID, submitRes *api.SubmitAnswersResponse, result report.Result, interactive bool) error {
+	if c.output != outputTable || !interactive {
+		return render(os.Stdout, c.output, result, resultTable(result, c.ui))
 	}
 
-	reviewResult, err := reviewPrompt.Run()
-	if err != nil {
+	fmt.Printf("\nYou got %d correct!\n", submitRes.Correct)
+	betterThan := fmt.Sprintf("You're at %s!", result.Standing())
+	if c.ui.color {
+		betterThan += " 🌱"
+	}
+	fmt.Println(betterThan)
+	if hist, err := c.scoreHistogram(ctx, submitRes.Quiz, submitRes.Correct); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: couldn't get the distribution of the scores: %v\n", err)
+	} else {
+		fmt.Printf("\n%s\n", hist)
+	}
+
+	if result.SolutionsWithheld {
+		fmt.Println(solutionsNotice(result.SolutionsAt))
 		return nil
 	}
 
-	if reviewResult != "y" && reviewResult != "Y" && reviewResult != "" {
+	review, err := c.ui.confirm("Would you like to check the solutions")
+	if err != nil || !review {
 		return nil
 	}
 
 	for _, solution := range submitRes.Solutions {
 		fmt.Printf("\n%s\n", solution.Question.Text)
 		userAnswer := answers[store.QuestionID(solution.Question.Id)]
+		correct := userAnswer == store.OptionID(solution.CorrectOptionId)
+		originalQ := findQuestion(questions, solution.Question.Id)
+		userAnswerText := findOptionText(originalQ.Options, int32(userAnswer))
 
-		if userAnswer == store.OptionID(solution.CorrectOptionId) {
-			// Correct
-			fmt.Printf("\033[32m✓ %s\033[0m\n", solution.CorrectOptionText)
-		} else {
-			// Incorrect
-			originalQ := findQuestion(questions.Questions, solution.Question.Id)
-			userAnswerText := findOptionText(originalQ.Options, int32(userAnswer))
-
-			fmt.Printf("\033[32m✓ Correct: %s\033[0m\n", solution.CorrectOptionText)
-			fmt.Printf("\033[31m✗ Your answer: %s\033[0m\n", userAnswerText)
+		switch {
+		case c.ui.color && correct:
+			fmt.Println(c.ui.good("✓ " + solution.CorrectOptionText))
+		case c.ui.color:
+			fmt.Println(c.ui.good("✓ Correct: " + solution.CorrectOptionText))
+			fmt.Println(c.ui.bad("✗ Your answer: " + userAnswerText))
+		case correct:
+			fmt.Printf("[correct] Your answer: %s\n", userAnswerText)
+		default:
+			fmt.Printf("[wrong] Your answer: %s\n", userAnswerText)
+			fmt.Printf("Correct answer: %s\n", solution.CorrectOptionText)
+		}
+		if solution.Explanation != "" {
+			if c.ui.color {
+				fmt.Println(c.ui.faint(solution.Explanation))
+			} else {
+				fmt.Printf("Explanation: %s\n", solution.Explanation)
+			}
 		}
 	}
 
 	return nil
 }
 
+// promptError tells how to continue a quiz left with Ctrl-C or from the review screen.
+func promptError(err error) error {
+	if errors.Is(err, errQuit) || errors.Is(err, promptui.ErrInterrupt) || errors.Is(err, promptui.ErrEOF) {
+		return errors.New("quiz interrupted, run `qstnnr take --resume` to continue where you left off")
+	}
+	return fmt.Errorf("prompt failed: %v", err)
+}
+
 func findQuestion(questions []*api.Question, id int32) *api.Question {
 	for _, q := range questions {
 		if q.Id == id {
@@ -0,0 +1,499 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/term"
+	"github.com/mateopresacastro/qstnnr/pkg/api"
+	"github.com/mateopresacastro/qstnnr/pkg/report"
+	"github.com/mateopresacastro/qstnnr/pkg/reqctx"
+	"github.com/mateopresacastro/qstnnr/pkg/store"
+)
+
+// tuiSupported reports whether the terminal can show the full-screen UI: both
+// stdin and stdout must be terminals that understand escape sequences.
+func tuiSupported() bool {
+	if t := os.Getenv("TERM"); t == "dumb" || t == "" {
+		return false
+	}
+	return term.IsTerminal(os.Stdin.Fd()) && term.IsTerminal(os.Stdout.Fd())
+}
+
+// Screens of the full-screen UI.
+type tuiScreen int
+
+const (
+	screenQuiz tuiScreen = iota
+	screenSubmitting
+	screenResults
+	screenReview
+)
+
+// sidebarWidth is the width of the question list, borders included.
+const sidebarWidth = 34
+
+var (
+	titleStyle    = lipgloss.NewStyle().Bold(true)
+	dimStyle      = lipgloss.NewStyle().Faint(true)
+	activeStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("6")).Bold(true)
+	correctStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+	wrongStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+	flagStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+	sidebarStyle  = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).Padding(0, 1)
+	mainPaneStyle = lipgloss.NewStyle().Padding(0, 2)
+)
+
+type tickMsg time.Time
+
+type submittedMsg struct {
+	res *api.SubmitAnswersResponse
+	// histogram is the distribution of the scores, empty if it couldn't be
+	// fetched as it's not worth failing the results for.
+	histogram string
+	err       error
+}
+
+// progressSaver saves the progress in the background, so an unavailable server
+// doesn't freeze the UI. Saves run one at a time and a save older than the last
+// one is dropped.
+type progressSaver struct {
+	c    *CLI
+	mu   sync.Mutex
+	last int
+}
+
+func (s *progressSaver) save(ctx context.Context, seq int, answers map[store.QuestionID]store.OptionID, flagged []store.QuestionID) tea.Cmd {
+	return func() tea.Msg {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if seq < s.last {
+			return nil
+		}
+		s.last = seq
+		s.c.saveProgress(ctx, answers, flagged)
+		return nil
+	}
+}
+
+// quizModel is the full-screen UI for taking the quiz, from the first question
+// to the review of the solutions.
+type quizModel struct {
+	c       *CLI
+	ctx     context.Context
+	session *quizSession
+	screen  tuiScreen
+
+	current int // position of the question shown
+	cursor  int // option under the cursor
+	width   int
+	height  int
+	started time.Time
+	now     time.Time
+	notice  string
+
+	saver   *progressSaver
+	saveSeq int
+
+	result    report.Result
+	histogram string
+	err       error
+}
+
+func newQuizModel(c *CLI, ctx context.Context, session *quizSession) *quizModel {
+	m := &quizModel{
+		c:       c,
+		ctx:     ctx,
+		session: session,
+		started: time.Now(),
+		now:     time.Now(),
+		saver:   &progressSaver{c: c},
+	}
+	m.current = max(session.nextUnanswered(0), 0)
+	m.cursor = m.answerPosition()
+	return m
+}
+
+// runQuizTUI runs the quiz in the full-screen UI. It returns the result once
+// the answers are submitted, or errQuit if the user left to continue later.
+func (c *CLI) runQuizTUI(ctx context.Context, session *quizSession) (report.Result, error) {
+	m := newQuizModel(c, ctx, session)
+	final, err := tea.NewProgram(m, tea.WithAltScreen(), tea.WithContext(ctx)).Run()
+	if err != nil {
+		return report.Result{}, fmt.Errorf("running the quiz: %w", err)
+	}
+	m = final.(*quizModel)
+	if m.err != nil {
+		return report.Result{}, m.err
+	}
+	if m.screen == screenQuiz {
+		// Save the last answers synchronously, the background save may not have
+		// finished before the program exited.
+		c.saveProgress(ctx, session.answers, session.flaggedIDs())
+		return report.Result{}, errQuit
+	}
+	return m.result, nil
+}
+
+func tick() tea.Cmd {
+	return tea.Every(time.Second, func(t time.Time) tea.Msg { return tickMsg(t) })
+}
+
+func (m *quizModel) Init() tea.Cmd {
+	return tick()
+}
+
+func (m *quizModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		return m, nil
+	case tickMsg:
+		if m.screen != screenQuiz {
+			return m, nil
+		}
+		m.now = time.Time(msg)
+		return m, tick()
+	case submittedMsg:
+		if msg.err != nil {
+			m.err = msg.err
+			return m, tea.Quit
+		}
+		m.result = newResult(m.session.questions, m.session.answers, msg.res)
+		m.result.User = m.c.caller
+		m.result.SubmittedAt = time.Now()
+		m.histogram = msg.histogram
+		m.screen = screenResults
+		return m, nil
+	case tea.KeyMsg:
+		// Leaving while submitting wouldn't tell whether the answers were
+		// scored. The submission has a deadline, so it doesn't take long.
+		if m.screen == screenSubmitting {
+			return m, nil
+		}
+		if msg.String() == "ctrl+c" {
+			return m, tea.Quit
+		}
+		switch m.screen {
+		case screenQuiz:
+			return m.updateQuiz(msg)
+		case screenResults:
+			return m.updateResults(msg)
+		case screenReview:
+			return m.updateReview(msg)
+		}
+	}
+	return m, nil
+}
+
+func (m *quizModel) updateQuiz(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	q := m.session.questions[m.current]
+	m.notice = ""
+	switch key := msg.String(); key {
+	case "q", "esc":
+		return m, tea.Quit
+	case "up", "k":
+		m.cursor = max(m.cursor-1, 0)
+	case "down", "j":
+		m.cursor = min(m.cursor+1, len(q.Options)-1)
+	case "enter", " ":
+		return m.answer(m.cursor)
+	case "n", "right", "l", "tab":
+		m.move(m.current + 1)
+	case "p", "left", "h", "shift+tab":
+		m.move(m.current - 1)
+	case "f":
+		qID := store.QuestionID(q.Id)
+		m.session.flagged[qID] = !m.session.flagged[qID]
+		return m, m.save()
+	case "s":
+		return m.submit()
+	default:
+		if len(key) == 1 && key[0] >= '1' && key[0] <= '9' {
+			if i := int(key[0] - '1'); i < len(q.Options) {
+				return m.answer(i)
+			}
+		}
+	}
+	return m, nil
+}
+
+// answer answers the current question with the option at position i and moves
+// on to the next unanswered question.
+func (m *quizModel) answer(i int) (tea.Model, tea.Cmd) {
+	q := m.session.questions[m.current]
+	m.session.answers[store.QuestionID(q.Id)] = store.OptionID(q.Options[i].Id)
+	if next := m.session.nextUnanswered(m.current + 1); next != reviewScreen {
+		m.move(next)
+	} else if next := m.session.nextUnanswered(0); next != reviewScreen {
+		m.move(next)
+	} else {
+		m.cursor = i
+		m.notice = "All questions answered, press s to submit."
+	}
+	return m, m.save()
+}
+
+func (m *quizModel) move(i int) {
+	if i < 0 || i >= len(m.session.questions) {
+		return
+	}
+	m.current = i
+	m.cursor = m.answerPosition()
+}
+
+// answerPosition returns the position of the answer to the current question,
+// or 0 if it's unanswered.
+func (m *quizModel) answerPosition() int {
+	q := m.session.questions[m.current]
+	oID, ok := m.session.answers[store.QuestionID(q.Id)]
+	if !ok {
+		return 0
+	}
+	for i, opt := range q.Options {
+		if store.OptionID(opt.Id) == oID {
+			return i
+		}
+	}
+	return 0
+}
+
+func (m *quizModel) save() tea.Cmd {
+	m.saveSeq++
+	return m.saver.save(m.ctx, m.saveSeq, maps.Clone(m.session.answers), m.session.flaggedIDs())
+}
+
+func (m *quizModel) submit() (tea.Model, tea.Cmd) {
+	if n := m.session.unanswered(); n > 0 {
+		m.notice = fmt.Sprintf("Answer the %d remaining questions before submitting.", n)
+		return m, nil
+	}
+	m.screen = screenSubmitting
+	answers := maps.Clone(m.session.answers)
+	return m, func() tea.Msg {
+		// The key makes retrying the submission safe: the server scores it only once.
+		res, err := m.c.submitAnswers(m.ctx, pendingSubmission{
+			IdempotencyKey: reqctx.NewID(),
+			Answers:        answers,
+			DisplayName:    m.c.displayName,
+			Cohort:         m.c.cohort,
+			Questions:      newPendingQuestions(m.session.questions),
+		})
+		if err != nil {
+			return submittedMsg{err: err}
+		}
+		hist, _ := m.c.scoreHistogram(m.ctx, res.Quiz, res.Correct)
+		return submittedMsg{res: res, histogram: hist}
+	}
+}
+
+func (m *quizModel) updateResults(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "esc", "enter":
+		return m, tea.Quit
+	case "r":
+		if len(m.result.Answers) == 0 {
+			return m, nil
+		}
+		m.screen = screenReview
+		m.current = 0
+	}
+	return m, nil
+}
+
+func (m *quizModel) updateReview(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "esc":
+		return m, tea.Quit
+	case "n", "right", "l", "down", "j", "tab":
+		m.current = min(m.current+1, len(m.result.Answers)-1)
+	case "p", "left", "h", "up", "k", "shift+tab":
+		m.current = max(m.current-1, 0)
+	case "b":
+		m.screen = screenResults
+	}
+	return m, nil
+}
+
+func (m *quizModel) View() string {
+	switch m.screen {
+	case screenSubmitting:
+		return mainPaneStyle.Render("\nSubmitting answers...")
+	case screenResults:
+		return m.resultsView()
+	case screenReview:
+		return m.reviewView()
+	default:
+		return m.quizView()
+	}
+}
+
+func (m *quizModel) quizView() string {
+	total := len(m.session.questions)
+	answered := total - m.session.unanswered()
+	elapsed := m.now.Sub(m.started).Round(time.Second)
+	clock := "⏱ "
+	if !m.c.ui.color {
+		clock = "elapsed "
+	}
+
+	header := lipgloss.JoinHorizontal(lipgloss.Top,
+		titleStyle.Render("qstnnr quiz"), "   ",
+		progressBar(answered, total, 20), fmt.Sprintf(" %d/%d answered", answered, total), "   ",
+		dimStyle.Render(clock+formatElapsed(elapsed)),
+	)
+
+	u := m.c.ui
+	var list strings.Builder
+	for i, q := range m.session.questions {
+		qID := store.QuestionID(q.Id)
+		_, answered := m.session.answers[qID]
+		var line string
+		if u.color {
+			flag := " "
+			if m.session.flagged[qID] {
+				flag = flagStyle.Render(u.flag())
+			}
+			line = fmt.Sprintf("%s %s %2d %s", flag, u.answered(answered), i+1, truncate(q.Text, sidebarWidth-12))
+		} else {
+			// The textual markers go after the question, which is cut to fit them.
+			marks := " " + u.answered(answered)
+			if m.session.flagged[qID] {
+				marks += " " + u.flag()
+			}
+			line = fmt.Sprintf("%2d %s%s", i+1, truncate(q.Text, max(sidebarWidth-8-len(marks), 3)), marks)
+		}
+		if i == m.current {
+			line = activeStyle.Render(line)
+		}
+		list.WriteString(line + "\n")
+	}
+	sidebar := sidebarStyle.Width(sidebarWidth - 2).Render(strings.TrimSuffix(list.String(), "\n"))
+
+	q := m.session.questions[m.current]
+	var main strings.Builder
+	label := fmt.Sprintf("Question %d of %d", m.current+1, total)
+	if m.session.flagged[store.QuestionID(q.Id)] {
+		label += flagStyle.Render("  " + u.item("⚑ flagged"))
+	}
+	main.WriteString(dimStyle.Render(label) + "\n\n")
+	main.WriteString(titleStyle.Render(q.Text) + "\n\n")
+	chosen, hasAnswer := m.session.answers[store.QuestionID(q.Id)]
+	for i, opt := range q.Options {
+		pointer := "  "
+		if i == m.cursor {
+			pointer = u.pointer()
+		}
+		line := fmt.Sprintf("%s%d  %s", pointer, i+1, opt.Text)
+		switch {
+		case hasAnswer && store.OptionID(opt.Id) == chosen:
+			line = activeStyle.Render(line + "  " + u.chosen())
+		case i == m.cursor:
+			line = activeStyle.Render(line)
+		}
+		main.WriteString(line + "\n")
+	}
+	if m.notice != "" {
+		main.WriteString("\n" + flagStyle.Render(m.notice) + "\n")
+	}
+	mainWidth := max(m.width-sidebarWidth, 20)
+	body := lipgloss.JoinHorizontal(lipgloss.Top, sidebar, mainPaneStyle.Width(mainWidth).Render(main.String()))
+
+	help := dimStyle.Render("1-9 answer · ↑/↓ move · enter select · n/p next/previous · f flag · s submit · q quit and continue later")
+	return lipgloss.JoinVertical(lipgloss.Left, header, "", body, "", help)
+}
+
+func (m *quizModel) resultsView() string {
+	r := m.result
+	var b strings.Builder
+	b.WriteString(titleStyle.Render("Results") + "\n\n")
+	fmt.Fprintf(&b, "You got %d of %d correct in %s.\n", r.Correct, r.Total, formatElapsed(m.now.Sub(m.started).Round(time.Second)))
+	fmt.Fprintf(&b, "You're at %s!", r.Standing())
+	if m.c.ui.color {
+		b.WriteString(" 🌱")
+	}
+	b.WriteString("\n\n")
+	b.WriteString(progressBar(int(r.Correct), r.Total, 30) + "\n\n")
+	if m.histogram != "" {
+		b.WriteString(m.histogram + "\n")
+	}
+	if r.SolutionsWithheld {
+		b.WriteString(solutionsNotice(r.SolutionsAt) + "\n\n")
+		b.WriteString(dimStyle.Render("q quit"))
+		return mainPaneStyle.Render("\n" + b.String())
+	}
+	b.WriteString(dimStyle.Render("r review the solutions · q quit"))
+	return mainPaneStyle.Render("\n" + b.String())
+}
+
+func (m *quizModel) reviewView() string {
+	u := m.c.ui
+	var list strings.Builder
+	for i, a := range m.result.Answers {
+		mark := correctStyle.Render(u.mark(true))
+		if !a.IsCorrect {
+			mark = wrongStyle.Render(u.mark(false))
+		}
+		line := fmt.Sprintf("%s %2d %s", mark, i+1, truncate(a.Question, max(sidebarWidth-9-lipgloss.Width(mark), 3)))
+		if i == m.current {
+			line = activeStyle.Render(line)
+		}
+		list.WriteString(line + "\n")
+	}
+	sidebar := sidebarStyle.Width(sidebarWidth - 2).Render(strings.TrimSuffix(list.String(), "\n"))
+
+	a := m.result.Answers[m.current]
+	var main strings.Builder
+	main.WriteString(dimStyle.Render(fmt.Sprintf("Solution %d of %d", m.current+1, len(m.result.Answers))) + "\n\n")
+	main.WriteString(titleStyle.Render(a.Question) + "\n\n")
+	switch {
+	case u.color && a.IsCorrect:
+		main.WriteString(correctStyle.Render("✓ "+a.CorrectAnswer) + "\n")
+	case u.color:
+		main.WriteString(correctStyle.Render("✓ Correct: "+a.CorrectAnswer) + "\n")
+		main.WriteString(wrongStyle.Render("✗ Your answer: "+a.Answer) + "\n")
+	case a.IsCorrect:
+		main.WriteString("[correct] Your answer: " + a.Answer + "\n")
+	default:
+		main.WriteString("[wrong] Your answer: " + a.Answer + "\n")
+		main.WriteString("Correct answer: " + a.CorrectAnswer + "\n")
+	}
+	if a.Explanation != "" {
+		main.WriteString("\n" + a.Explanation + "\n")
+	}
+	mainWidth := max(m.width-sidebarWidth, 20)
+	body := lipgloss.JoinHorizontal(lipgloss.Top, sidebar, mainPaneStyle.Width(mainWidth).Render(main.String()))
+
+	header := titleStyle.Render(fmt.Sprintf("Solutions · %d of %d correct", m.result.Correct, m.result.Total))
+	help := dimStyle.Render("n/p next/previous · b back to the results · q quit")
+	return lipgloss.JoinVertical(lipgloss.Left, header, "", body, "", help)
+}
+
+// progressBar draws done out of total as a bar of the given width.
+func progressBar(done, total, width int) string {
+	filled := 0
+	if total > 0 {
+		filled = done * width / total
+	}
+	return correctStyle.Render(strings.Repeat("█", filled)) + dimStyle.Render(strings.Repeat("░", width-filled))
+}
+
+func formatElapsed(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d.Minutes()), int(d.Seconds())%60)
+}
+
+// truncate shortens s to n runes, ending it with an ellipsis if it was cut.
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
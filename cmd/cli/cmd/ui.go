@@ -0,0 +1,257 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/term"
+	"github.com/manifoldco/promptui"
+	"github.com/muesli/termenv"
+)
+
+// ui renders the interactive parts of the CLI for the terminal it runs in.
+// Colors are only used on terminals, and not with NO_COLOR or --no-color.
+// Without them, symbols and emoji are replaced by textual markers, which screen
+// readers and logs handle well. In numbered mode choices are made by typing
+// their number instead of moving with the arrow keys.
+type ui struct {
+	color    bool
+	numbered bool
+	in       *bufio.Reader
+}
+
+func newUI(noColor bool) *ui {
+	color := !noColor &&
+		os.Getenv("NO_COLOR") == "" &&
+		os.Getenv("TERM") != "dumb" &&
+		term.IsTerminal(os.Stdout.Fd())
+	u := &ui{
+		color: color,
+		// The arrow keys need a terminal.
+		numbered: !term.IsTerminal(os.Stdin.Fd()),
+		in:       bufio.NewReader(os.Stdin),
+	}
+	if !color {
+		promptui.IconInitial = "?"
+		promptui.IconGood = ">"
+		promptui.IconWarn = "!"
+		promptui.IconBad = "x"
+		promptui.IconSelect = ">"
+		lipgloss.SetColorProfile(termenv.Ascii)
+	}
+	return u
+}
+
+func (u *ui) style(code, s string) string {
+	if !u.color {
+		return s
+	}
+	return "\033[" + code + "m" + s + "\033[0m"
+}
+
+func (u *ui) good(s string) string  { return u.style("32", s) }
+func (u *ui) bad(s string) string   { return u.style("31", s) }
+func (u *ui) faint(s string) string { return u.style("2", s) }
+
+// mark tells whether an answer is correct.
+func (u *ui) mark(correct bool) string {
+	switch {
+	case u.color && correct:
+		return "✓"
+	case u.color:
+		return "✗"
+	case correct:
+		return "correct"
+	default:
+		return "wrong"
+	}
+}
+
+// flag marks a question flagged for review.
+func (u *ui) flag() string {
+	if u.color {
+		return "⚑"
+	}
+	return "[flagged]"
+}
+
+// answered marks whether a question has an answer, in the question list of
+// the full-screen UI.
+func (u *ui) answered(ok bool) string {
+	switch {
+	case u.color && ok:
+		return "●"
+	case u.color:
+		return "○"
+	case ok:
+		return "[answered]"
+	default:
+		return "[unanswered]"
+	}
+}
+
+// chosen marks the option chosen as the answer.
+func (u *ui) chosen() string {
+	if u.color {
+		return "✔"
+	}
+	return "(current answer)"
+}
+
+// pointer marks the option the cursor is on.
+func (u *ui) pointer() string {
+	if u.color {
+		return "➜ "
+	}
+	return "> "
+}
+
+// item returns the text of a navigation entry, without its symbol if the
+// output is plain.
+func (u *ui) item(item string) string {
+	if u.color {
+		return item
+	}
+	_, text, _ := strings.Cut(item, " ")
+	return text
+}
+
+// choice is a list of items to choose one from.
+type choice struct {
+	label string
+	items []string
+	// cursor is the item the cursor starts on with the arrow keys.
+	cursor int
+	// current is the item marked as the current choice in numbered mode, -1 for none.
+	current int
+	// size is the number of items shown at once with the arrow keys, all of them if 0.
+	size int
+	// selected is a template shown after choosing, e.g. "Question 1: {{ . }}".
+	// Nothing is shown if it's empty.
+	selected string
+	search   bool
+}
+
+// choose returns the position of the item chosen. If the input ends it returns
+// promptui.ErrEOF, and promptui.ErrInterrupt on Ctrl-C with the arrow keys.
+func (u *ui) choose(c choice) (int, error) {
+	if u.numbered {
+		return u.chooseNumbered(c)
+	}
+
+	size := c.size
+	if size == 0 || size > len(c.items) {
+		size = len(c.items)
+	}
+	templates := &promptui.SelectTemplates{
+		Label:    "{{ . }}",
+		Active:   "➜ {{ . | cyan }}",
+		Inactive: "  {{ . }}",
+	}
+	if c.selected != "" {
+		templates.Selected = "✔ " + c.selected
+	}
+	if !u.color {
+		templates.Active = "> {{ . }}"
+		templates.Selected = c.selected
+		templates.Help = "Use the up and down arrow keys to move and enter to choose" +
+			`{{ if .Search }}, {{ .SearchKey }} to search{{ end }}`
+	}
+	prompt := promptui.Select{
+		Label:        c.label,
+		Items:        c.items,
+		Size:         size,
+		Templates:    templates,
+		HideSelected: c.selected == "",
+	}
+	if c.search {
+		items := c.items
+		prompt.Searcher = func(input string, index int) bool {
+			return strings.Contains(strings.ToLower(items[index]), strings.ToLower(input))
+		}
+	}
+	// Scroll as far as possible towards the cursor, so it's visible.
+	scroll := max(min(c.cursor, len(c.items)-size), 0)
+	index, _, err := prompt.RunCursorAt(c.cursor, scroll)
+	return index, err
+}
+
+// chooseNumbered lists the items with numbers and reads the number of the one
+// chosen. An empty line keeps the current choice, if any.
+func (u *ui) chooseNumbered(c choice) (int, error) {
+	fmt.Println(c.label)
+	for i, item := range c.items {
+		line := fmt.Sprintf("%3d. %s", i+1, item)
+		if i == c.current {
+			line += " (current answer)"
+		}
+		fmt.Println(line)
+	}
+	for {
+		prompt := fmt.Sprintf("Enter a number from 1 to %d", len(c.items))
+		if c.current >= 0 {
+			prompt += ", or press enter to keep the current answer"
+		}
+		fmt.Print(prompt + ": ")
+
+		line, err := u.readLine()
+		if err != nil {
+			return 0, err
+		}
+		if line == "" && c.current >= 0 {
+			return c.current, nil
+		}
+		n, err := strconv.Atoi(line)
+		if err == nil && n >= 1 && n <= len(c.items) {
+			return n - 1, nil
+		}
+		fmt.Printf("%q is not a number from 1 to %d.\n", line, len(c.items))
+	}
+}
+
+// confirm asks a yes or no question, yes being the default.
+func (u *ui) confirm(label string) (bool, error) {
+	if u.numbered {
+		fmt.Printf("%s? [Y/n]: ", label)
+		line, err := u.readLine()
+		if err != nil {
+			return false, err
+		}
+		switch strings.ToLower(line) {
+		case "", "y", "yes":
+			return true, nil
+		default:
+			return false, nil
+		}
+	}
+
+	prompt := promptui.Prompt{Label: label, IsConfirm: true}
+	result, err := prompt.Run()
+	if errors.Is(err, promptui.ErrAbort) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return result == "y" || result == "Y" || result == "", nil
+}
+
+// readLine reads a line of input, trimmed. It returns promptui.ErrEOF when the
+// input ends, so it's handled like the end of input in a prompt.
+func (u *ui) readLine() (string, error) {
+	line, err := u.in.ReadString('\n')
+	if errors.Is(err, io.EOF) && line == "" {
+		fmt.Println()
+		return "", promptui.ErrEOF
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
@@ -20,9 +20,18 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	Questionnaire_GetQuestions_FullMethodName  = "/api.Questionnaire/GetQuestions"
-	Questionnaire_SubmitAnswers_FullMethodName = "/api.Questionnaire/SubmitAnswers"
-	Questionnaire_GetSolutions_FullMethodName  = "/api.Questionnaire/GetSolutions"
+	Questionnaire_GetQuestions_FullMethodName           = "/api.Questionnaire/GetQuestions"
+	Questionnaire_SubmitAnswers_FullMethodName          = "/api.Questionnaire/SubmitAnswers"
+	Questionnaire_GetSolutions_FullMethodName           = "/api.Questionnaire/GetSolutions"
+	Questionnaire_GetServerInfo_FullMethodName          = "/api.Questionnaire/GetServerInfo"
+	Questionnaire_SaveProgress_FullMethodName           = "/api.Questionnaire/SaveProgress"
+	Questionnaire_GetProgress_FullMethodName            = "/api.Questionnaire/GetProgress"
+	Questionnaire_StartAdaptiveQuiz_FullMethodName      = "/api.Questionnaire/StartAdaptiveQuiz"
+	Questionnaire_AnswerAdaptiveQuestion_FullMethodName = "/api.Questionnaire/AnswerAdaptiveQuestion"
+	Questionnaire_GetItemStats_FullMethodName           = "/api.Questionnaire/GetItemStats"
+	Questionnaire_GetStats_FullMethodName               = "/api.Questionnaire/GetStats"
+	Questionnaire_GetLeaderboard_FullMethodName         = "/api.Questionnaire/GetLeaderboard"
+	Questionnaire_ListEvents_FullMethodName             = "/api.Questionnaire/ListEvents"
 )
 
 // QuestionnaireClient is the client API for Questionnaire service.
@@ -34,7 +43,32 @@ type QuestionnaireClient interface {
 	// SubmitAnswers submits the answers to be evaluated.
 	SubmitAnswers(ctx context.Context, in *SubmitAnswersRequest, opts ...grpc.CallOption) (*SubmitAnswersResponse, error)
 	// GetSolutons gets all solutions if the user wants to check them in isolation.
-	GetSolutions(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*GetSolutionsResponse, error)
+	GetSolutions(ctx context.Context, in *GetSolutionsRequest, opts ...grpc.CallOption) (*GetSolutionsResponse, error)
+	// GetServerInfo gets the version of the server and when it was started.
+	GetServerInfo(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*GetServerInfoResponse, error)
+	// SaveProgress saves the answers of the caller's quiz in progress.
+	SaveProgress(ctx context.Context, in *SaveProgressRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// GetProgress gets the answers of the caller's quiz in progress.
+	GetProgress(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*GetProgressResponse, error)
+	// StartAdaptiveQuiz starts an adaptive quiz, where every question is chosen
+	// after the answer to the previous one, and returns its first question.
+	StartAdaptiveQuiz(ctx context.Context, in *StartAdaptiveQuizRequest, opts ...grpc.CallOption) (*AdaptiveQuizResponse, error)
+	// AnswerAdaptiveQuestion answers the current question of an adaptive quiz
+	// and returns the next one.
+	AnswerAdaptiveQuestion(ctx context.Context, in *AnswerAdaptiveQuestionRequest, opts ...grpc.CallOption) (*AdaptiveQuizResponse, error)
+	// GetItemStats analyses how every question performs in the submitted
+	// attempts. Only admins can call it.
+	GetItemStats(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*GetItemStatsResponse, error)
+	// GetStats gets the distribution of the scores of a quiz in a time window
+	// and where the caller landed in it.
+	GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*GetStatsResponse, error)
+	// GetLeaderboard gets the participants with the best scores, overall or
+	// within a cohort. Only the participants who submitted with a display name
+	// are listed.
+	GetLeaderboard(ctx context.Context, in *GetLeaderboardRequest, opts ...grpc.CallOption) (*GetLeaderboardResponse, error)
+	// ListEvents lists the upcoming and open events, the windows of time in
+	// which the questionnaire can be taken.
+	ListEvents(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ListEventsResponse, error)
 }
 
 type questionnaireClient struct {
@@ -65,7 +99,7 @@ func (c *questionnaireClient) SubmitAnswers(ctx context.Context, in *SubmitAnswe
 	return out, nil
 }
 
-func (c *questionnaireClient) GetSolutions(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*GetSolutionsResponse, error) {
+func (c *questionnaireClient) GetSolutions(ctx context.Context, in *GetSolutionsRequest, opts ...grpc.CallOption) (*GetSolutionsResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(GetSolutionsResponse)
 	err := c.cc.Invoke(ctx, Questionnaire_GetSolutions_FullMethodName, in, out, cOpts...)
@@ -75,6 +109,96 @@ func (c *questionnaireClient) GetSolutions(ctx context.Context, in *emptypb.Empt
 	return out, nil
 }
 
+func (c *questionnaireClient) GetServerInfo(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*GetServerInfoResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetServerInfoResponse)
+	err := c.cc.Invoke(ctx, Questionnaire_GetServerInfo_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *questionnaireClient) SaveProgress(ctx context.Context, in *SaveProgressRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Questionnaire_SaveProgress_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *questionnaireClient) GetProgress(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*GetProgressResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetProgressResponse)
+	err := c.cc.Invoke(ctx, Questionnaire_GetProgress_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *questionnaireClient) StartAdaptiveQuiz(ctx context.Context, in *StartAdaptiveQuizRequest, opts ...grpc.CallOption) (*AdaptiveQuizResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AdaptiveQuizResponse)
+	err := c.cc.Invoke(ctx, Questionnaire_StartAdaptiveQuiz_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *questionnaireClient) AnswerAdaptiveQuestion(ctx context.Context, in *AnswerAdaptiveQuestionRequest, opts ...grpc.CallOption) (*AdaptiveQuizResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AdaptiveQuizResponse)
+	err := c.cc.Invoke(ctx, Questionnaire_AnswerAdaptiveQuestion_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *questionnaireClient) GetItemStats(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*GetItemStatsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetItemStatsResponse)
+	err := c.cc.Invoke(ctx, Questionnaire_GetItemStats_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *questionnaireClient) GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*GetStatsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetStatsResponse)
+	err := c.cc.Invoke(ctx, Questionnaire_GetStats_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *questionnaireClient) GetLeaderboard(ctx context.Context, in *GetLeaderboardRequest, opts ...grpc.CallOption) (*GetLeaderboardResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetLeaderboardResponse)
+	err := c.cc.Invoke(ctx, Questionnaire_GetLeaderboard_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *questionnaireClient) ListEvents(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ListEventsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListEventsResponse)
+	err := c.cc.Invoke(ctx, Questionnaire_ListEvents_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // QuestionnaireServer is the server API for Questionnaire service.
 // All implementations must embed UnimplementedQuestionnaireServer
 // for forward compatibility.
@@ -84,7 +208,32 @@ type QuestionnaireServer interface {
 	// SubmitAnswers submits the answers to be evaluated.
 	SubmitAnswers(context.Context, *SubmitAnswersRequest) (*SubmitAnswersResponse, error)
 	// GetSolutons gets all solutions if the user wants to check them in isolation.
-	GetSolutions(context.Context, *emptypb.Empty) (*GetSolutionsResponse, error)
+	GetSolutions(context.Context, *GetSolutionsRequest) (*GetSolutionsResponse, error)
+	// GetServerInfo gets the version of the server and when it was started.
+	GetServerInfo(context.Context, *emptypb.Empty) (*GetServerInfoResponse, error)
+	// SaveProgress saves the answers of the caller's quiz in progress.
+	SaveProgress(context.Context, *SaveProgressRequest) (*emptypb.Empty, error)
+	// GetProgress gets the answers of the caller's quiz in progress.
+	GetProgress(context.Context, *emptypb.Empty) (*GetProgressResponse, error)
+	// StartAdaptiveQuiz starts an adaptive quiz, where every question is chosen
+	// after the answer to the previous one, and returns its first question.
+	StartAdaptiveQuiz(context.Context, *StartAdaptiveQuizRequest) (*AdaptiveQuizResponse, error)
+	// AnswerAdaptiveQuestion answers the current question of an adaptive quiz
+	// and returns the next one.
+	AnswerAdaptiveQuestion(context.Context, *AnswerAdaptiveQuestionRequest) (*AdaptiveQuizResponse, error)
+	// GetItemStats analyses how every question performs in the submitted
+	// attempts. Only admins can call it.
+	GetItemStats(context.Context, *emptypb.Empty) (*GetItemStatsResponse, error)
+	// GetStats gets the distribution of the scores of a quiz in a time window
+	// and where the caller landed in it.
+	GetStats(context.Context, *GetStatsRequest) (*GetStatsResponse, error)
+	// GetLeaderboard gets the participants with the best scores, overall or
+	// within a cohort. Only the participants who submitted with a display name
+	// are listed.
+	GetLeaderboard(context.Context, *GetLeaderboardRequest) (*GetLeaderboardResponse, error)
+	// ListEvents lists the upcoming and open events, the windows of time in
+	// which the questionnaire can be taken.
+	ListEvents(context.Context, *emptypb.Empty) (*ListEventsResponse, error)
 	mustEmbedUnimplementedQuestionnaireServer()
 }
 
@@ -101,9 +250,36 @@ func (UnimplementedQuestionnaireServer) GetQuestions(context.Context, *emptypb.E
 func (UnimplementedQuestionnaireServer) SubmitAnswers(context.Context, *SubmitAnswersRequest) (*SubmitAnswersResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method SubmitAnswers not implemented")
 }
-func (UnimplementedQuestionnaireServer) GetSolutions(context.Context, *emptypb.Empty) (*GetSolutionsResponse, error) {
+func (UnimplementedQuestionnaireServer) GetSolutions(context.Context, *GetSolutionsRequest) (*GetSolutionsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetSolutions not implemented")
 }
+func (UnimplementedQuestionnaireServer) GetServerInfo(context.Context, *emptypb.Empty) (*GetServerInfoResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetServerInfo not implemented")
+}
+func (UnimplementedQuestionnaireServer) SaveProgress(context.Context, *SaveProgressRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SaveProgress not implemented")
+}
+func (UnimplementedQuestionnaireServer) GetProgress(context.Context, *emptypb.Empty) (*GetProgressResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetProgress not implemented")
+}
+func (UnimplementedQuestionnaireServer) StartAdaptiveQuiz(context.Context, *StartAdaptiveQuizRequest) (*AdaptiveQuizResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StartAdaptiveQuiz not implemented")
+}
+func (UnimplementedQuestionnaireServer) AnswerAdaptiveQuestion(context.Context, *AnswerAdaptiveQuestionRequest) (*AdaptiveQuizResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AnswerAdaptiveQuestion not implemented")
+}
+func (UnimplementedQuestionnaireServer) GetItemStats(context.Context, *emptypb.Empty) (*GetItemStatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetItemStats not implemented")
+}
+func (UnimplementedQuestionnaireServer) GetStats(context.Context, *GetStatsRequest) (*GetStatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStats not implemented")
+}
+func (UnimplementedQuestionnaireServer) GetLeaderboard(context.Context, *GetLeaderboardRequest) (*GetLeaderboardResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLeaderboard not implemented")
+}
+func (UnimplementedQuestionnaireServer) ListEvents(context.Context, *emptypb.Empty) (*ListEventsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListEvents not implemented")
+}
 func (UnimplementedQuestionnaireServer) mustEmbedUnimplementedQuestionnaireServer() {}
 func (UnimplementedQuestionnaireServer) testEmbeddedByValue()                       {}
 
@@ -162,7 +338,7 @@ func _Questionnaire_SubmitAnswers_Handler(srv interface{}, ctx context.Context,
 }
 
 func _Questionnaire_GetSolutions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(emptypb.Empty)
+	in := new(GetSolutionsRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
@@ -174,7 +350,169 @@ func _Questionnaire_GetSolutions_Handler(srv interface{}, ctx context.Context, d
 		FullMethod: Questionnaire_GetSolutions_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(QuestionnaireServer).GetSolutions(ctx, req.(*emptypb.Empty))
+		return srv.(QuestionnaireServer).GetSolutions(ctx, req.(*GetSolutionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Questionnaire_GetServerInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QuestionnaireServer).GetServerInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Questionnaire_GetServerInfo_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QuestionnaireServer).GetServerInfo(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Questionnaire_SaveProgress_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SaveProgressRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QuestionnaireServer).SaveProgress(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Questionnaire_SaveProgress_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QuestionnaireServer).SaveProgress(ctx, req.(*SaveProgressRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Questionnaire_GetProgress_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QuestionnaireServer).GetProgress(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Questionnaire_GetProgress_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QuestionnaireServer).GetProgress(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Questionnaire_StartAdaptiveQuiz_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StartAdaptiveQuizRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QuestionnaireServer).StartAdaptiveQuiz(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Questionnaire_StartAdaptiveQuiz_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QuestionnaireServer).StartAdaptiveQuiz(ctx, req.(*StartAdaptiveQuizRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Questionnaire_AnswerAdaptiveQuestion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AnswerAdaptiveQuestionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QuestionnaireServer).AnswerAdaptiveQuestion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Questionnaire_AnswerAdaptiveQuestion_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QuestionnaireServer).AnswerAdaptiveQuestion(ctx, req.(*AnswerAdaptiveQuestionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Questionnaire_GetItemStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QuestionnaireServer).GetItemStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Questionnaire_GetItemStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QuestionnaireServer).GetItemStats(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Questionnaire_GetStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QuestionnaireServer).GetStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Questionnaire_GetStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QuestionnaireServer).GetStats(ctx, req.(*GetStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Questionnaire_GetLeaderboard_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLeaderboardRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QuestionnaireServer).GetLeaderboard(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Questionnaire_GetLeaderboard_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QuestionnaireServer).GetLeaderboard(ctx, req.(*GetLeaderboardRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Questionnaire_ListEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QuestionnaireServer).ListEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Questionnaire_ListEvents_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QuestionnaireServer).ListEvents(ctx, req.(*emptypb.Empty))
 	}
 	return interceptor(ctx, in, info, handler)
 }
@@ -198,6 +536,42 @@ var Questionnaire_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetSolutions",
 			Handler:    _Questionnaire_GetSolutions_Handler,
 		},
+		{
+			MethodName: "GetServerInfo",
+			Handler:    _Questionnaire_GetServerInfo_Handler,
+		},
+		{
+			MethodName: "SaveProgress",
+			Handler:    _Questionnaire_SaveProgress_Handler,
+		},
+		{
+			MethodName: "GetProgress",
+			Handler:    _Questionnaire_GetProgress_Handler,
+		},
+		{
+			MethodName: "StartAdaptiveQuiz",
+			Handler:    _Questionnaire_StartAdaptiveQuiz_Handler,
+		},
+		{
+			MethodName: "AnswerAdaptiveQuestion",
+			Handler:    _Questionnaire_AnswerAdaptiveQuestion_Handler,
+		},
+		{
+			MethodName: "GetItemStats",
+			Handler:    _Questionnaire_GetItemStats_Handler,
+		},
+		{
+			MethodName: "GetStats",
+			Handler:    _Questionnaire_GetStats_Handler,
+		},
+		{
+			MethodName: "GetLeaderboard",
+			Handler:    _Questionnaire_GetLeaderboard_Handler,
+		},
+		{
+			MethodName: "ListEvents",
+			Handler:    _Questionnaire_ListEvents_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "pkg/api/qstnnr.proto",
@@ -0,0 +1,131 @@
+// Package bank loads question banks: the questions of a quiz with their
+// options, correct answers and explanations. The default bank is embedded, and
+// others can be read from YAML or JSON quiz files.
+package bank
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/mateopresacastro/qstnnr/pkg/decode"
+	"github.com/mateopresacastro/qstnnr/pkg/store"
+)
+
+//go:embed go.yaml
+var defaultBank []byte
+
+// File is the format of a quiz file.
+type File struct {
+	Questions []Question `yaml:"questions" json:"questions"`
+}
+
+// Question is a question in a quiz file.
+type Question struct {
+	ID          int    `yaml:"id" json:"id"`
+	Text        string `yaml:"text" json:"text"`
+	Explanation string `yaml:"explanation" json:"explanation"`
+	// Difficulty goes from 1, the easiest, to 5, the hardest. Optional.
+	Difficulty int      `yaml:"difficulty" json:"difficulty"`
+	Options    []Option `yaml:"options" json:"options"`
+}
+
+// Option is an answer choice in a quiz file. Exactly one option of each
+// question is correct.
+type Option struct {
+	ID      int    `yaml:"id" json:"id"`
+	Text    string `yaml:"text" json:"text"`
+	Correct bool   `yaml:"correct" json:"correct"`
+}
+
+// Default returns the embedded question bank about Go.
+func Default() (store.InitialData, error) {
+	data, err := Parse(defaultBank)
+	if err != nil {
+		return store.InitialData{}, fmt.Errorf("default bank: %w", err)
+	}
+	return data, nil
+}
+
+// Load reads the quiz file at path.
+func Load(path string) (store.InitialData, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return store.InitialData{}, fmt.Errorf("reading the quiz file: %w", err)
+	}
+	data, err := Parse(b)
+	if err != nil {
+		return store.InitialData{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return data, nil
+}
+
+// Parse reads a quiz file in YAML or JSON, and checks that every question can
+// be answered.
+func Parse(b []byte) (store.InitialData, error) {
+	var f File
+	if err := decode.File(b, &f); err != nil {
+		return store.InitialData{}, fmt.Errorf("invalid quiz file: %w", err)
+	}
+	if len(f.Questions) == 0 {
+		return store.InitialData{}, errors.New("the quiz has no questions")
+	}
+
+	data := store.InitialData{
+		Questions: make(map[store.QuestionID]store.Question, len(f.Questions)),
+		Solutions: make(map[store.QuestionID]store.OptionID, len(f.Questions)),
+	}
+	for _, q := range f.Questions {
+		qID := store.QuestionID(q.ID)
+		if q.ID <= 0 {
+			return store.InitialData{}, fmt.Errorf("question %q: the ID must be a positive number", q.Text)
+		}
+		if _, ok := data.Questions[qID]; ok {
+			return store.InitialData{}, fmt.Errorf("question %d: duplicate ID", q.ID)
+		}
+		if q.Text == "" {
+			return store.InitialData{}, fmt.Errorf("question %d: the text is empty", q.ID)
+		}
+		difficulty := store.Difficulty(q.Difficulty)
+		if difficulty != 0 && (difficulty < store.MinDifficulty || difficulty > store.MaxDifficulty) {
+			return store.InitialData{}, fmt.Errorf("question %d: the difficulty must be between %d and %d", q.ID, store.MinDifficulty, store.MaxDifficulty)
+		}
+		if len(q.Options) < 2 {
+			return store.InitialData{}, fmt.Errorf("question %d: needs at least 2 options", q.ID)
+		}
+
+		question := store.Question{
+			ID:          qID,
+			Text:        q.Text,
+			Explanation: q.Explanation,
+			Difficulty:  difficulty,
+			Options:     make(map[store.OptionID]store.Option, len(q.Options)),
+		}
+		for _, o := range q.Options {
+			oID := store.OptionID(o.ID)
+			if o.ID <= 0 {
+				return store.InitialData{}, fmt.Errorf("question %d, option %q: the ID must be a positive number", q.ID, o.Text)
+			}
+			if _, ok := question.Options[oID]; ok {
+				return store.InitialData{}, fmt.Errorf("question %d, option %d: duplicate ID", q.ID, o.ID)
+			}
+			if o.Text == "" {
+				return store.InitialData{}, fmt.Errorf("question %d, option %d: the text is empty", q.ID, o.ID)
+			}
+			question.Options[oID] = store.Option{ID: oID, Text: o.Text}
+			if !o.Correct {
+				continue
+			}
+			if _, ok := data.Solutions[qID]; ok {
+				return store.InitialData{}, fmt.Errorf("question %d: more than one correct option", q.ID)
+			}
+			data.Solutions[qID] = oID
+		}
+		if _, ok := data.Solutions[qID]; !ok {
+			return store.InitialData{}, fmt.Errorf("question %d: no correct option", q.ID)
+		}
+		data.Questions[qID] = question
+	}
+	return data, nil
+}
@@ -0,0 +1,98 @@
+package bank_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mateopresacastro/qstnnr/pkg/bank"
+	"github.com/mateopresacastro/qstnnr/pkg/store"
+)
+
+func TestBank(t *testing.T) {
+	t.Run("should load the default bank", func(t *testing.T) {
+		data, err := bank.Default()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data.Questions) != 10 || len(data.Solutions) != 10 {
+			t.Fatalf("expected 10 questions and solutions, got %d and %d", len(data.Questions), len(data.Solutions))
+		}
+		for qID, q := range data.Questions {
+			if q.Explanation == "" {
+				t.Errorf("question %d: expected an explanation", qID)
+			}
+			if _, ok := q.Options[data.Solutions[qID]]; !ok {
+				t.Errorf("question %d: the solution is not one of the options", qID)
+			}
+		}
+		if data.Solutions[1] != 2 {
+			t.Errorf("expected defer() to be the answer to question 1, got %d", data.Solutions[1])
+		}
+	})
+
+	t.Run("should read JSON quiz files", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "quiz.json")
+		quiz := `{"questions": [{"id": 7, "text": "2 + 2?", "options": [
+			{"id": 1, "text": "3"}, {"id": 2, "text": "4", "correct": true}]}]}`
+		if err := os.WriteFile(path, []byte(quiz), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		data, err := bank.Load(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := store.Option{ID: 2, Text: "4"}
+		if got := data.Questions[7].Options[2]; got != want {
+			t.Errorf("expected %+v, got %+v", want, got)
+		}
+		if data.Solutions[7] != 2 {
+			t.Errorf("expected option 2 to be correct, got %d", data.Solutions[7])
+		}
+	})
+
+	t.Run("should reject invalid quizzes", func(t *testing.T) {
+		cases := []struct {
+			quiz string
+			err  string
+		}{
+			{quiz: `questions: []`, err: "no questions"},
+			{quiz: `
+questions:
+  - id: 1
+    text: Q
+    options: [{id: 1, text: A}, {id: 2, text: B}]`, err: "no correct option"},
+			{quiz: `
+questions:
+  - id: 1
+    text: Q
+    options: [{id: 1, text: A, correct: true}, {id: 2, text: B, correct: true}]`, err: "more than one correct option"},
+			{quiz: `
+questions:
+  - id: 1
+    text: Q
+    options: [{id: 1, text: A, correct: true}, {id: 2, text: B}]
+  - id: 1
+    text: R
+    options: [{id: 1, text: A, correct: true}, {id: 2, text: B}]`, err: "duplicate ID"},
+			{quiz: `
+questions:
+  - id: 1
+    text: Q
+    options: [{id: 1, text: A, correct: true}]`, err: "at least 2 options"},
+			{quiz: `
+questions:
+  - id: 1
+    text: Q
+    difficulty: 6
+    options: [{id: 1, text: A, correct: true}, {id: 2, text: B}]`, err: "difficulty must be between 1 and 5"},
+		}
+		for _, c := range cases {
+			_, err := bank.Parse([]byte(c.quiz))
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Errorf("expected an error about %s, got %v", c.err, err)
+			}
+		}
+	})
+}
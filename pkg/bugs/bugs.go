@@ -0,0 +1,324 @@
+package bugs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"regexp"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mateopresacastro/qstnnr/pkg/qerr"
+	"github.com/mateopresacastro/qstnnr/pkg/reqctx"
+)
+
+// Reporter sends bugs, unexpected errors that are not known edge cases, to a
+// destination where they can be looked at.
+type Reporter interface {
+	Report(ctx context.Context, bug Bug) error
+}
+
+// Bug describes an unexpected error and where it happened.
+type Bug struct {
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+	// Detail is the Go representation of the error, with all its fields.
+	Detail string `json:"detail"`
+	// StackTrace is the stack trace of the QError in the chain, or the stack
+	// trace at the moment the bug was created if there is none.
+	StackTrace string `json:"stack_trace"`
+	// Fingerprint identifies the bug, so the same bug happening several times
+	// can be grouped. See Fingerprint.
+	Fingerprint string `json:"fingerprint"`
+	// Method is the RPC method or the HTTP route that triggered the bug.
+	Method    string `json:"method"`
+	RequestID string `json:"request_id,omitempty"`
+	Caller    string `json:"caller,omitempty"`
+}
+
+// New creates a Bug for err, triggered by the given method.
+func New(ctx context.Context, method string, err error) Bug {
+	stackTrace := string(debug.Stack())
+	var qErr qerr.QError
+	if errors.As(err, &qErr) && qErr.StackTrace != "" {
+		stackTrace = qErr.StackTrace
+	}
+	return Bug{
+		Time:        time.Now().UTC(),
+		Message:     err.Error(),
+		Detail:      fmt.Sprintf("%#v", err),
+		StackTrace:  stackTrace,
+		Fingerprint: Fingerprint(method, err),
+		Method:      method,
+		RequestID:   reqctx.RequestID(ctx),
+		Caller:      reqctx.Caller(ctx),
+	}
+}
+
+var (
+	// Arguments of the frames, e.g. main.run(0xc000012345, 0x2).
+	frameArgs = regexp.MustCompile(`\(.*\)$`)
+	// Program counter offsets, e.g. /src/main.go:12 +0x1d.
+	frameOffset = regexp.MustCompile(` \+0x[0-9a-f]+$`)
+)
+
+// Fingerprint identifies a bug by the method that triggered it, the types of
+// the errors in its chain and the codes and reasons of the QErrors among them.
+// The messages are left out, as they carry IDs, addresses and times that
+// change between two occurrences of the same bug. The stack trace where a
+// QError was created tells apart the errors coming from different places.
+// Other errors don't carry one, and the stack when reporting them is the same
+// for all the bugs of a method, so it isn't used.
+func Fingerprint(method string, err error) string {
+	parts := []string{method}
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		part := fmt.Sprintf("%T", e)
+		if qErr, ok := e.(qerr.QError); ok {
+			part += fmt.Sprintf(" %d %s", qErr.Code, qErr.Reason)
+		}
+		parts = append(parts, part)
+	}
+	var qErr qerr.QError
+	if errors.As(err, &qErr) && qErr.StackTrace != "" {
+		parts = append(parts, stackFrames(qErr.StackTrace)...)
+	}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\n")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// stackFrames returns the frames of a stack trace, without the goroutine, the
+// arguments and the offsets, which change between two occurrences of the same bug.
+func stackFrames(stackTrace string) []string {
+	var frames []string
+	for _, line := range strings.Split(stackTrace, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "goroutine ") {
+			continue
+		}
+		line = frameOffset.ReplaceAllString(line, "")
+		line = frameArgs.ReplaceAllString(line, "")
+		frames = append(frames, line)
+	}
+	return frames
+}
+
+// LogReporter writes bugs to a structured logger.
+type LogReporter struct {
+	logger *slog.Logger
+}
+
+// NewLogReporter creates a Reporter logging bugs as errors.
+func NewLogReporter(logger *slog.Logger) *LogReporter {
+	return &LogReporter{logger: logger}
+}
+
+func (r *LogReporter) Report(ctx context.Context, bug Bug) error {
+	msg := "there was an unnespected issue; please report this as a bug"
+	r.logger.ErrorContext(ctx, msg,
+		"err", bug.Detail,
+		"method", bug.Method,
+		"request_id", bug.RequestID,
+		"caller", bug.Caller,
+		"fingerprint", bug.Fingerprint,
+		"stack_trace", bug.StackTrace,
+	)
+	return nil
+}
+
+// FileReporter appends bugs to a JSON lines file. A bug is only written the
+// first time its fingerprint is seen, including fingerprints already in the file.
+type FileReporter struct {
+	path string
+	seen map[string]bool
+	mu   sync.Mutex
+}
+
+// NewFileReporter creates a Reporter appending to the file at path, reading the
+// fingerprints it already contains.
+func NewFileReporter(path string) (*FileReporter, error) {
+	r := &FileReporter{path: path, seen: make(map[string]bool)}
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening bug report file: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var bug Bug
+		if err := json.Unmarshal(scanner.Bytes(), &bug); err != nil {
+			return nil, fmt.Errorf("reading bug report file: %w", err)
+		}
+		r.seen[bug.Fingerprint] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading bug report file: %w", err)
+	}
+	return r, nil
+}
+
+func (r *FileReporter) Report(ctx context.Context, bug Bug) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.seen[bug.Fingerprint] {
+		return nil
+	}
+
+	line, err := json.Marshal(bug)
+	if err != nil {
+		return fmt.Errorf("encoding bug: %w", err)
+	}
+
+	f, err := os.OpenFile(r.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("opening bug report file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("writing bug report file: %w", err)
+	}
+	r.seen[bug.Fingerprint] = true
+	return nil
+}
+
+// webhookQueueSize is the number of bugs waiting to be posted to the webhook.
+// More are dropped, as a burst of bugs is most likely the same one anyway.
+const webhookQueueSize = 100
+
+// WebhookReporter posts bugs as JSON to a URL. The bugs are posted one at a
+// time in the background, so a slow webhook doesn't hold up the requests that
+// ran into them. The errors posting them are logged.
+type WebhookReporter struct {
+	url    string
+	client *http.Client
+	logger *slog.Logger
+	queue  chan Bug
+	done   chan struct{}
+	mu     sync.Mutex
+	closed bool
+}
+
+// NewWebhookReporter creates a Reporter posting bugs to url. Close must be
+// called to send the pending bugs and stop it.
+func NewWebhookReporter(url string, logger *slog.Logger) *WebhookReporter {
+	r := &WebhookReporter{
+		url:    url,
+		client: &http.Client{Timeout: 5 * time.Second},
+		logger: logger,
+		queue:  make(chan Bug, webhookQueueSize),
+		done:   make(chan struct{}),
+	}
+	go r.run()
+	return r
+}
+
+// Report queues the bug to be posted. It fails if the queue is full or the
+// reporter is closed.
+func (r *WebhookReporter) Report(ctx context.Context, bug Bug) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return errors.New("posting bug to webhook: the reporter is closed")
+	}
+	select {
+	case r.queue <- bug:
+		return nil
+	default:
+		return fmt.Errorf("posting bug to webhook: the queue is full, dropping bug %s", bug.Fingerprint)
+	}
+}
+
+// Close stops accepting bugs and waits until the queued ones are posted or ctx
+// is done.
+func (r *WebhookReporter) Close(ctx context.Context) error {
+	r.mu.Lock()
+	if !r.closed {
+		r.closed = true
+		close(r.queue)
+	}
+	r.mu.Unlock()
+
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("posting the pending bugs to webhook: %w", ctx.Err())
+	}
+}
+
+func (r *WebhookReporter) run() {
+	defer close(r.done)
+	for bug := range r.queue {
+		if err := r.post(bug); err != nil {
+			r.logger.Error("failed to report bug", "err", err, "fingerprint", bug.Fingerprint)
+		}
+	}
+}
+
+func (r *WebhookReporter) post(bug Bug) error {
+	body, err := json.Marshal(bug)
+	if err != nil {
+		return fmt.Errorf("encoding bug: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, r.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("posting bug to webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("posting bug to webhook: unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// Multi sends every bug to all the reporters, returning their errors joined.
+type Multi []Reporter
+
+func (m Multi) Report(ctx context.Context, bug Bug) error {
+	var errs []error
+	for _, r := range m {
+		if err := r.Report(ctx, bug); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes the reporters that send bugs in the background, waiting for
+// their pending bugs until ctx is done.
+func (m Multi) Close(ctx context.Context) error {
+	var errs []error
+	for _, r := range m {
+		if c, ok := r.(interface{ Close(context.Context) error }); ok {
+			if err := c.Close(ctx); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,250 @@
+package bugs_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mateopresacastro/qstnnr/pkg/bugs"
+	"github.com/mateopresacastro/qstnnr/pkg/qerr"
+	"github.com/mateopresacastro/qstnnr/pkg/reqctx"
+)
+
+func TestBugs(t *testing.T) {
+	ctx := reqctx.WithCaller(reqctx.WithRequestID(context.Background(), "req-1"), "gopher")
+
+	t.Run("should use the stack trace of the QError", func(t *testing.T) {
+		err := qerr.Wrap(errors.New("boom"), qerr.Internal, "something broke")
+		bug := bugs.New(ctx, "/api.Questionnaire/SubmitAnswers", err)
+		if bug.StackTrace != err.StackTrace {
+			t.Error("expected the stack trace of the QError")
+		}
+		if bug.Method != "/api.Questionnaire/SubmitAnswers" {
+			t.Errorf("expected the method, got %s", bug.Method)
+		}
+		if bug.RequestID != "req-1" || bug.Caller != "gopher" {
+			t.Errorf("expected the request scoped values, got %s and %s", bug.RequestID, bug.Caller)
+		}
+	})
+
+	t.Run("should capture a stack trace for other errors", func(t *testing.T) {
+		bug := bugs.New(ctx, "method", errors.New("boom"))
+		if !strings.Contains(bug.StackTrace, "bugs_test.TestBugs") {
+			t.Errorf("expected the stack trace of the caller, got %s", bug.StackTrace)
+		}
+		if bug.Message != "boom" {
+			t.Errorf("expected boom, got %s", bug.Message)
+		}
+	})
+
+	t.Run("should fingerprint bugs by method and error type, not by message", func(t *testing.T) {
+		a := bugs.New(ctx, "method", errors.New("attempt 1f3a from 10.0.0.1 failed"))
+		b := bugs.New(ctx, "method", errors.New("attempt 9c2e from 10.0.0.7 failed"))
+		if a.Fingerprint != b.Fingerprint {
+			t.Error("expected the same fingerprint for the same bug")
+		}
+		for _, other := range []bugs.Bug{
+			bugs.New(ctx, "other", errors.New("boom")),
+			bugs.New(ctx, "method", fmt.Errorf("%w", errors.New("boom"))),
+		} {
+			if other.Fingerprint == a.Fingerprint {
+				t.Errorf("expected a different fingerprint for %s in %s", other.Detail, other.Method)
+			}
+		}
+	})
+
+	t.Run("should fingerprint QErrors by where they were created", func(t *testing.T) {
+		var fingerprints []string
+		for range 2 {
+			err := qerr.Wrap(nil, qerr.Internal, "boom")
+			fingerprints = append(fingerprints, bugs.New(ctx, "method", err).Fingerprint)
+		}
+		other := bugs.New(ctx, "method", qerr.Wrap(nil, qerr.Internal, "boom")).Fingerprint
+		if fingerprints[0] != fingerprints[1] {
+			t.Error("expected the same fingerprint for the same origin")
+		}
+		if other == fingerprints[0] {
+			t.Error("expected a different fingerprint for another origin")
+		}
+	})
+
+	t.Run("should fingerprint QErrors by code and reason", func(t *testing.T) {
+		var fingerprints []string
+		for _, reason := range []string{"STORE_FULL", "STORE_FULL", "STORE_CLOSED"} {
+			err := qerr.Wrap(nil, qerr.Internal, "boom %s", reason).WithReason(reason)
+			fingerprints = append(fingerprints, bugs.New(ctx, "method", err).Fingerprint)
+		}
+		if fingerprints[0] != fingerprints[1] {
+			t.Error("expected the same fingerprint for the same reason")
+		}
+		if fingerprints[2] == fingerprints[0] {
+			t.Error("expected a different fingerprint for another reason")
+		}
+	})
+
+	t.Run("should log bugs", func(t *testing.T) {
+		var buf bytes.Buffer
+		reporter := bugs.NewLogReporter(slog.New(slog.NewJSONHandler(&buf, nil)))
+		if err := reporter.Report(ctx, bugs.New(ctx, "method", errors.New("boom"))); err != nil {
+			t.Fatal(err)
+		}
+		var entry map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+			t.Fatal(err)
+		}
+		if entry["method"] != "method" || entry["request_id"] != "req-1" || entry["stack_trace"] == "" {
+			t.Errorf("expected the bug fields in the log entry, got %v", entry)
+		}
+	})
+
+	t.Run("should write each bug once to the file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "bugs.jsonl")
+		reporter, err := bugs.NewFileReporter(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		first := bugs.Bug{Message: "first", Fingerprint: "a"}
+		second := bugs.Bug{Message: "second", Fingerprint: "b"}
+		for _, bug := range []bugs.Bug{first, first, second} {
+			if err := reporter.Report(ctx, bug); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// A new reporter knows the bugs already in the file.
+		reporter, err = bugs.NewFileReporter(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := reporter.Report(ctx, second); err != nil {
+			t.Fatal(err)
+		}
+
+		got := readBugs(t, path)
+		if len(got) != 2 {
+			t.Fatalf("expected 2 bugs, got %d", len(got))
+		}
+		if got[0].Message != "first" || got[1].Message != "second" {
+			t.Errorf("expected first and second, got %s and %s", got[0].Message, got[1].Message)
+		}
+	})
+
+	t.Run("should post bugs to the webhook", func(t *testing.T) {
+		var got bugs.Bug
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+				t.Errorf("expected a JSON POST, got %s %s", r.Method, r.Header.Get("Content-Type"))
+			}
+			if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+				t.Error(err)
+			}
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		defer srv.Close()
+
+		bug := bugs.New(ctx, "method", errors.New("boom"))
+		reporter := bugs.NewWebhookReporter(srv.URL, slog.Default())
+		if err := reporter.Report(ctx, bug); err != nil {
+			t.Fatal(err)
+		}
+		if err := reporter.Close(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if got.Fingerprint != bug.Fingerprint || got.StackTrace != bug.StackTrace || got.Method != "method" {
+			t.Errorf("expected the bug to be posted, got %+v", got)
+		}
+	})
+
+	t.Run("should log if the webhook rejects the bug", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer srv.Close()
+
+		var buf bytes.Buffer
+		reporter := bugs.NewWebhookReporter(srv.URL, slog.New(slog.NewJSONHandler(&buf, nil)))
+		if err := reporter.Report(ctx, bugs.Bug{Fingerprint: "a"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := reporter.Close(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buf.String(), "500 Internal Server Error") {
+			t.Errorf("expected the error to be logged, got %s", buf.String())
+		}
+	})
+
+	t.Run("should not wait for the webhook", func(t *testing.T) {
+		release := make(chan struct{})
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}))
+		defer srv.Close()
+
+		reporter := bugs.NewWebhookReporter(srv.URL, slog.Default())
+		// One bug is being posted and the rest fill the queue.
+		var err error
+		for i := 0; err == nil && i < 1000; i++ {
+			err = reporter.Report(ctx, bugs.Bug{})
+		}
+		if err == nil || !strings.Contains(err.Error(), "queue is full") {
+			t.Errorf("expected the queue to fill up, got %v", err)
+		}
+		close(release)
+		if err := reporter.Close(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if err := reporter.Report(ctx, bugs.Bug{}); err == nil {
+			t.Error("expected an error reporting to a closed reporter")
+		}
+	})
+
+	t.Run("should report to all the reporters", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "bugs.jsonl")
+		file, err := bugs.NewFileReporter(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		failing := bugs.NewWebhookReporter("http://127.0.0.1:0", slog.Default())
+		if err := failing.Close(ctx); err != nil {
+			t.Fatal(err)
+		}
+		err = bugs.Multi{failing, file}.Report(ctx, bugs.Bug{Fingerprint: "a"})
+		if err == nil {
+			t.Error("expected the webhook error")
+		}
+		if got := readBugs(t, path); len(got) != 1 {
+			t.Errorf("expected the bug in the file despite the webhook error, got %d", len(got))
+		}
+	})
+}
+
+func readBugs(t *testing.T, path string) []bugs.Bug {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var got []bugs.Bug
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var bug bugs.Bug
+		if err := json.Unmarshal(scanner.Bytes(), &bug); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, bug)
+	}
+	return got
+}
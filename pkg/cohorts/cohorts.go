@@ -0,0 +1,102 @@
+// Package cohorts loads the participants and their cohorts, such as their
+// teams or onboarding batches, so they are also ranked within them. Cohorts
+// are read from YAML or JSON files kept by the operator, as a participant can't
+// be trusted to tell their own, and participants prove who they are with a
+// bearer token listed in the same file.
+package cohorts
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+
+	"github.com/mateopresacastro/qstnnr/pkg/decode"
+)
+
+// NamePattern matches cohort names such as team-payments, batch:2026-10 or
+// gophercon.eu.
+var NamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._:-]{0,63}$`)
+
+// File is the format of a cohorts file: the SHA-256 of the token of every
+// participant in hex, by their name, and the members of every cohort by its
+// name.
+type File struct {
+	Participants map[string]string   `yaml:"participants" json:"participants"`
+	Cohorts      map[string][]string `yaml:"cohorts" json:"cohorts"`
+}
+
+// Membership is the cohorts of every participant by their name, sorted.
+type Membership map[string][]string
+
+// Roster is a parsed cohorts file.
+type Roster struct {
+	// Participants is the name of every participant by the SHA-256 of their
+	// token.
+	Participants map[[sha256.Size]byte]string
+	Members      Membership
+}
+
+// Load reads the cohorts file at path.
+func Load(path string) (*Roster, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading the cohorts file: %w", err)
+	}
+	r, err := Parse(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return r, nil
+}
+
+// Parse reads a cohorts file in YAML or JSON. A participant can be in any
+// number of cohorts, but every member needs a token.
+func Parse(b []byte) (*Roster, error) {
+	var f File
+	if err := decode.File(b, &f); err != nil {
+		return nil, fmt.Errorf("invalid cohorts file: %w", err)
+	}
+	if len(f.Cohorts) == 0 {
+		return nil, errors.New("the file has no cohorts")
+	}
+
+	r := &Roster{Participants: make(map[[sha256.Size]byte]string), Members: make(Membership)}
+	for user, token := range f.Participants {
+		if user == "" {
+			return nil, errors.New("a participant has an empty name")
+		}
+		var hash [sha256.Size]byte
+		if n, err := hex.Decode(hash[:], []byte(token)); err != nil || n != sha256.Size {
+			return nil, fmt.Errorf("invalid token of %q: use the SHA-256 of the token in hex", user)
+		}
+		if other, ok := r.Participants[hash]; ok {
+			return nil, fmt.Errorf("%q and %q have the same token", min(user, other), max(user, other))
+		}
+		r.Participants[hash] = user
+	}
+
+	for name, members := range f.Cohorts {
+		if !NamePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid cohort name %q: use up to 64 letters, digits, dots, colons, dashes and underscores", name)
+		}
+		for _, user := range members {
+			if user == "" {
+				return nil, fmt.Errorf("cohort %q has an empty member", name)
+			}
+			if _, ok := f.Participants[user]; !ok {
+				return nil, fmt.Errorf("%q of cohort %q is not a participant", user, name)
+			}
+			if !slices.Contains(r.Members[user], name) {
+				r.Members[user] = append(r.Members[user], name)
+			}
+		}
+	}
+	for _, names := range r.Members {
+		slices.Sort(names)
+	}
+	return r, nil
+}
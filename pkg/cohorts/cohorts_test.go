@@ -0,0 +1,85 @@
+package cohorts_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mateopresacastro/qstnnr/pkg/cohorts"
+)
+
+func TestCohorts(t *testing.T) {
+	hash := func(token string) string {
+		h := sha256.Sum256([]byte(token))
+		return hex.EncodeToString(h[:])
+	}
+
+	t.Run("should read the participants and their cohorts", func(t *testing.T) {
+		r, err := cohorts.Parse([]byte(fmt.Sprintf(`
+participants:
+  alice: %s
+  bob: %s
+  carol: %s
+cohorts:
+  team-payments: [alice, bob]
+  batch:2026-10:
+    - carol
+    - alice`, hash("alice-token"), hash("bob-token"), hash("carol-token"))))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := cohorts.Membership{"alice": {"batch:2026-10", "team-payments"}, "bob": {"team-payments"}, "carol": {"batch:2026-10"}}
+		if len(r.Members) != len(want) {
+			t.Fatalf("expected %v, got %v", want, r.Members)
+		}
+		for user, names := range want {
+			if !slices.Equal(r.Members[user], names) {
+				t.Errorf("expected %s in %v, got %v", user, names, r.Members[user])
+			}
+		}
+		if user := r.Participants[sha256.Sum256([]byte("alice-token"))]; user != "alice" {
+			t.Errorf("expected the token of alice, got %q", user)
+		}
+	})
+
+	t.Run("should read JSON cohorts files", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cohorts.json")
+		file := fmt.Sprintf(`{"participants": {"gordon": %q}, "cohorts": {"gophers": ["gordon"]}}`, hash("gordon-token"))
+		if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		r, err := cohorts.Load(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(r.Members["gordon"], []string{"gophers"}) {
+			t.Errorf("expected gordon in gophers, got %v", r.Members)
+		}
+	})
+
+	t.Run("should reject invalid cohorts files", func(t *testing.T) {
+		gordon := fmt.Sprintf("participants: {gordon: %s}\n", hash("gordon-token"))
+		cases := []struct {
+			file string
+			err  string
+		}{
+			{file: `cohorts: {}`, err: "no cohorts"},
+			{file: gordon + `cohorts: {"team payments": [gordon]}`, err: "invalid cohort name"},
+			{file: gordon + `cohorts: {gophers: [""]}`, err: "empty member"},
+			{file: gordon + `cohorts: {gophers: [gordon, ken]}`, err: `"ken" of cohort "gophers" is not a participant`},
+			{file: `participants: {gordon: s3cret}` + "\ncohorts: {gophers: [gordon]}", err: `invalid token of "gordon"`},
+			{file: fmt.Sprintf("participants: {gordon: %[1]s, ken: %[1]s}\ncohorts: {gophers: [gordon]}", hash("shared")), err: `"gordon" and "ken" have the same token`},
+			{file: `cohorts: [gophers]`, err: "invalid cohorts file"},
+		}
+		for _, c := range cases {
+			if _, err := cohorts.Parse([]byte(c.file)); err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Errorf("%s: expected an error about %s, got %v", c.file, c.err, err)
+			}
+		}
+	})
+}
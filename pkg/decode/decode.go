@@ -0,0 +1,11 @@
+// Package decode reads the files given to qstnnr, such as quiz banks, event
+// schedules and answers, which can be written in YAML or JSON.
+package decode
+
+import "gopkg.in/yaml.v3"
+
+// File decodes b, in YAML or JSON, into v.
+func File(b []byte, v any) error {
+	// JSON is valid YAML, so this reads both.
+	return yaml.Unmarshal(b, v)
+}
@@ -0,0 +1,33 @@
+package decode_test
+
+import (
+	"testing"
+
+	"github.com/mateopresacastro/qstnnr/pkg/decode"
+)
+
+func TestFile(t *testing.T) {
+	type file struct {
+		Name  string `yaml:"name"`
+		Count int    `yaml:"count"`
+	}
+
+	t.Run("should read YAML and JSON", func(t *testing.T) {
+		for _, b := range []string{"name: gopher\ncount: 2", `{"name": "gopher", "count": 2}`} {
+			var f file
+			if err := decode.File([]byte(b), &f); err != nil {
+				t.Fatal(err)
+			}
+			if f != (file{Name: "gopher", Count: 2}) {
+				t.Errorf("%s: expected gopher and 2, got %+v", b, f)
+			}
+		}
+	})
+
+	t.Run("should reject malformed files", func(t *testing.T) {
+		var f file
+		if err := decode.File([]byte(`{"name": `), &f); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
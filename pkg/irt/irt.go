@@ -0,0 +1,61 @@
+// Package irt estimates abilities with item response theory, using the Rasch
+// model: the chance of answering a question correctly only depends on the
+// difference between the ability of the taker and the difficulty of the
+// question, both on the same logit scale.
+package irt
+
+import "math"
+
+// Response is the answer to a question of the given difficulty.
+type Response struct {
+	Difficulty float64
+	Correct    bool
+}
+
+// Probability returns the chance that a taker of the given ability answers a
+// question of the given difficulty correctly. It is 0.5 when both are equal.
+func Probability(ability, difficulty float64) float64 {
+	return 1 / (1 + math.Exp(difficulty-ability))
+}
+
+// Information returns how much a question of the given difficulty tells about
+// an ability. It peaks when the difficulty matches the ability, so that is the
+// most useful question to ask next.
+func Information(ability, difficulty float64) float64 {
+	p := Probability(ability, difficulty)
+	return p * (1 - p)
+}
+
+const (
+	// MinAbility and MaxAbility bound the abilities considered.
+	MinAbility = -4.0
+	MaxAbility = 4.0
+	// step is the resolution of the estimates.
+	step = 0.05
+)
+
+// Estimate returns the expected ability given the responses (EAP), and its
+// standard error. A standard normal prior keeps the estimate finite when all
+// the answers are correct or all are wrong: without responses it is 0 with a
+// standard error of about 1. Every correct answer raises the estimate and
+// every wrong one lowers it.
+func Estimate(responses []Response) (ability, standardError float64) {
+	var total, mean, squares float64
+	for theta := MinAbility; theta <= MaxAbility+step/2; theta += step {
+		// Prior density, up to a constant that cancels out.
+		w := math.Exp(-theta * theta / 2)
+		for _, r := range responses {
+			p := Probability(theta, r.Difficulty)
+			if !r.Correct {
+				p = 1 - p
+			}
+			w *= p
+		}
+		total += w
+		mean += w * theta
+		squares += w * theta * theta
+	}
+	mean /= total
+	variance := squares/total - mean*mean
+	return mean, math.Sqrt(max(variance, 0))
+}
@@ -0,0 +1,62 @@
+package irt_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mateopresacastro/qstnnr/pkg/irt"
+)
+
+func TestIRT(t *testing.T) {
+	t.Run("should give even odds when the ability matches the difficulty", func(t *testing.T) {
+		if p := irt.Probability(1.5, 1.5); p != 0.5 {
+			t.Errorf("expected a probability of 0.5, got %v", p)
+		}
+		if irt.Probability(2, 0) <= irt.Probability(0, 0) {
+			t.Error("expected a higher ability to make a correct answer more likely")
+		}
+		if irt.Information(0, 0) <= irt.Information(0, 2) {
+			t.Error("expected the information to peak at the ability")
+		}
+	})
+
+	t.Run("should start from the prior without responses", func(t *testing.T) {
+		ability, se := irt.Estimate(nil)
+		if math.Abs(ability) > 1e-9 {
+			t.Errorf("expected an ability of 0, got %v", ability)
+		}
+		if math.Abs(se-1) > 0.01 {
+			t.Errorf("expected a standard error of about 1, got %v", se)
+		}
+	})
+
+	t.Run("should raise the estimate after correct answers and lower it after wrong ones", func(t *testing.T) {
+		var responses []irt.Response
+		prev, prevSE := irt.Estimate(nil)
+		for i, correct := range []bool{true, true, false, true, false, false} {
+			responses = append(responses, irt.Response{Difficulty: float64(i%3 - 1), Correct: correct})
+			ability, se := irt.Estimate(responses)
+			if correct && ability <= prev {
+				t.Errorf("response %d: expected the ability to grow from %v, got %v", i, prev, ability)
+			}
+			if !correct && ability >= prev {
+				t.Errorf("response %d: expected the ability to drop from %v, got %v", i, prev, ability)
+			}
+			if se >= prevSE {
+				t.Errorf("response %d: expected the standard error to shrink from %v, got %v", i, prevSE, se)
+			}
+			prev, prevSE = ability, se
+		}
+	})
+
+	t.Run("should keep the estimate finite with only correct answers", func(t *testing.T) {
+		responses := make([]irt.Response, 20)
+		for i := range responses {
+			responses[i] = irt.Response{Difficulty: 2, Correct: true}
+		}
+		ability, _ := irt.Estimate(responses)
+		if ability <= 2 || ability > irt.MaxAbility {
+			t.Errorf("expected an ability between 2 and %v, got %v", irt.MaxAbility, ability)
+		}
+	})
+}
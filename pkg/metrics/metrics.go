@@ -0,0 +1,128 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// Metrics holds the Prometheus collectors for the server and the service.
+// A nil *Metrics is valid and records nothing, so callers don't need to check
+// whether metrics are enabled.
+type Metrics struct {
+	requests       *prometheus.CounterVec
+	requestLatency *prometheus.HistogramVec
+	submissions    *prometheus.CounterVec
+	scores         *prometheus.HistogramVec
+	storeLatency   *prometheus.HistogramVec
+}
+
+// New creates the collectors and registers them in the given registerer.
+func New(reg prometheus.Registerer) (*Metrics, error) {
+	m := &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "qstnnr",
+			Subsystem: "grpc",
+			Name:      "requests_total",
+			Help:      "Number of gRPC requests handled, by method and status code.",
+		}, []string{"method", "code"}),
+		requestLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "qstnnr",
+			Subsystem: "grpc",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of the gRPC requests, by method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method"}),
+		submissions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "qstnnr",
+			Name:      "submissions_total",
+			Help:      "Number of scored submissions, by quiz.",
+		}, []string{"quiz"}),
+		scores: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "qstnnr",
+			Name:      "submission_score_ratio",
+			Help:      "Distribution of the ratio of correct answers per submission, by quiz.",
+			Buckets:   prometheus.LinearBuckets(0, 0.1, 11),
+		}, []string{"quiz"}),
+		storeLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "qstnnr",
+			Subsystem: "store",
+			Name:      "operation_duration_seconds",
+			Help:      "Latency of the store operations, by operation and result.",
+			Buckets:   prometheus.ExponentialBuckets(0.00001, 4, 10),
+		}, []string{"operation", "result"}),
+	}
+
+	collectors := []prometheus.Collector{
+		m.requests, m.requestLatency, m.submissions, m.scores, m.storeLatency,
+	}
+	for _, c := range collectors {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// Handler serves the metrics gathered by g in the Prometheus exposition format.
+func Handler(g prometheus.Gatherer) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", promhttp.HandlerFor(g, promhttp.HandlerOpts{}))
+	return mux
+}
+
+// UnaryServerInterceptor records the count, latency and status code of every unary RPC.
+func (m *Metrics) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		m.observeRequest(info.FullMethod, time.Since(start), err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor records the count, latency and status code of every streaming RPC.
+func (m *Metrics) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		m.observeRequest(info.FullMethod, time.Since(start), err)
+		return err
+	}
+}
+
+func (m *Metrics) observeRequest(method string, d time.Duration, err error) {
+	if m == nil {
+		return
+	}
+	m.requests.WithLabelValues(method, status.Code(err).String()).Inc()
+	m.requestLatency.WithLabelValues(method).Observe(d.Seconds())
+}
+
+// ObserveSubmission records a scored submission for the given quiz.
+func (m *Metrics) ObserveSubmission(quiz string, correct, total int) {
+	if m == nil {
+		return
+	}
+	m.submissions.WithLabelValues(quiz).Inc()
+	if total > 0 {
+		m.scores.WithLabelValues(quiz).Observe(float64(correct) / float64(total))
+	}
+}
+
+// ObserveStoreOperation records the latency of a store operation.
+func (m *Metrics) ObserveStoreOperation(operation string, d time.Duration, err error) {
+	if m == nil {
+		return
+	}
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	m.storeLatency.WithLabelValues(operation, result).Observe(d.Seconds())
+}
@@ -0,0 +1,113 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mateopresacastro/qstnnr/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m, err := metrics.New(reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("should fail registering twice", func(t *testing.T) {
+		if _, err := metrics.New(reg); err == nil {
+			t.Fatal("expected error registering the collectors twice")
+		}
+	})
+
+	t.Run("should count requests by method and code", func(t *testing.T) {
+		interceptor := m.UnaryServerInterceptor()
+		info := &grpc.UnaryServerInfo{FullMethod: "/api.Questionnaire/SubmitAnswers"}
+		ok := func(ctx context.Context, req any) (any, error) { return "ok", nil }
+		invalid := func(ctx context.Context, req any) (any, error) {
+			return nil, status.Error(codes.InvalidArgument, "invalid")
+		}
+
+		for range 2 {
+			if _, err := interceptor(context.Background(), nil, info, ok); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := interceptor(context.Background(), nil, info, invalid); err == nil {
+			t.Fatal("expected the handler error to be returned")
+		}
+
+		expected := `
+# HELP qstnnr_grpc_requests_total Number of gRPC requests handled, by method and status code.
+# TYPE qstnnr_grpc_requests_total counter
+qstnnr_grpc_requests_total{code="InvalidArgument",method="/api.Questionnaire/SubmitAnswers"} 1
+qstnnr_grpc_requests_total{code="OK",method="/api.Questionnaire/SubmitAnswers"} 2
+`
+		if err := testutil.GatherAndCompare(reg, strings.NewReader(expected), "qstnnr_grpc_requests_total"); err != nil {
+			t.Fatal(err)
+		}
+		if n := testutil.CollectAndCount(reg, "qstnnr_grpc_request_duration_seconds"); n != 1 {
+			t.Fatalf("expected 1 latency series, got %d", n)
+		}
+	})
+
+	t.Run("should record submissions and scores", func(t *testing.T) {
+		m.ObserveSubmission("default", 3, 4)
+		m.ObserveSubmission("default", 4, 4)
+
+		expected := `
+# HELP qstnnr_submissions_total Number of scored submissions, by quiz.
+# TYPE qstnnr_submissions_total counter
+qstnnr_submissions_total{quiz="default"} 2
+`
+		if err := testutil.GatherAndCompare(reg, strings.NewReader(expected), "qstnnr_submissions_total"); err != nil {
+			t.Fatal(err)
+		}
+		if n := testutil.CollectAndCount(reg, "qstnnr_submission_score_ratio"); n != 1 {
+			t.Fatalf("expected 1 score series, got %d", n)
+		}
+	})
+
+	t.Run("should record store operations by result", func(t *testing.T) {
+		m.ObserveStoreOperation("save_score", time.Millisecond, nil)
+		m.ObserveStoreOperation("save_score", time.Millisecond, errors.New("boom"))
+		if n := testutil.CollectAndCount(reg, "qstnnr_store_operation_duration_seconds"); n != 2 {
+			t.Fatalf("expected 2 store series, got %d", n)
+		}
+	})
+
+	t.Run("should do nothing when nil", func(t *testing.T) {
+		var m *metrics.Metrics
+		m.ObserveSubmission("default", 1, 2)
+		m.ObserveStoreOperation("ping", time.Millisecond, nil)
+	})
+
+	t.Run("should serve the metrics", func(t *testing.T) {
+		srv := httptest.NewServer(metrics.Handler(reg))
+		defer srv.Close()
+
+		resp, err := http.Get(srv.URL + "/metrics")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(body), "qstnnr_submissions_total") {
+			t.Fatal("expected the submissions metric to be exposed")
+		}
+	})
+}
@@ -8,10 +8,62 @@ import (
 type ErrorCode int
 
 const (
-	Unknown      ErrorCode = iota
-	InvalidInput           // For validation errors
-	NotFound               // For missing resources
-	Internal               // For system errors
+	Unknown            ErrorCode = iota
+	InvalidInput                 // For validation errors
+	NotFound                     // For missing resources
+	Internal                     // For system errors
+	AlreadyExists                // For resources that can only be created once, e.g. a submitted attempt
+	FailedPrecondition           // For operations not allowed in the current state, e.g. a closed quiz
+	PermissionDenied             // For callers not allowed to do the operation
+	Unauthenticated              // For callers that couldn't be identified
+	ResourceExhausted            // For callers over a limit, e.g. rate limited
+	DeadlineExceeded             // For operations that ran out of time
+	Unavailable                  // For dependencies that can't be reached, worth retrying
+)
+
+// String returns the name of the code in UPPER_SNAKE_CASE. It is used as the
+// default reason of an error.
+func (c ErrorCode) String() string {
+	switch c {
+	case InvalidInput:
+		return "INVALID_INPUT"
+	case NotFound:
+		return "NOT_FOUND"
+	case Internal:
+		return "INTERNAL"
+	case AlreadyExists:
+		return "ALREADY_EXISTS"
+	case FailedPrecondition:
+		return "FAILED_PRECONDITION"
+	case PermissionDenied:
+		return "PERMISSION_DENIED"
+	case Unauthenticated:
+		return "UNAUTHENTICATED"
+	case ResourceExhausted:
+		return "RESOURCE_EXHAUSTED"
+	case DeadlineExceeded:
+		return "DEADLINE_EXCEEDED"
+	case Unavailable:
+		return "UNAVAILABLE"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// Sentinel errors to match a QError by code with errors.Is, e.g.
+// errors.Is(err, qerr.ErrNotFound).
+var (
+	ErrUnknown            = QError{Code: Unknown, Message: "unknown error"}
+	ErrInvalidInput       = QError{Code: InvalidInput, Message: "invalid input"}
+	ErrNotFound           = QError{Code: NotFound, Message: "not found"}
+	ErrInternal           = QError{Code: Internal, Message: "internal error"}
+	ErrAlreadyExists      = QError{Code: AlreadyExists, Message: "already exists"}
+	ErrFailedPrecondition = QError{Code: FailedPrecondition, Message: "failed precondition"}
+	ErrPermissionDenied   = QError{Code: PermissionDenied, Message: "permission denied"}
+	ErrUnauthenticated    = QError{Code: Unauthenticated, Message: "unauthenticated"}
+	ErrResourceExhausted  = QError{Code: ResourceExhausted, Message: "resource exhausted"}
+	ErrDeadlineExceeded   = QError{Code: DeadlineExceeded, Message: "deadline exceeded"}
+	ErrUnavailable        = QError{Code: Unavailable, Message: "unavailable"}
 )
 
 type QError struct {
@@ -20,6 +72,17 @@ type QError struct {
 	StackTrace string
 	Code       ErrorCode
 	Misc       map[string]any
+	// Reason identifies the cause of the error in UPPER_SNAKE_CASE so clients
+	// can handle it without parsing the message, e.g. UNKNOWN_QUESTION.
+	Reason string
+	// Violations lists the fields of the request that were invalid.
+	Violations []FieldViolation
+}
+
+// FieldViolation describes why a single field of a request is invalid.
+type FieldViolation struct {
+	Field       string
+	Description string
 }
 
 // WrapError creates a QError. It adds more information to the error
@@ -34,6 +97,41 @@ func Wrap(err error, code ErrorCode, messagef string, msgArgs ...any) QError {
 	}
 }
 
+// WithReason returns a copy of the error with the given reason.
+func (err QError) WithReason(reason string) QError {
+	err.Reason = reason
+	return err
+}
+
+// WithViolations returns a copy of the error with the given violations added.
+func (err QError) WithViolations(violations ...FieldViolation) QError {
+	err.Violations = append(append([]FieldViolation(nil), err.Violations...), violations...)
+	return err
+}
+
+// WithMisc returns a copy of the error with key set to value in Misc.
+func (err QError) WithMisc(key string, value any) QError {
+	misc := make(map[string]any, len(err.Misc)+1)
+	for k, v := range err.Misc {
+		misc[k] = v
+	}
+	misc[key] = value
+	err.Misc = misc
+	return err
+}
+
 func (err QError) Error() string {
 	return err.Message
 }
+
+// Unwrap gives access to the error that caused this one.
+func (err QError) Unwrap() error {
+	return err.Inner
+}
+
+// Is reports whether target is a QError with the same code. It makes the
+// sentinel errors match any error with their code.
+func (err QError) Is(target error) bool {
+	t, ok := target.(QError)
+	return ok && t.Code == err.Code
+}
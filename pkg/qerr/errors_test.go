@@ -0,0 +1,70 @@
+package qerr_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/mateopresacastro/qstnnr/pkg/qerr"
+)
+
+func TestQError(t *testing.T) {
+	t.Run("should unwrap the inner error", func(t *testing.T) {
+		err := qerr.Wrap(context.DeadlineExceeded, qerr.DeadlineExceeded, "too slow")
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatal("expected to find the inner error")
+		}
+	})
+
+	t.Run("should match the sentinel of its code", func(t *testing.T) {
+		err := fmt.Errorf("submitting: %w", qerr.Wrap(nil, qerr.AlreadyExists, "attempt %d already submitted", 3))
+		if !errors.Is(err, qerr.ErrAlreadyExists) {
+			t.Fatal("expected to match ErrAlreadyExists")
+		}
+		if errors.Is(err, qerr.ErrNotFound) {
+			t.Fatal("expected not to match ErrNotFound")
+		}
+	})
+
+	t.Run("should be extracted with errors.As", func(t *testing.T) {
+		err := fmt.Errorf("limiting: %w", qerr.Wrap(nil, qerr.ResourceExhausted, "slow down"))
+		var qErr qerr.QError
+		if !errors.As(err, &qErr) {
+			t.Fatal("expected a QError")
+		}
+		if qErr.Code != qerr.ResourceExhausted || qErr.Message != "slow down" {
+			t.Fatalf("unexpected QError: %v", qErr)
+		}
+	})
+
+	t.Run("should name every code", func(t *testing.T) {
+		codes := []qerr.ErrorCode{
+			qerr.Unknown, qerr.InvalidInput, qerr.NotFound, qerr.Internal,
+			qerr.AlreadyExists, qerr.FailedPrecondition, qerr.PermissionDenied,
+			qerr.Unauthenticated, qerr.ResourceExhausted, qerr.DeadlineExceeded,
+			qerr.Unavailable,
+		}
+		seen := make(map[string]bool)
+		for _, code := range codes {
+			name := code.String()
+			if seen[name] {
+				t.Errorf("duplicated name %s", name)
+			}
+			seen[name] = true
+		}
+	})
+
+	t.Run("should not share violations between copies", func(t *testing.T) {
+		base := qerr.Wrap(nil, qerr.InvalidInput, "invalid")
+		a := base.WithViolations(qerr.FieldViolation{Field: "a"})
+		b := a.WithViolations(qerr.FieldViolation{Field: "b"})
+		c := a.WithViolations(qerr.FieldViolation{Field: "c"}).WithMisc("k", 1)
+		if len(a.Violations) != 1 || b.Violations[1].Field != "b" || c.Violations[1].Field != "c" {
+			t.Fatalf("violations were shared: %v %v %v", a.Violations, b.Violations, c.Violations)
+		}
+		if _, ok := a.Misc["k"]; ok {
+			t.Fatal("misc was shared")
+		}
+	})
+}
@@ -0,0 +1,307 @@
+package qservice
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/mateopresacastro/qstnnr/pkg/irt"
+	"github.com/mateopresacastro/qstnnr/pkg/qerr"
+	"github.com/mateopresacastro/qstnnr/pkg/reqctx"
+	"github.com/mateopresacastro/qstnnr/pkg/store"
+)
+
+// adaptiveQuiz labels the metrics of the adaptive quizzes, whose raw scores
+// can't be compared with those of the full questionnaire.
+const adaptiveQuiz = "adaptive"
+
+// maxAdaptiveRetries is how many times an answer is tried again when the
+// attempt is updated concurrently, e.g. by a retry of the same answer.
+const maxAdaptiveRetries = 3
+
+// AdaptiveResult is the state of an adaptive quiz after starting it or
+// answering one of its questions.
+type AdaptiveResult struct {
+	AttemptID string
+	// Question is the question to answer next, nil once the quiz is over.
+	Question *store.Question
+	// Asked is the number of questions asked so far, including the next one,
+	// out of Total.
+	Asked   int
+	Total   int
+	Correct int
+	// Ability is the estimated ability of the caller, on the logit scale of
+	// the difficulties: 0 matches medium questions and every point is one
+	// difficulty level. StandardError tells how precise the estimate is.
+	Ability       float64
+	StandardError float64
+	// Answers and Solutions are only given once the quiz is over, so the
+	// answers of the questions already asked can't be looked up mid-quiz.
+	Answers   []store.AdaptiveAnswer
+	Solutions map[store.QuestionID]store.OptionID
+}
+
+// Finished reports whether the quiz is over.
+func (r *AdaptiveResult) Finished() bool {
+	return r.Question == nil
+}
+
+// StartAdaptive starts an adaptive quiz of up to maxQuestions questions, all of
+// them if zero. The first question is of medium difficulty and every following
+// one is harder after a correct answer and easier after a wrong one. Adaptive
+// quizzes ask the questions of the store, never those of the events, so they
+// can be taken at any time.
+func (qs *QstnnrService) StartAdaptive(ctx context.Context, maxQuestions int) (*AdaptiveResult, error) {
+	if maxQuestions < 0 {
+		qErr := qerr.Wrap(nil, qerr.InvalidInput, "the number of questions cannot be negative: %d", maxQuestions).
+			WithReason("INVALID_MAX_QUESTIONS").
+			WithViolations(qerr.FieldViolation{Field: "max_questions", Description: "use 0 to ask all the questions"})
+		return nil, ServiceError{qErr}
+	}
+	qsts, err := qs.store.Questions(ctx)
+	if err != nil {
+		return nil, storeErr(err, "failed to get questions")
+	}
+	if maxQuestions == 0 || maxQuestions > len(qsts) {
+		maxQuestions = len(qsts)
+	}
+
+	now := time.Now()
+	attempt := store.AdaptiveAttempt{
+		ID:           reqctx.NewID(),
+		User:         reqctx.Caller(ctx),
+		MaxQuestions: maxQuestions,
+		Current:      nextQuestion(qsts, nil, 0),
+		StartedAt:    now,
+		UpdatedAt:    now,
+	}
+	if err := qs.store.SaveAdaptiveAttempt(ctx, attempt); err != nil {
+		return nil, storeErr(err, "failed to save adaptive attempt")
+	}
+	return qs.adaptiveResult(ctx, attempt, qsts)
+}
+
+// AnswerAdaptive answers the current question of an adaptive quiz and returns
+// the next one. Retrying the last answer returns the same result.
+func (qs *QstnnrService) AnswerAdaptive(ctx context.Context, attemptID string, qID store.QuestionID, oID store.OptionID) (*AdaptiveResult, error) {
+	for range maxAdaptiveRetries {
+		result, err := qs.answerAdaptive(ctx, attemptID, qID, oID)
+		if !errors.Is(err, store.ErrAdaptiveAttemptConflict) {
+			return result, err
+		}
+	}
+	qErr := qerr.Wrap(nil, qerr.Unavailable, "the adaptive quiz %s is being answered concurrently, try again", attemptID).
+		WithReason("ADAPTIVE_QUIZ_CONFLICT")
+	return nil, ServiceError{qErr}
+}
+
+// answerAdaptive reads the attempt, answers it and saves it. It returns the
+// store error wrapping ErrAdaptiveAttemptConflict as is if the attempt was
+// saved in between, to be read again.
+func (qs *QstnnrService) answerAdaptive(ctx context.Context, attemptID string, qID store.QuestionID, oID store.OptionID) (*AdaptiveResult, error) {
+	attempt, err := qs.store.AdaptiveAttempt(ctx, attemptID)
+	if err != nil {
+		if errors.Is(err, store.ErrAdaptiveAttemptNotFound) {
+			return nil, adaptiveNotFoundError(attemptID)
+		}
+		return nil, storeErr(err, "failed to get adaptive attempt")
+	}
+	// Someone else's attempt is reported as missing, so IDs can't be probed.
+	if attempt.User != reqctx.Caller(ctx) {
+		return nil, adaptiveNotFoundError(attemptID)
+	}
+
+	qsts, err := qs.store.Questions(ctx)
+	if err != nil {
+		return nil, storeErr(err, "failed to get questions")
+	}
+
+	if n := len(attempt.Answers); n > 0 {
+		last := attempt.Answers[n-1]
+		if last.QuestionID == qID && last.OptionID == oID {
+			return qs.adaptiveResult(ctx, attempt, qsts)
+		}
+	}
+	if attempt.Current == 0 {
+		qErr := qerr.Wrap(nil, qerr.FailedPrecondition, "the adaptive quiz %s is over", attemptID).
+			WithReason("ADAPTIVE_QUIZ_OVER")
+		return nil, ServiceError{qErr}
+	}
+	if qID != attempt.Current {
+		qErr := qerr.Wrap(nil, qerr.InvalidInput, "question %d is not the current question of the quiz", qID).
+			WithReason("NOT_CURRENT_QUESTION").
+			WithMisc("question_id", qID).
+			WithMisc("current_question_id", attempt.Current).
+			WithViolations(qerr.FieldViolation{
+				Field:       "answer.question_id",
+				Description: "answer the question returned by the previous call",
+			})
+		return nil, ServiceError{qErr}
+	}
+	if _, ok := qsts[qID].Options[oID]; !ok {
+		qErr := qerr.Wrap(nil, qerr.InvalidInput, "couldn't find option %d of question %d", oID, qID).
+			WithReason("UNKNOWN_OPTION").
+			WithMisc("option_id", oID).
+			WithViolations(qerr.FieldViolation{
+				Field:       "answer.option_id",
+				Description: "choose one of the options of the question",
+			})
+		return nil, ServiceError{qErr}
+	}
+
+	solutions, err := qs.store.Solutions(ctx)
+	if err != nil {
+		return nil, storeErr(err, "failed to get solutions")
+	}
+
+	attempt.Answers = append(attempt.Answers, store.AdaptiveAnswer{
+		QuestionID: qID,
+		OptionID:   oID,
+		Correct:    solutions[qID] == oID,
+	})
+	attempt.Current = 0
+	if len(attempt.Answers) < attempt.MaxQuestions {
+		ability, _ := irt.Estimate(responses(attempt.Answers, qsts))
+		attempt.Current = nextQuestion(qsts, attempt.Answers, ability)
+	}
+	attempt.UpdatedAt = time.Now()
+	if err := qs.store.SaveAdaptiveAttempt(ctx, attempt); err != nil {
+		if errors.Is(err, store.ErrAdaptiveAttemptConflict) {
+			return nil, err
+		}
+		return nil, storeErr(err, "failed to save adaptive attempt")
+	}
+	if attempt.Current == 0 {
+		if err := qs.saveAdaptiveScore(ctx, attempt); err != nil {
+			return nil, err
+		}
+		qs.metrics.ObserveSubmission(adaptiveQuiz, correctAnswers(attempt.Answers), len(attempt.Answers))
+	}
+	return qs.adaptiveResult(ctx, attempt, qsts)
+}
+
+// saveAdaptiveScore records a finished adaptive quiz with the other attempts,
+// for the score statistics.
+func (qs *QstnnrService) saveAdaptiveScore(ctx context.Context, attempt store.AdaptiveAttempt) error {
+	answers := make(map[store.QuestionID]store.OptionID, len(attempt.Answers))
+	for _, a := range attempt.Answers {
+		answers[a.QuestionID] = a.OptionID
+	}
+	err := qs.store.SaveAttempt(ctx, store.Attempt{
+		User:        attempt.User,
+		Quiz:        adaptiveQuiz,
+		Score:       correctAnswers(attempt.Answers),
+		Total:       len(attempt.Answers),
+		SubmittedAt: attempt.UpdatedAt,
+		Answers:     answers,
+	})
+	if err != nil {
+		return storeErr(err, "failed to save attempt")
+	}
+	return nil
+}
+
+// adaptiveResult describes the state of an attempt.
+func (qs *QstnnrService) adaptiveResult(ctx context.Context, attempt store.AdaptiveAttempt, qsts map[store.QuestionID]store.Question) (*AdaptiveResult, error) {
+	result := &AdaptiveResult{
+		AttemptID: attempt.ID,
+		Asked:     len(attempt.Answers),
+		Total:     attempt.MaxQuestions,
+		Correct:   correctAnswers(attempt.Answers),
+	}
+	result.Ability, result.StandardError = irt.Estimate(responses(attempt.Answers, qsts))
+	if attempt.Current != 0 {
+		q := qsts[attempt.Current]
+		result.Question = &q
+		result.Asked++
+		return result, nil
+	}
+
+	result.Answers = attempt.Answers
+	solutions, err := qs.store.Solutions(ctx)
+	if err != nil {
+		return nil, storeErr(err, "failed to get solutions")
+	}
+	result.Solutions = make(map[store.QuestionID]store.OptionID, len(attempt.Answers))
+	for _, a := range attempt.Answers {
+		result.Solutions[a.QuestionID] = solutions[a.QuestionID]
+	}
+	return result, nil
+}
+
+// nextQuestion returns the question to ask after the given answers: a harder
+// one than the last after a correct answer and an easier one after a wrong
+// one, as close as possible to the ability so it tells the most about it. When
+// there is none in that direction, the closest of the rest. Zero if all the
+// questions were asked.
+func nextQuestion(qsts map[store.QuestionID]store.Question, answers []store.AdaptiveAnswer, ability float64) store.QuestionID {
+	asked := make(map[store.QuestionID]bool, len(answers))
+	for _, a := range answers {
+		asked[a.QuestionID] = true
+	}
+	var left, candidates []store.QuestionID
+	for _, qID := range slices.Sorted(maps.Keys(qsts)) {
+		if !asked[qID] {
+			left = append(left, qID)
+		}
+	}
+	if n := len(answers); n > 0 {
+		last := answers[n-1]
+		lastDifficulty := difficulty(qsts[last.QuestionID])
+		for _, qID := range left {
+			d := difficulty(qsts[qID])
+			if last.Correct && d > lastDifficulty || !last.Correct && d < lastDifficulty {
+				candidates = append(candidates, qID)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = left
+	}
+
+	var next store.QuestionID
+	best := math.Inf(1)
+	for _, qID := range candidates {
+		if distance := math.Abs(difficulty(qsts[qID]) - ability); distance < best {
+			next, best = qID, distance
+		}
+	}
+	return next
+}
+
+// difficulty returns the difficulty of a question on the logit scale of the
+// abilities, with medium questions at 0.
+func difficulty(q store.Question) float64 {
+	d := q.Difficulty
+	if d == 0 {
+		d = store.MediumDifficulty
+	}
+	return float64(d - store.MediumDifficulty)
+}
+
+func responses(answers []store.AdaptiveAnswer, qsts map[store.QuestionID]store.Question) []irt.Response {
+	rs := make([]irt.Response, len(answers))
+	for i, a := range answers {
+		rs[i] = irt.Response{Difficulty: difficulty(qsts[a.QuestionID]), Correct: a.Correct}
+	}
+	return rs
+}
+
+func correctAnswers(answers []store.AdaptiveAnswer) int {
+	correct := 0
+	for _, a := range answers {
+		if a.Correct {
+			correct++
+		}
+	}
+	return correct
+}
+
+func adaptiveNotFoundError(attemptID string) error {
+	qErr := qerr.Wrap(nil, qerr.NotFound, "couldn't find adaptive quiz %s", attemptID).
+		WithReason("ADAPTIVE_QUIZ_NOT_FOUND")
+	return ServiceError{qErr}
+}
@@ -0,0 +1,225 @@
+package qservice
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mateopresacastro/qstnnr/pkg/cohorts"
+	"github.com/mateopresacastro/qstnnr/pkg/qerr"
+	"github.com/mateopresacastro/qstnnr/pkg/reqctx"
+	"github.com/mateopresacastro/qstnnr/pkg/store"
+)
+
+const (
+	// defaultLeaderboardSize is the number of participants on the leaderboard
+	// when no limit is given, and maxLeaderboardSize the most it can show.
+	defaultLeaderboardSize = 10
+	maxLeaderboardSize     = 100
+	// maxDisplayNameLength is the most characters a display name can have.
+	maxDisplayNameLength = 40
+)
+
+// WithCohorts ranks the submissions of the members of the cohorts within one
+// of their cohorts too. Members are the participants authenticated by their
+// token, see reqctx.Participant, and nobody is in a cohort by default.
+func WithCohorts(m cohorts.Membership) Option {
+	return func(qs *QstnnrService) {
+		qs.cohorts = m
+	}
+}
+
+// Leaderboard ranks the participants by the best score of their attempts.
+// Only the participants who submitted with a display name are listed, but all
+// the attempts count for the percentile ranks.
+type Leaderboard struct {
+	// Quiz is the quiz ranked, and Cohort the cohort ranked, empty for all the
+	// participants.
+	Quiz   string
+	Cohort string
+	// Participants is the number of participants listed, of which Entries has
+	// the best ones. Anonymous callers are not listed.
+	Participants int
+	Entries      []LeaderboardEntry
+}
+
+// LeaderboardEntry is the best attempt of a participant.
+type LeaderboardEntry struct {
+	// Rank is shared by the participants with the same score, and the next
+	// ones skip as many places as the tie has participants.
+	Rank int
+	// DisplayName is the name given with the best attempt, and You tells if
+	// the participant is the caller.
+	DisplayName string
+	You         bool
+	Score       store.Score
+	Total       int
+	SubmittedAt time.Time
+	// BetterThan is the percentile rank of the score among all the attempts,
+	// and CohortBetterThan among those of the cohort, if any.
+	BetterThan       store.Stat
+	CohortBetterThan store.Stat
+}
+
+// validateCohort checks the name of a cohort, if any.
+func validateCohort(cohort string) error {
+	if cohort == "" || cohorts.NamePattern.MatchString(cohort) {
+		return nil
+	}
+	qErr := qerr.Wrap(nil, qerr.InvalidInput, "invalid cohort %q", cohort).
+		WithReason("INVALID_COHORT").
+		WithViolations(qerr.FieldViolation{
+			Field:       "cohort",
+			Description: "use up to 64 letters, digits, dots, colons, dashes and underscores",
+		})
+	return ServiceError{qErr}
+}
+
+// validateDisplayName checks the name to show on the leaderboard, if any.
+func validateDisplayName(name string) error {
+	if name == "" {
+		return nil
+	}
+	if utf8.RuneCountInString(name) <= maxDisplayNameLength &&
+		strings.TrimSpace(name) == name &&
+		!strings.ContainsFunc(name, func(r rune) bool { return !unicode.IsPrint(r) }) {
+		return nil
+	}
+	qErr := qerr.Wrap(nil, qerr.InvalidInput, "invalid display name %q", name).
+		WithReason("INVALID_DISPLAY_NAME").
+		WithViolations(qerr.FieldViolation{
+			Field:       "display_name",
+			Description: fmt.Sprintf("use up to %d printable characters, without leading or trailing spaces", maxDisplayNameLength),
+		})
+	return ServiceError{qErr}
+}
+
+// cohortOf returns the cohort to rank a submission of the participant in:
+// the given one, which must be one of theirs, or their only cohort if none is
+// given. It's empty for the participants in several cohorts who didn't choose
+// one, and for the requests that didn't authenticate as a participant.
+func (qs *QstnnrService) cohortOf(ctx context.Context, cohort string) (string, error) {
+	if err := validateCohort(cohort); err != nil {
+		return "", err
+	}
+	var member []string
+	if participant := reqctx.Participant(ctx); participant != "" {
+		member = qs.cohorts[participant]
+	}
+	if cohort == "" {
+		if len(member) == 1 {
+			return member[0], nil
+		}
+		return "", nil
+	}
+	if !slices.Contains(member, cohort) {
+		qErr := qerr.Wrap(nil, qerr.PermissionDenied, "not a member of cohort %q", cohort).
+			WithReason("NOT_A_MEMBER").
+			WithMisc("cohort", cohort)
+		return "", ServiceError{qErr}
+	}
+	return cohort, nil
+}
+
+// cohortStats calculates the percentile rank of a score among the scores of
+// the cohort in a quiz, including it.
+func (qs *QstnnrService) cohortStats(ctx context.Context, quiz, cohort string, score store.Score) (store.Stat, error) {
+	scores, err := qs.store.Scores(ctx, quiz, cohort)
+	if err != nil {
+		return 0, err
+	}
+	// The score is not saved yet, but it's one of the participants.
+	scores = append(scores, score)
+	return percentileRank(scores, score, qs.rankMethod), nil
+}
+
+// Leaderboard returns the best limit participants of a quiz, named as in
+// Solutions, 10 if zero, by the best score of their attempts with a display
+// name. With a cohort, only the attempts in the cohort are ranked.
+func (qs *QstnnrService) Leaderboard(ctx context.Context, quiz, cohort string, limit int) (*Leaderboard, error) {
+	event, err := qs.quizEvent(quiz, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	quiz = quizName(event)
+	if err := validateCohort(cohort); err != nil {
+		return nil, err
+	}
+	if limit < 0 || limit > maxLeaderboardSize {
+		qErr := qerr.Wrap(nil, qerr.InvalidInput, "the limit must be between 0 and %d: %d", maxLeaderboardSize, limit).
+			WithReason("INVALID_LIMIT").
+			WithViolations(qerr.FieldViolation{Field: "limit", Description: "use 0 for the top 10"})
+		return nil, ServiceError{qErr}
+	}
+	if limit == 0 {
+		limit = defaultLeaderboardSize
+	}
+
+	attempts, err := qs.store.AllAttempts(ctx)
+	if err != nil {
+		return nil, storeErr(err, "failed to get attempts")
+	}
+
+	var all, inCohort []store.Score
+	best := make(map[string]store.Attempt)
+	for _, a := range attempts {
+		if a.Quiz != quiz {
+			continue
+		}
+		all = append(all, a.Score)
+		if cohort != "" && a.Cohort != cohort {
+			continue
+		}
+		inCohort = append(inCohort, a.Score)
+		if a.User == reqctx.Anonymous || a.DisplayName == "" {
+			continue
+		}
+		// The first attempt with the best score is kept, so the earliest
+		// wins a tie.
+		if b, ok := best[a.User]; !ok || a.Score > b.Score {
+			best[a.User] = a
+		}
+	}
+
+	caller := reqctx.Caller(ctx)
+	entries := make([]LeaderboardEntry, 0, len(best))
+	for _, a := range best {
+		entry := LeaderboardEntry{
+			DisplayName: a.DisplayName,
+			You:         a.User == caller,
+			Score:       a.Score,
+			Total:       a.Total,
+			SubmittedAt: a.SubmittedAt,
+			BetterThan:  percentileRank(all, a.Score, qs.rankMethod),
+		}
+		if cohort != "" {
+			entry.CohortBetterThan = percentileRank(inCohort, a.Score, qs.rankMethod)
+		}
+		entries = append(entries, entry)
+	}
+	slices.SortFunc(entries, func(a, b LeaderboardEntry) int {
+		return cmp.Or(
+			cmp.Compare(b.Score, a.Score),
+			a.SubmittedAt.Compare(b.SubmittedAt),
+			cmp.Compare(a.DisplayName, b.DisplayName),
+		)
+	})
+	for i := range entries {
+		entries[i].Rank = i + 1
+		if i > 0 && entries[i].Score == entries[i-1].Score {
+			entries[i].Rank = entries[i-1].Rank
+		}
+	}
+
+	return &Leaderboard{
+		Quiz:         quiz,
+		Cohort:       cohort,
+		Participants: len(entries),
+		Entries:      entries[:min(limit, len(entries))],
+	}, nil
+}
@@ -0,0 +1,196 @@
+package qservice
+
+import (
+	"context"
+	"time"
+
+	"github.com/mateopresacastro/qstnnr/pkg/qerr"
+	"github.com/mateopresacastro/qstnnr/pkg/schedule"
+	"github.com/mateopresacastro/qstnnr/pkg/store"
+)
+
+// WithSchedule limits the questionnaire to the windows of the events, ordered
+// by opening time and not overlapping, as returned by schedule.Load. Every
+// event is a quiz of its own, with the questions of its bank instead of those
+// of the store, and the solutions of its quiz are withheld until it closes.
+// Without events the questionnaire of the store is always open.
+func WithSchedule(events []schedule.Event) Option {
+	return func(qs *QstnnrService) {
+		qs.events = events
+	}
+}
+
+// ScheduledEvent is an event and its status when it was listed.
+type ScheduledEvent struct {
+	schedule.Event
+	Status schedule.Status
+}
+
+// Events returns the upcoming and open events, ordered by opening time.
+func (qs *QstnnrService) Events(ctx context.Context) ([]ScheduledEvent, error) {
+	now := time.Now()
+	var events []ScheduledEvent
+	for _, e := range qs.events {
+		if status := e.Status(now); status != schedule.Closed {
+			events = append(events, ScheduledEvent{Event: e, Status: status})
+		}
+	}
+	return events, nil
+}
+
+// openEvent returns the event open at now, nil if none.
+func (qs *QstnnrService) openEvent(now time.Time) *schedule.Event {
+	for i, e := range qs.events {
+		if e.Status(now) == schedule.Open {
+			return &qs.events[i]
+		}
+	}
+	return nil
+}
+
+// checkOpen returns the event open at now, whose quiz is the one to take, or
+// nil if the questionnaire has no events. It fails with FailedPrecondition if
+// none of the events is open.
+func (qs *QstnnrService) checkOpen(now time.Time) (*schedule.Event, error) {
+	if len(qs.events) == 0 {
+		return nil, nil
+	}
+	if e := qs.openEvent(now); e != nil {
+		return e, nil
+	}
+	for _, e := range qs.events {
+		if e.Status(now) == schedule.Upcoming {
+			msg := "the quiz is closed until %s opens at %s"
+			qErr := qerr.Wrap(nil, qerr.FailedPrecondition, msg, eventName(e), e.OpensAt.UTC().Format(time.RFC3339)).
+				WithReason("QUIZ_CLOSED").
+				WithMisc("event", e.Name).
+				WithMisc("opens_at", e.OpensAt.UTC().Format(time.RFC3339))
+			return nil, ServiceError{qErr}
+		}
+	}
+	qErr := qerr.Wrap(nil, qerr.FailedPrecondition, "the quiz is closed, there are no upcoming events").
+		WithReason("QUIZ_CLOSED")
+	return nil, ServiceError{qErr}
+}
+
+// quizEvent returns the event of a quiz by its name, nil for the questionnaire
+// of the store. Without a name, it is the last event that opened by now, or
+// the questionnaire of the store if none did.
+func (qs *QstnnrService) quizEvent(name string, now time.Time) (*schedule.Event, error) {
+	switch name {
+	case "":
+		var last *schedule.Event
+		for i, e := range qs.events {
+			if e.Status(now) != schedule.Upcoming {
+				last = &qs.events[i]
+			}
+		}
+		return last, nil
+	case DefaultQuiz:
+		return nil, nil
+	}
+	for i, e := range qs.events {
+		if e.Name == name {
+			return &qs.events[i], nil
+		}
+	}
+	qErr := qerr.Wrap(nil, qerr.InvalidInput, "unknown quiz %q", name).
+		WithReason("UNKNOWN_QUIZ").
+		WithViolations(qerr.FieldViolation{Field: "quiz", Description: "use default or the name of an event"})
+	return nil, ServiceError{qErr}
+}
+
+// quizName names the quiz of an event in the attempts and the metrics, or the
+// questionnaire of the store if e is nil.
+func quizName(e *schedule.Event) string {
+	if e == nil {
+		return DefaultQuiz
+	}
+	return e.Name
+}
+
+// quizQuestions returns the questions of the quiz of an event, or of the
+// questionnaire of the store if e is nil.
+func (qs *QstnnrService) quizQuestions(ctx context.Context, e *schedule.Event) (map[store.QuestionID]store.Question, error) {
+	if e != nil {
+		return e.Bank.Questions, nil
+	}
+	questions, err := qs.store.Questions(ctx)
+	if err != nil {
+		return nil, storeErr(err, "failed to get questions")
+	}
+	return questions, nil
+}
+
+// quizSolutions returns the solutions of the quiz of an event, or of the
+// questionnaire of the store if e is nil.
+func (qs *QstnnrService) quizSolutions(ctx context.Context, e *schedule.Event) (map[store.QuestionID]store.OptionID, error) {
+	if e != nil {
+		return e.Bank.Solutions, nil
+	}
+	solutions, err := qs.store.Solutions(ctx)
+	if err != nil {
+		return nil, storeErr(err, "failed to get solutions")
+	}
+	return solutions, nil
+}
+
+// withheld reports whether the solutions of the quiz of an event are withheld
+// at now, which they are until it closes. Those of the store never are.
+func withheld(e *schedule.Event, now time.Time) bool {
+	return e != nil && e.Status(now) != schedule.Closed
+}
+
+// checkPublished fails with FailedPrecondition if the solutions of the quiz of
+// an event are withheld.
+func checkPublished(e *schedule.Event, now time.Time) error {
+	if !withheld(e, now) {
+		return nil
+	}
+	if e.ClosesAt.IsZero() {
+		qErr := qerr.Wrap(nil, qerr.FailedPrecondition, "the solutions are not published, %s never closes", eventName(*e)).
+			WithReason("SOLUTIONS_NOT_PUBLISHED").
+			WithMisc("event", e.Name)
+		return ServiceError{qErr}
+	}
+	msg := "the solutions are published when %s closes at %s"
+	qErr := qerr.Wrap(nil, qerr.FailedPrecondition, msg, eventName(*e), e.ClosesAt.UTC().Format(time.RFC3339)).
+		WithReason("SOLUTIONS_NOT_PUBLISHED").
+		WithMisc("event", e.Name).
+		WithMisc("published_at", e.ClosesAt.UTC().Format(time.RFC3339))
+	return ServiceError{qErr}
+}
+
+// withholdSolutions removes the solutions from the result of a submission to
+// the quiz of an event until it closes.
+func withholdSolutions(result *SubmitResult, e *schedule.Event, now time.Time) {
+	if withheld(e, now) {
+		result.Solutions = nil
+		result.SolutionsWithheld = true
+		result.SolutionsAt = e.ClosesAt
+	}
+}
+
+// ReviewQuestions returns the questions of a quiz with their explanations, to
+// review them along the solutions. Unlike Questions, it is available outside
+// the windows of the events, but only once the solutions are published. The
+// quiz is named as in Solutions.
+func (qs *QstnnrService) ReviewQuestions(ctx context.Context, quiz string) (map[store.QuestionID]store.Question, error) {
+	now := time.Now()
+	e, err := qs.quizEvent(quiz, now)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPublished(e, now); err != nil {
+		return nil, err
+	}
+	return qs.quizQuestions(ctx, e)
+}
+
+// eventName is the title of an event, or its name if it has none.
+func eventName(e schedule.Event) string {
+	if e.Title != "" {
+		return e.Title
+	}
+	return e.Name
+}
@@ -0,0 +1,130 @@
+package qservice
+
+import (
+	"context"
+	"time"
+
+	"github.com/mateopresacastro/qstnnr/pkg/metrics"
+	"github.com/mateopresacastro/qstnnr/pkg/store"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentedStore traces every operation of the wrapped store and records its latency.
+type instrumentedStore struct {
+	store.Store
+	metrics *metrics.Metrics
+}
+
+// observe starts a span for the given store method. The returned function ends
+// it and records the latency and error of the operation.
+func (s *instrumentedStore) observe(ctx context.Context, method, operation string) (context.Context, func(error)) {
+	start := time.Now()
+	ctx, span := tracer.Start(ctx, "Store."+method, trace.WithSpanKind(trace.SpanKindInternal))
+	return ctx, func(err error) {
+		s.metrics.ObserveStoreOperation(operation, time.Since(start), err)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
+
+func (s *instrumentedStore) Questions(ctx context.Context) (map[store.QuestionID]store.Question, error) {
+	ctx, done := s.observe(ctx, "Questions", "questions")
+	questions, err := s.Store.Questions(ctx)
+	done(err)
+	return questions, err
+}
+
+func (s *instrumentedStore) Solutions(ctx context.Context) (map[store.QuestionID]store.OptionID, error) {
+	ctx, done := s.observe(ctx, "Solutions", "solutions")
+	solutions, err := s.Store.Solutions(ctx)
+	done(err)
+	return solutions, err
+}
+
+func (s *instrumentedStore) SaveAttempt(ctx context.Context, attempt store.Attempt) error {
+	ctx, done := s.observe(ctx, "SaveAttempt", "save_attempt")
+	err := s.Store.SaveAttempt(ctx, attempt)
+	done(err)
+	return err
+}
+
+func (s *instrumentedStore) Scores(ctx context.Context, quiz, cohort string) ([]store.Score, error) {
+	ctx, done := s.observe(ctx, "Scores", "scores")
+	scores, err := s.Store.Scores(ctx, quiz, cohort)
+	done(err)
+	return scores, err
+}
+
+func (s *instrumentedStore) Attempts(ctx context.Context, user string, since time.Time) ([]store.Attempt, error) {
+	ctx, done := s.observe(ctx, "Attempts", "attempts")
+	attempts, err := s.Store.Attempts(ctx, user, since)
+	done(err)
+	return attempts, err
+}
+
+func (s *instrumentedStore) AllAttempts(ctx context.Context) ([]store.Attempt, error) {
+	ctx, done := s.observe(ctx, "AllAttempts", "all_attempts")
+	attempts, err := s.Store.AllAttempts(ctx)
+	done(err)
+	return attempts, err
+}
+
+func (s *instrumentedStore) SaveSubmission(ctx context.Context, submission store.Submission, attempt store.Attempt, limit store.AttemptLimit) error {
+	ctx, done := s.observe(ctx, "SaveSubmission", "save_submission")
+	err := s.Store.SaveSubmission(ctx, submission, attempt, limit)
+	done(err)
+	return err
+}
+
+func (s *instrumentedStore) Submission(ctx context.Context, key string) (store.Submission, error) {
+	ctx, done := s.observe(ctx, "Submission", "submission")
+	submission, err := s.Store.Submission(ctx, key)
+	done(err)
+	return submission, err
+}
+
+func (s *instrumentedStore) SaveProgress(ctx context.Context, progress store.Progress) error {
+	ctx, done := s.observe(ctx, "SaveProgress", "save_progress")
+	err := s.Store.SaveProgress(ctx, progress)
+	done(err)
+	return err
+}
+
+func (s *instrumentedStore) Progress(ctx context.Context, user string) (store.Progress, error) {
+	ctx, done := s.observe(ctx, "Progress", "progress")
+	progress, err := s.Store.Progress(ctx, user)
+	done(err)
+	return progress, err
+}
+
+func (s *instrumentedStore) DeleteProgress(ctx context.Context, user string) error {
+	ctx, done := s.observe(ctx, "DeleteProgress", "delete_progress")
+	err := s.Store.DeleteProgress(ctx, user)
+	done(err)
+	return err
+}
+
+func (s *instrumentedStore) SaveAdaptiveAttempt(ctx context.Context, attempt store.AdaptiveAttempt) error {
+	ctx, done := s.observe(ctx, "SaveAdaptiveAttempt", "save_adaptive_attempt")
+	err := s.Store.SaveAdaptiveAttempt(ctx, attempt)
+	done(err)
+	return err
+}
+
+func (s *instrumentedStore) AdaptiveAttempt(ctx context.Context, id string) (store.AdaptiveAttempt, error) {
+	ctx, done := s.observe(ctx, "AdaptiveAttempt", "adaptive_attempt")
+	attempt, err := s.Store.AdaptiveAttempt(ctx, id)
+	done(err)
+	return attempt, err
+}
+
+func (s *instrumentedStore) Ping(ctx context.Context) error {
+	ctx, done := s.observe(ctx, "Ping", "ping")
+	err := s.Store.Ping(ctx)
+	done(err)
+	return err
+}
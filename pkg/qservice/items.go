@@ -0,0 +1,216 @@
+package qservice
+
+import (
+	"context"
+	"maps"
+	"math"
+	"slices"
+
+	"github.com/mateopresacastro/qstnnr/pkg/qerr"
+	"github.com/mateopresacastro/qstnnr/pkg/reqctx"
+	"github.com/mateopresacastro/qstnnr/pkg/store"
+)
+
+// ItemFlag points out a question that may need a review.
+type ItemFlag string
+
+const (
+	// TooEasy questions are answered correctly by almost everyone.
+	TooEasy ItemFlag = "too_easy"
+	// TooHard questions are answered correctly by few more than guessing would.
+	TooHard ItemFlag = "too_hard"
+	// Misleading questions have a wrong option chosen more than the correct
+	// one, or are answered correctly more often by those who do worse in the
+	// rest of the quiz.
+	Misleading ItemFlag = "misleading"
+)
+
+const (
+	// minFlagResponses is the number of responses needed to flag a question,
+	// as a handful of them says little.
+	minFlagResponses = 5
+	tooEasyShare     = 0.9
+	tooHardShare     = 0.3
+)
+
+// ItemReport is the analysis of how every question performs in the attempts.
+type ItemReport struct {
+	Attempts int
+	// Items are ordered by question ID.
+	Items []ItemStats
+}
+
+// ItemStats describes how a question performs.
+type ItemStats struct {
+	Question store.Question
+	// Responses is the number of attempts that answered the question.
+	Responses int
+	// Correct is the share of correct responses, from 0 to 1.
+	Correct float64
+	// Discrimination is the point-biserial correlation between answering the
+	// question correctly and the score in the rest of the quiz, from -1 to 1.
+	// Good questions are answered correctly by those who do well overall, and
+	// have a discrimination of 0.3 or more. It's 0 when it can't be computed:
+	// everyone got the question right, or wrong, or the same rest score.
+	Discrimination float64
+	// Options are ordered by ID.
+	Options []OptionStats
+	// UnusedDistractors are the wrong options nobody chose.
+	UnusedDistractors []store.OptionID
+	Flags             []ItemFlag
+}
+
+// OptionStats tells how often an option was chosen.
+type OptionStats struct {
+	Option  store.Option
+	Correct bool
+	Count   int
+	// Share of the responses, from 0 to 1.
+	Share float64
+}
+
+// ItemStats analyses the answers to every question in all the attempts. Only
+// admins can see it.
+func (qs *QstnnrService) ItemStats(ctx context.Context) (*ItemReport, error) {
+	if err := qs.checkAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	qsts, err := qs.store.Questions(ctx)
+	if err != nil {
+		return nil, storeErr(err, "failed to get questions")
+	}
+	solutions, err := qs.store.Solutions(ctx)
+	if err != nil {
+		return nil, storeErr(err, "failed to get solutions")
+	}
+	attempts, err := qs.store.AllAttempts(ctx)
+	if err != nil {
+		return nil, storeErr(err, "failed to get attempts")
+	}
+
+	// Scores are computed again with the current solutions, in case they
+	// changed since the attempts were scored.
+	var answered []store.Attempt
+	scores := make([]int, 0, len(attempts))
+	for _, a := range attempts {
+		// Adaptive quizzes choose the questions by the previous answers, so
+		// their answers would skew the analysis.
+		if a.Quiz != DefaultQuiz || len(a.Answers) == 0 {
+			continue
+		}
+		score := 0
+		for qID, oID := range a.Answers {
+			if solutions[qID] == oID {
+				score++
+			}
+		}
+		answered = append(answered, a)
+		scores = append(scores, score)
+	}
+
+	report := &ItemReport{Attempts: len(answered)}
+	for _, qID := range slices.Sorted(maps.Keys(qsts)) {
+		report.Items = append(report.Items, itemStats(qsts[qID], solutions[qID], answered, scores))
+	}
+	return report, nil
+}
+
+func itemStats(q store.Question, solution store.OptionID, attempts []store.Attempt, scores []int) ItemStats {
+	item := ItemStats{Question: q}
+	counts := make(map[store.OptionID]int, len(q.Options))
+	// The item scores and the rest scores of the attempts that answered.
+	var xs, rest []float64
+	for i, a := range attempts {
+		oID, ok := a.Answers[q.ID]
+		if !ok {
+			continue
+		}
+		counts[oID]++
+		x := 0.0
+		if oID == solution {
+			x = 1
+		}
+		xs = append(xs, x)
+		rest = append(rest, float64(scores[i])-x)
+	}
+	item.Responses = len(xs)
+	if item.Responses == 0 {
+		for _, oID := range slices.Sorted(maps.Keys(q.Options)) {
+			item.Options = append(item.Options, OptionStats{Option: q.Options[oID], Correct: oID == solution})
+		}
+		return item
+	}
+	item.Correct = float64(counts[solution]) / float64(item.Responses)
+	item.Discrimination = pointBiserial(xs, rest)
+
+	misleading := item.Discrimination < 0
+	for _, oID := range slices.Sorted(maps.Keys(q.Options)) {
+		opt := OptionStats{
+			Option:  q.Options[oID],
+			Correct: oID == solution,
+			Count:   counts[oID],
+			Share:   float64(counts[oID]) / float64(item.Responses),
+		}
+		item.Options = append(item.Options, opt)
+		if opt.Correct {
+			continue
+		}
+		if opt.Count == 0 {
+			item.UnusedDistractors = append(item.UnusedDistractors, oID)
+		}
+		if opt.Count > counts[solution] {
+			misleading = true
+		}
+	}
+
+	if item.Responses >= minFlagResponses {
+		switch {
+		case item.Correct >= tooEasyShare:
+			item.Flags = append(item.Flags, TooEasy)
+		case item.Correct <= tooHardShare:
+			item.Flags = append(item.Flags, TooHard)
+		}
+		if misleading {
+			item.Flags = append(item.Flags, Misleading)
+		}
+	}
+	return item
+}
+
+// pointBiserial returns the correlation between the item scores, 0 or 1, and
+// the rest scores. Using the rest of the quiz instead of the total score keeps
+// the item from correlating with itself.
+func pointBiserial(xs, rest []float64) float64 {
+	n := float64(len(xs))
+	var meanX, meanR float64
+	for i := range xs {
+		meanX += xs[i]
+		meanR += rest[i]
+	}
+	meanX /= n
+	meanR /= n
+
+	var cov, varX, varR float64
+	for i := range xs {
+		dx, dr := xs[i]-meanX, rest[i]-meanR
+		cov += dx * dr
+		varX += dx * dx
+		varR += dr * dr
+	}
+	if varX == 0 || varR == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(varX*varR)
+}
+
+// checkAdmin fails unless the request was authenticated as an admin's, see
+// reqctx.WithAdmin. The caller name is not enough, as nothing verifies it.
+func (qs *QstnnrService) checkAdmin(ctx context.Context) error {
+	if !reqctx.IsAdmin(ctx) {
+		qErr := qerr.Wrap(nil, qerr.Unauthenticated, "admin operations need an admin token").
+			WithReason("ADMIN_ONLY")
+		return ServiceError{qErr}
+	}
+	return nil
+}
@@ -1,30 +1,109 @@
 package qservice
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
 	"math"
+	"slices"
+	"time"
 
+	"github.com/mateopresacastro/qstnnr/pkg/cohorts"
+	"github.com/mateopresacastro/qstnnr/pkg/metrics"
 	"github.com/mateopresacastro/qstnnr/pkg/qerr"
+	"github.com/mateopresacastro/qstnnr/pkg/reqctx"
+	"github.com/mateopresacastro/qstnnr/pkg/schedule"
 	"github.com/mateopresacastro/qstnnr/pkg/store"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 )
 
+// tracer creates the spans of the service and the store calls. It uses the global
+// tracer provider so nothing is exported unless tracing has been set up.
+var tracer = otel.Tracer("github.com/mateopresacastro/qstnnr/pkg/qservice")
+
 // QService defines the questionnaire operations.
 type QService interface {
-	Questions() (map[store.QuestionID]store.Question, error)
-	SubmitAnswers(answers map[store.QuestionID]store.OptionID) (*SubmitResult, error)
-	Solutions() (map[store.QuestionID]store.OptionID, error)
+	Questions(ctx context.Context) (map[store.QuestionID]store.Question, error)
+	SubmitAnswers(ctx context.Context, answers map[store.QuestionID]store.OptionID, idempotencyKey, displayName, cohort string) (*SubmitResult, error)
+	Solutions(ctx context.Context, quiz string) (map[store.QuestionID]store.OptionID, error)
+	SaveProgress(ctx context.Context, answers map[store.QuestionID]store.OptionID) error
+	Progress(ctx context.Context) (store.Progress, error)
+	StartAdaptive(ctx context.Context, maxQuestions int) (*AdaptiveResult, error)
+	AnswerAdaptive(ctx context.Context, attemptID string, qID store.QuestionID, oID store.OptionID) (*AdaptiveResult, error)
+	ItemStats(ctx context.Context) (*ItemReport, error)
+	ScoreStats(ctx context.Context, filter StatsFilter) (*ScoreStats, error)
+	Leaderboard(ctx context.Context, quiz, cohort string, limit int) (*Leaderboard, error)
+	Events(ctx context.Context) ([]ScheduledEvent, error)
+	ReviewQuestions(ctx context.Context, quiz string) (map[store.QuestionID]store.Question, error)
+	Ping(ctx context.Context) error
 }
 
 // QstnnrService implements QService using a persistent store.
 type QstnnrService struct {
-	store store.Store
+	store         store.Store
+	metrics       *metrics.Metrics
+	dailyAttempts int
+	rankMethod    RankMethod
+	events        []schedule.Event
+	cohorts       cohorts.Membership
+	logger        *slog.Logger
+}
+
+// Option configures optional behaviour of QstnnrService.
+type Option func(*QstnnrService)
+
+// WithMetrics records domain metrics and store operation latencies in m.
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(qs *QstnnrService) {
+		qs.metrics = m
+	}
+}
+
+// WithLogger logs the failures that don't fail the operation, such as cleaning
+// up after it. Defaults to slog.Default().
+func WithLogger(logger *slog.Logger) Option {
+	return func(qs *QstnnrService) {
+		qs.logger = logger
+	}
+}
+
+// WithDailyAttemptLimit caps the submissions of each client address per UTC
+// day. The caller is whatever name the client sends, so it isn't counted: the
+// callers behind a shared address share its attempts. Submissions without an
+// address aren't limited. Zero means no limit.
+func WithDailyAttemptLimit(n int) Option {
+	return func(qs *QstnnrService) {
+		qs.dailyAttempts = n
+	}
 }
 
+// DefaultQuiz names the questionnaire served by the store, outside the events.
+const DefaultQuiz = "default"
+
 // SubmitResult contains a map of questions and their correct options,
 // and the user's percentile ranking.
 type SubmitResult struct {
+	// Quiz is the quiz submitted: default, or the name of the event it was
+	// submitted in.
+	Quiz      string
 	Solutions map[store.QuestionID]store.OptionID
 	Stat      store.Stat
 	Correct   int
+	// Cohort is the cohort of the caller, if any, and CohortStat the
+	// percentile rank within it.
+	Cohort     string
+	CohortStat store.Stat
+	// SolutionsWithheld tells that the submission was made during an event,
+	// so Solutions is empty until SolutionsAt, when the event closes. A zero
+	// SolutionsAt means it never closes.
+	SolutionsWithheld bool
+	SolutionsAt       time.Time
 }
 
 // SubmitResult contains quiz submission results and ranking.
@@ -38,54 +117,125 @@ func (se ServiceError) Unwrap() error {
 }
 
 // NewQstnnrService creates a new questionnaire service.
-func New(store store.Store) QService {
-	return &QstnnrService{store: store}
+func New(s store.Store, opts ...Option) QService {
+	qs := &QstnnrService{rankMethod: MidRank, logger: slog.Default()}
+	for _, opt := range opts {
+		opt(qs)
+	}
+	qs.store = &instrumentedStore{Store: s, metrics: qs.metrics}
+	return qs
 }
 
-// GetQuestions returns all available questions.
-func (qs *QstnnrService) Questions() (map[store.QuestionID]store.Question, error) {
-	questions, err := qs.store.Questions()
+// GetQuestions returns all available questions. With events, only while one
+// of them is open, and they are the questions of its quiz.
+func (qs *QstnnrService) Questions(ctx context.Context) (map[store.QuestionID]store.Question, error) {
+	e, err := qs.checkOpen(time.Now())
 	if err != nil {
-		if _, ok := err.(store.StoreError); !ok {
-			// If this error is not a StoreError we know it's a bug and not a known edge case.
-			return nil, err
-		}
-		return nil, ServiceError{err}
+		return nil, err
 	}
-	return questions, nil
+	return qs.quizQuestions(ctx, e)
 }
 
-// SubmitAnswers processes a questionnaire submission and returns results.
-func (qs *QstnnrService) SubmitAnswers(answers map[store.QuestionID]store.OptionID) (*SubmitResult, error) {
+// SubmitAnswers processes a questionnaire submission and returns results. When
+// an idempotency key is given, retrying the submission with the same key and
+// answers returns the original result without scoring it again. A display
+// name, if given, lists the submission on the leaderboard under it. A
+// participant is also ranked within the given cohort, which must be one of
+// theirs, or within their only cohort if none is given.
+func (qs *QstnnrService) SubmitAnswers(ctx context.Context, answers map[store.QuestionID]store.OptionID, idempotencyKey, displayName, cohort string) (result *SubmitResult, err error) {
+	ctx, span := tracer.Start(ctx, "QstnnrService.SubmitAnswers")
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+	span.SetAttributes(
+		attribute.Int("qstnnr.answers", len(answers)),
+		attribute.String("qstnnr.caller", reqctx.Caller(ctx)),
+		attribute.String("qstnnr.request_id", reqctx.RequestID(ctx)),
+	)
+
 	if len(answers) == 0 {
-		return nil, ServiceError{qerr.Wrap(nil, qerr.InvalidInput, "no answers provided")}
+		qErr := qerr.Wrap(nil, qerr.InvalidInput, "no answers provided").
+			WithReason("NO_ANSWERS").
+			WithViolations(qerr.FieldViolation{Field: "answers", Description: "at least one answer is required"})
+		return nil, ServiceError{qErr}
+	}
+	if err := validateDisplayName(displayName); err != nil {
+		return nil, err
 	}
 
-	qsts, err := qs.store.Questions()
+	cohort, err = qs.cohortOf(ctx, cohort)
 	if err != nil {
-		if _, ok := err.(store.StoreError); !ok {
-			return nil, err
+		return nil, err
+	}
+
+	caller := reqctx.Caller(ctx)
+	span.SetAttributes(attribute.String("qstnnr.cohort", cohort))
+	answersHash := hashAnswers(answers, displayName)
+	if idempotencyKey != "" {
+		sub, err := qs.store.Submission(ctx, idempotencyKey)
+		if err == nil {
+			span.SetAttributes(attribute.Bool("qstnnr.replayed", true))
+			return qs.replay(ctx, sub, caller, cohort, answersHash)
 		}
-		return nil, ServiceError{qerr.Wrap(err, qerr.Internal, "failed to get questions")}
+		if !errors.Is(err, store.ErrSubmissionNotFound) {
+			return nil, storeErr(err, "failed to get submission")
+		}
+	}
+
+	// Retries are replayed even after the event closes, as the answers were
+	// submitted in time.
+	now := time.Now()
+	event, err := qs.checkOpen(now)
+	if err != nil {
+		return nil, err
+	}
+	quiz := quizName(event)
+
+	qsts, err := qs.quizQuestions(ctx, event)
+	if err != nil {
+		return nil, err
 	}
 
 	if len(answers) != len(qsts) {
 		msg := "number of answers (%d) must match number of questions (%d)"
-		return nil, ServiceError{qerr.Wrap(nil, qerr.InvalidInput, msg, len(answers), len(qsts))}
+		qErr := qerr.Wrap(nil, qerr.InvalidInput, msg, len(answers), len(qsts)).
+			WithReason("ANSWER_COUNT_MISMATCH").
+			WithMisc("answers", len(answers)).
+			WithMisc("questions", len(qsts)).
+			WithViolations(qerr.FieldViolation{
+				Field:       "answers",
+				Description: fmt.Sprintf("expected one answer for each of the %d questions, got %d", len(qsts), len(answers)),
+			})
+		return nil, ServiceError{qErr}
 	}
 
+	var unknown []store.QuestionID
 	for qID := range answers {
 		if _, ok := qsts[qID]; !ok {
-			return nil, ServiceError{qerr.Wrap(nil, qerr.InvalidInput, "couldn't find question with id: %d", qID)}
+			unknown = append(unknown, qID)
+		}
+	}
+	if len(unknown) > 0 {
+		slices.Sort(unknown)
+		qErr := qerr.Wrap(nil, qerr.InvalidInput, "couldn't find question with id: %d", unknown[0]).
+			WithReason("UNKNOWN_QUESTION").
+			WithMisc("question_id", unknown[0])
+		for _, qID := range unknown {
+			qErr = qErr.WithViolations(qerr.FieldViolation{
+				Field:       "answers.question_id",
+				Description: fmt.Sprintf("couldn't find question with id: %d", qID),
+			})
 		}
+		return nil, ServiceError{qErr}
 	}
 
-	solutions, err := qs.store.Solutions()
+	solutions, err := qs.quizSolutions(ctx, event)
 	if err != nil {
-		if _, ok := err.(store.StoreError); !ok {
-			return nil, err
-		}
-		return nil, ServiceError{qerr.Wrap(err, qerr.Internal, "failed to get solutions")}
+		return nil, err
 	}
 
 	correct := 0
@@ -95,54 +245,257 @@ func (qs *QstnnrService) SubmitAnswers(answers map[store.QuestionID]store.Option
 		}
 	}
 
-	stat, err := qs.stats(correct)
+	span.SetAttributes(attribute.Int("qstnnr.correct", correct))
+
+	stat, err := qs.stats(ctx, quiz, correct)
 	if err != nil {
-		if _, ok := err.(store.StoreError); !ok {
-			return nil, err
+		return nil, storeErr(err, "calculating stats")
+	}
+	var cohortStat store.Stat
+	if cohort != "" {
+		cohortStat, err = qs.cohortStats(ctx, quiz, cohort, correct)
+		if err != nil {
+			return nil, storeErr(err, "calculating cohort stats")
 		}
-		return nil, ServiceError{qerr.Wrap(err, qerr.Internal, "calculating stats")}
 	}
 
-	if err := qs.store.SaveScore(correct); err != nil {
-		if _, ok := err.(store.StoreError); !ok {
-			return nil, err
+	// The attempts of anonymous callers are kept too, for the item analysis
+	// and the ranks.
+	attempt := store.Attempt{
+		User:        caller,
+		Quiz:        quiz,
+		Cohort:      cohort,
+		DisplayName: displayName,
+		Addr:        reqctx.Addr(ctx),
+		Score:       correct,
+		Total:       len(qsts),
+		SubmittedAt: now,
+		Answers:     answers,
+	}
+	// The key, if any, is taken and the attempt limit checked in the same
+	// operation that saves the attempt, so concurrent submissions can't save
+	// it twice or go over the limit.
+	sub := store.Submission{
+		Key:         idempotencyKey,
+		Caller:      caller,
+		Quiz:        quiz,
+		AnswersHash: answersHash,
+		Correct:     correct,
+		Stat:        stat,
+		Cohort:      cohort,
+		CohortStat:  cohortStat,
+		SubmittedAt: now,
+	}
+	if err := qs.store.SaveSubmission(ctx, sub, attempt, qs.attemptLimit(attempt)); err != nil {
+		if errors.Is(err, store.ErrAttemptLimit) {
+			return nil, qs.attemptLimitErr(now)
+		}
+		if !errors.Is(err, store.ErrSubmissionExists) {
+			return nil, storeErr(err, "failed to save submission")
+		}
+		sub, err := qs.store.Submission(ctx, idempotencyKey)
+		if err != nil {
+			return nil, storeErr(err, "failed to get submission")
+		}
+		span.SetAttributes(attribute.Bool("qstnnr.replayed", true))
+		return qs.replay(ctx, sub, caller, cohort, answersHash)
+	}
+	// Only identified callers have a quiz in progress. The submission is
+	// recorded by now, so failing to forget the progress doesn't fail it.
+	if caller != reqctx.Anonymous {
+		if err := qs.store.DeleteProgress(ctx, caller); err != nil {
+			qs.logger.WarnContext(ctx, "failed to delete progress", "err", err, "caller", caller)
 		}
-		return nil, ServiceError{qerr.Wrap(err, qerr.Internal, "failed to save score: %d", correct)}
 	}
+	qs.metrics.ObserveSubmission(quiz, correct, len(qsts))
 
-	return &SubmitResult{Solutions: solutions, Stat: stat, Correct: correct}, nil
+	result = &SubmitResult{Quiz: quiz, Solutions: solutions, Stat: stat, Correct: correct, Cohort: cohort, CohortStat: cohortStat}
+	withholdSolutions(result, event, now)
+	return result, nil
 }
 
-// stats calculates the percentile ranking for a score.
-func (qs *QstnnrService) stats(score store.Score) (store.Stat, error) {
-	scores, err := qs.store.AllScores()
+// replay returns the result of a submission already scored. The key must have
+// been used by the same caller for the same answers and display name, otherwise
+// it is a mistake of the client and not a retry.
+func (qs *QstnnrService) replay(ctx context.Context, sub store.Submission, caller, cohort, answersHash string) (*SubmitResult, error) {
+	if sub.Caller != caller || sub.Cohort != cohort || sub.AnswersHash != answersHash {
+		qErr := qerr.Wrap(nil, qerr.InvalidInput, "idempotency key %q was already used for a different submission", sub.Key).
+			WithReason("IDEMPOTENCY_KEY_REUSED").
+			WithViolations(qerr.FieldViolation{
+				Field:       "idempotency_key",
+				Description: "use a new key for every submission",
+			})
+		return nil, ServiceError{qErr}
+	}
+
+	// The solutions are published when the event the answers were submitted
+	// in closes, even if another one is open by now.
+	now := time.Now()
+	event, err := qs.quizEvent(sub.Quiz, now)
+	if err != nil {
+		return nil, err
+	}
+	solutions, err := qs.quizSolutions(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+	result := &SubmitResult{Quiz: sub.Quiz, Solutions: solutions, Stat: sub.Stat, Correct: sub.Correct, Cohort: sub.Cohort, CohortStat: sub.CohortStat}
+	withholdSolutions(result, event, now)
+	return result, nil
+}
+
+// hashAnswers identifies a set of answers regardless of their order, and the
+// display name they were submitted with.
+func hashAnswers(answers map[store.QuestionID]store.OptionID, displayName string) string {
+	ids := slices.Sorted(maps.Keys(answers))
+	h := sha256.New()
+	fmt.Fprintf(h, "%q;", displayName)
+	for _, qID := range ids {
+		fmt.Fprintf(h, "%d:%d;", qID, answers[qID])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// attemptLimit is the limit of the attempts of the current UTC day by the
+// address of an attempt. The caller isn't verified, so counting by it would
+// let anyone use up the attempts of someone else. Adaptive quizzes aren't
+// limited, only the rate of their RPCs.
+func (qs *QstnnrService) attemptLimit(attempt store.Attempt) store.AttemptLimit {
+	return store.AttemptLimit{
+		Max:   qs.dailyAttempts,
+		Since: attempt.SubmittedAt.UTC().Truncate(24 * time.Hour),
+		Addr:  attempt.Addr,
+	}
+}
+
+// attemptLimitErr tells that the address of the caller used all the attempts
+// of the current UTC day, and when they can try again.
+func (qs *QstnnrService) attemptLimitErr(now time.Time) error {
+	day := now.UTC().Truncate(24 * time.Hour)
+	retryAfter := day.Add(24 * time.Hour).Sub(now).Round(time.Minute)
+	msg := "daily limit of %d attempts reached, try again in %s"
+	qErr := qerr.Wrap(nil, qerr.ResourceExhausted, msg, qs.dailyAttempts, retryAfter).
+		WithReason("DAILY_ATTEMPT_LIMIT").
+		WithMisc("limit", qs.dailyAttempts).
+		WithMisc("retry_after", int(math.Ceil(retryAfter.Seconds())))
+	return ServiceError{qErr}
+}
+
+// storeErr wraps a known store error in a ServiceError. Any other error is a
+// bug and not a known edge case, so it is returned as is.
+func storeErr(err error, format string, args ...any) error {
+	if _, ok := err.(store.StoreError); !ok {
+		return err
+	}
+	return ServiceError{qerr.Wrap(err, storeErrorCode(err), format, args...)}
+}
+
+// storeErrorCode returns the code for a known store error. A store that ran out
+// of time is reported as such, anything else is an internal error.
+func storeErrorCode(err error) qerr.ErrorCode {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return qerr.DeadlineExceeded
+	}
+	return qerr.Internal
+}
+
+// stats calculates the percentile rank of a score among the scores of all the
+// attempts of a quiz, including it. They are the same attempts ranked by
+// ScoreStats and Leaderboard.
+func (qs *QstnnrService) stats(ctx context.Context, quiz string, score store.Score) (store.Stat, error) {
+	ctx, span := tracer.Start(ctx, "QstnnrService.stats")
+	defer span.End()
+
+	scores, err := qs.store.Scores(ctx, quiz, "")
 	if err != nil {
 		return 0, err
 	}
+	span.SetAttributes(attribute.Int("qstnnr.scores", len(scores)))
+
+	// The score is not saved yet, but it's one of the participants.
+	scores = append(scores, score)
+	return percentileRank(scores, score, qs.rankMethod), nil
+}
 
-	if len(scores) == 0 {
-		return 100, nil // First quiz taker.
+// GetSolutions returns the correct answers for all the questions of a quiz:
+// default, or the name of an event, whose solutions are only published once
+// it closes. Without a name, it is the quiz of the last event that opened, or
+// default if none did.
+func (qs *QstnnrService) Solutions(ctx context.Context, quiz string) (map[store.QuestionID]store.OptionID, error) {
+	now := time.Now()
+	e, err := qs.quizEvent(quiz, now)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPublished(e, now); err != nil {
+		return nil, err
+	}
+	return qs.quizSolutions(ctx, e)
+}
+
+// SaveProgress saves the answers of the caller's quiz in progress, replacing the
+// previous ones. Anonymous callers can't be told apart, so they have no progress.
+func (qs *QstnnrService) SaveProgress(ctx context.Context, answers map[store.QuestionID]store.OptionID) error {
+	caller := reqctx.Caller(ctx)
+	if caller == reqctx.Anonymous {
+		return anonymousProgressError()
 	}
 
-	betterThan := 0
-	for _, s := range scores {
-		if score > s {
-			betterThan++
+	// The answers are to the quiz of the event open, if any.
+	qsts, err := qs.quizQuestions(ctx, qs.openEvent(time.Now()))
+	if err != nil {
+		return err
+	}
+	for _, qID := range slices.Sorted(maps.Keys(answers)) {
+		if _, ok := qsts[qID]; !ok {
+			qErr := qerr.Wrap(nil, qerr.InvalidInput, "couldn't find question with id: %d", qID).
+				WithReason("UNKNOWN_QUESTION").
+				WithMisc("question_id", qID).
+				WithViolations(qerr.FieldViolation{
+					Field:       "answers.question_id",
+					Description: fmt.Sprintf("couldn't find question with id: %d", qID),
+				})
+			return ServiceError{qErr}
 		}
 	}
 
-	percentage := float64(betterThan) / float64(len(scores)) * 100
-	return store.Stat(math.Round(percentage)), nil
+	progress := store.Progress{User: caller, Answers: answers, UpdatedAt: time.Now()}
+	if err := qs.store.SaveProgress(ctx, progress); err != nil {
+		return storeErr(err, "failed to save progress")
+	}
+	return nil
 }
 
-// GetSolutions returns the correct answers for all questions.
-func (qs *QstnnrService) Solutions() (map[store.QuestionID]store.OptionID, error) {
-	solutions, err := qs.store.Solutions()
+// Progress returns the answers of the caller's quiz in progress.
+func (qs *QstnnrService) Progress(ctx context.Context) (store.Progress, error) {
+	caller := reqctx.Caller(ctx)
+	if caller == reqctx.Anonymous {
+		return store.Progress{}, anonymousProgressError()
+	}
+
+	progress, err := qs.store.Progress(ctx, caller)
 	if err != nil {
+		if errors.Is(err, store.ErrProgressNotFound) {
+			return store.Progress{}, ServiceError{qerr.Wrap(err, qerr.NotFound, "there is no quiz in progress")}
+		}
+		return store.Progress{}, storeErr(err, "failed to get progress")
+	}
+	return progress, nil
+}
+
+func anonymousProgressError() error {
+	qErr := qerr.Wrap(nil, qerr.FailedPrecondition, "progress is only kept for identified callers").
+		WithReason("ANONYMOUS_CALLER")
+	return ServiceError{qErr}
+}
+
+// Ping checks that the underlying store is healthy.
+func (qs *QstnnrService) Ping(ctx context.Context) error {
+	if err := qs.store.Ping(ctx); err != nil {
 		if _, ok := err.(store.StoreError); !ok {
-			return nil, err
+			return err
 		}
-		return nil, ServiceError{qerr.Wrap(err, qerr.Internal, "failed to get solutions")}
+		return ServiceError{qerr.Wrap(err, qerr.Unavailable, "store is unhealthy")}
 	}
-	return solutions, nil
+	return nil
 }
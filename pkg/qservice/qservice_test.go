@@ -1,11 +1,27 @@
 package qservice_test
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/mateopresacastro/qstnnr/pkg/cohorts"
+	"github.com/mateopresacastro/qstnnr/pkg/metrics"
+	"github.com/mateopresacastro/qstnnr/pkg/qerr"
 	"github.com/mateopresacastro/qstnnr/pkg/qservice"
+	"github.com/mateopresacastro/qstnnr/pkg/reqctx"
+	"github.com/mateopresacastro/qstnnr/pkg/schedule"
 	"github.com/mateopresacastro/qstnnr/pkg/store"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 func TestService(t *testing.T) {
@@ -57,9 +73,10 @@ func TestService(t *testing.T) {
 		t.Fatal(err)
 	}
 	service := qservice.New(s)
+	ctx := context.Background()
 
 	t.Run("should get questions", func(t *testing.T) {
-		qs, err := service.Questions()
+		qs, err := service.Questions(ctx)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -78,13 +95,13 @@ func TestService(t *testing.T) {
 			3: 1, // Wrong
 		}
 
-		result, err := service.SubmitAnswers(answers)
+		result, err := service.SubmitAnswers(ctx, answers, "", "", "")
 		if err != nil {
 			t.Fatal(err)
 		}
 
-		if result.Stat != 100 { // First submission should be better than 100% of participants
-			t.Fatalf("got stat: %d, want: 100", result.Stat)
+		if result.Stat != 50 { // The first submission ties with itself
+			t.Fatalf("got stat: %d, want: 50", result.Stat)
 		}
 
 		if len(result.Solutions) != len(solutions) {
@@ -97,68 +114,68 @@ func TestService(t *testing.T) {
 	})
 
 	t.Run("should calculate stats correctly", func(t *testing.T) {
-		// Since we have one score saved from previous test and the answers
-		// where not all correct, this stats should be 100 still.
+		// The scores are 2 from the previous test and 3. Ties with the
+		// submission itself count as half.
 		answers := map[store.QuestionID]store.OptionID{
 			1: 2, // Correct
 			2: 2, // Correct
 			3: 2, // Correct
 		}
-		result, err := service.SubmitAnswers(answers)
+		result, err := service.SubmitAnswers(ctx, answers, "", "", "")
 		if err != nil {
 			t.Fatal(err)
 		}
-		if result.Stat != 100 {
-			t.Fatalf("got stat: %d, want: 100", result.Stat)
+		if result.Stat != 75 {
+			t.Fatalf("got stat: %d, want: 75", result.Stat)
 		}
 
-		// All wrong so this is the worst participant. 0%
+		// All wrong so this is the worst participant, tied with itself: 0.5 of 3.
 		answers = map[store.QuestionID]store.OptionID{
 			1: 1, // Wrong
 			2: 1, // Wrong
 			3: 1, // Wrong
 		}
-		result, err = service.SubmitAnswers(answers)
+		result, err = service.SubmitAnswers(ctx, answers, "", "", "")
 		if err != nil {
 			t.Fatal(err)
 		}
-		if result.Stat != 0 {
-			t.Fatalf("got stat: %d, want: 100", result.Stat)
+		if result.Stat != 17 {
+			t.Fatalf("got stat: %d, want: 17", result.Stat)
 		}
 
-		// One correct. Better than 1 out of 3 participants
+		// One correct. Better than 1 and tied with itself: 1.5 of 4.
 		answers = map[store.QuestionID]store.OptionID{
 			1: 2, // Correct
 			2: 1, // Wrong
 			3: 1, // Wrong
 		}
-		result, err = service.SubmitAnswers(answers)
+		result, err = service.SubmitAnswers(ctx, answers, "", "", "")
 		if err != nil {
 			t.Fatal(err)
 		}
-		if result.Stat != 33 {
-			t.Fatalf("got stat: %d, want: 33", result.Stat)
+		if result.Stat != 38 {
+			t.Fatalf("got stat: %d, want: 38", result.Stat)
 		}
 
-		// Better than 2 out of 4 participants
+		// Better than 2 and tied with 2: 3 of 5.
 		answers = map[store.QuestionID]store.OptionID{
 			1: 2, // Correct
 			2: 2, // Correct
 			3: 1, // Wrong
 		}
-		result, err = service.SubmitAnswers(answers)
+		result, err = service.SubmitAnswers(ctx, answers, "", "", "")
 		if err != nil {
 			t.Fatal(err)
 		}
-		if result.Stat != 50 {
-			t.Fatalf("got stat: %d, want: 50", result.Stat)
+		if result.Stat != 60 {
+			t.Fatalf("got stat: %d, want: 60", result.Stat)
 		}
 
 	})
 
 	t.Run("should handle empty answers", func(t *testing.T) {
 		answers := map[store.QuestionID]store.OptionID{}
-		_, err := service.SubmitAnswers(answers)
+		_, err := service.SubmitAnswers(ctx, answers, "", "", "")
 		if err == nil {
 			t.Fatal(err)
 		}
@@ -168,7 +185,7 @@ func TestService(t *testing.T) {
 		answers := map[store.QuestionID]store.OptionID{
 			999: 1, // Invalid question ID
 		}
-		_, err := service.SubmitAnswers(answers)
+		_, err := service.SubmitAnswers(ctx, answers, "", "", "")
 		if err == nil {
 			t.Fatal(err)
 		}
@@ -178,26 +195,47 @@ func TestService(t *testing.T) {
 		answers := map[store.QuestionID]store.OptionID{
 			999: 1, // Invalid question ID
 		}
-		_, err := service.SubmitAnswers(answers)
+		_, err := service.SubmitAnswers(ctx, answers, "", "", "")
 		if _, ok := err.(qservice.ServiceError); !ok {
 			t.Fatalf("error not correcet type")
 		}
 	})
 
+	t.Run("should describe the invalid fields", func(t *testing.T) {
+		answers := map[store.QuestionID]store.OptionID{
+			1: 2,
+			2: 2,
+		}
+		_, err := service.SubmitAnswers(ctx, answers, "", "", "")
+		var qErr qerr.QError
+		if !errors.As(err, &qErr) {
+			t.Fatalf("expected QError, got %v", err)
+		}
+		if qErr.Reason != "ANSWER_COUNT_MISMATCH" {
+			t.Errorf("expected reason ANSWER_COUNT_MISMATCH, got %s", qErr.Reason)
+		}
+		if len(qErr.Violations) != 1 || qErr.Violations[0].Field != "answers" {
+			t.Errorf("expected a violation of the answers field, got %v", qErr.Violations)
+		}
+		if qErr.Misc["answers"] != 2 || qErr.Misc["questions"] != 3 {
+			t.Errorf("expected the counts in Misc, got %v", qErr.Misc)
+		}
+	})
+
 	t.Run("should handle store errors in Solutions()", func(t *testing.T) {
 		errStore := &errorStore{solutionsErr: store.StoreError{}}
 		service := qservice.New(errStore)
-		_, err := service.Solutions()
+		_, err := service.Solutions(ctx, "")
 		if _, ok := err.(qservice.ServiceError); !ok {
 			t.Fatal("expected ServiceError")
 		}
 	})
 
-	t.Run("should handle store errors in SaveScore", func(t *testing.T) {
+	t.Run("should handle store errors in SaveSubmission", func(t *testing.T) {
 		errStore := &errorStore{
-			saveScoreErr:  store.StoreError{},
-			questionsData: questions,
-			solutionsData: solutions,
+			saveSubmissionErr: store.StoreError{},
+			questionsData:     questions,
+			solutionsData:     solutions,
 		}
 		service := qservice.New(errStore)
 		answers := map[store.QuestionID]store.OptionID{
@@ -205,7 +243,7 @@ func TestService(t *testing.T) {
 			2: 2,
 			3: 2,
 		}
-		_, err := service.SubmitAnswers(answers)
+		_, err := service.SubmitAnswers(ctx, answers, "", "", "")
 		if _, ok := err.(qservice.ServiceError); !ok {
 			t.Fatal("expected ServiceError")
 		}
@@ -213,7 +251,7 @@ func TestService(t *testing.T) {
 
 	t.Run("should handle store errors in stats calculation", func(t *testing.T) {
 		errStore := &errorStore{
-			allScoresErr:  store.StoreError{},
+			scoresErr:     store.StoreError{},
 			questionsData: questions,
 			solutionsData: solutions,
 		}
@@ -223,44 +261,1029 @@ func TestService(t *testing.T) {
 			2: 2,
 			3: 2,
 		}
-		_, err := service.SubmitAnswers(answers)
+		_, err := service.SubmitAnswers(ctx, answers, "", "", "")
 		if _, ok := err.(qservice.ServiceError); !ok {
 			t.Fatal("expected ServiceError")
 		}
 	})
 
+	t.Run("should stop when the context is canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := service.SubmitAnswers(ctx, map[store.QuestionID]store.OptionID{1: 2, 2: 2, 3: 2}, "", "", "")
+		if _, ok := err.(qservice.ServiceError); !ok {
+			t.Fatalf("expected ServiceError, got %v", err)
+		}
+	})
+
+	t.Run("should report a store that ran out of time", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 0)
+		defer cancel()
+		_, err := service.Solutions(ctx, "")
+		if !errors.Is(err, qerr.ErrDeadlineExceeded) {
+			t.Fatalf("expected a DeadlineExceeded QError, got %v", err)
+		}
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected the context error as the cause, got %v", err)
+		}
+	})
+
+	t.Run("should report an unhealthy store as unavailable", func(t *testing.T) {
+		service := qservice.New(&errorStore{pingErr: store.StoreError{}})
+		if err := service.Ping(ctx); !errors.Is(err, qerr.ErrUnavailable) {
+			t.Fatalf("expected an Unavailable QError, got %v", err)
+		}
+	})
+
 	t.Run("should handle non-store errors", func(t *testing.T) {
 		errStore := &errorStore{questionsErr: errors.New("non-store error")}
 		service := qservice.New(errStore)
-		_, err := service.Questions()
+		_, err := service.Questions(ctx)
 		if _, ok := err.(qservice.ServiceError); ok {
 			t.Fatal("expected non-ServiceError")
 		}
 	})
 }
 
+// newStore returns a store with n questions, whose options are A, B and C and
+// B is always the correct one.
+func newStore(t *testing.T, n int) store.Store {
+	t.Helper()
+	data := store.InitialData{
+		Questions: make(map[store.QuestionID]store.Question, n),
+		Solutions: make(map[store.QuestionID]store.OptionID, n),
+	}
+	for qID := store.QuestionID(1); qID <= store.QuestionID(n); qID++ {
+		data.Questions[qID] = store.Question{ID: qID, Text: fmt.Sprintf("Question %d", qID), Options: map[store.OptionID]store.Option{
+			1: {ID: 1, Text: "A"},
+			2: {ID: 2, Text: "B"},
+			3: {ID: 3, Text: "C"},
+		}}
+		data.Solutions[qID] = 2
+	}
+	s, err := store.NewInMemory(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestDailyAttemptLimit(t *testing.T) {
+	s := newStore(t, 1)
+	service := qservice.New(s, qservice.WithDailyAttemptLimit(2))
+	answers := map[store.QuestionID]store.OptionID{1: 2}
+	gopher := reqctx.WithCaller(reqctx.WithAddr(context.Background(), "192.0.2.1"), "gopher")
+
+	t.Run("should allow attempts up to the limit", func(t *testing.T) {
+		for range 2 {
+			if _, err := service.SubmitAnswers(gopher, answers, "", "", ""); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+
+	t.Run("should reject attempts over the limit", func(t *testing.T) {
+		_, err := service.SubmitAnswers(gopher, answers, "", "", "")
+		if !errors.Is(err, qerr.ErrResourceExhausted) {
+			t.Fatalf("expected a ResourceExhausted QError, got %v", err)
+		}
+		var qErr qerr.QError
+		if !errors.As(err, &qErr) || qErr.Reason != "DAILY_ATTEMPT_LIMIT" {
+			t.Fatalf("expected reason DAILY_ATTEMPT_LIMIT, got %v", err)
+		}
+		if retryAfter, ok := qErr.Misc["retry_after"].(int); !ok || retryAfter <= 0 {
+			t.Errorf("expected a positive retry_after, got %v", qErr.Misc["retry_after"])
+		}
+	})
+
+	t.Run("should limit the callers of an address whatever their name", func(t *testing.T) {
+		for _, caller := range []string{reqctx.Anonymous, "gopher2"} {
+			ctx := reqctx.WithCaller(reqctx.WithAddr(context.Background(), "192.0.2.1"), caller)
+			if _, err := service.SubmitAnswers(ctx, answers, "", "", ""); !errors.Is(err, qerr.ErrResourceExhausted) {
+				t.Errorf("expected a ResourceExhausted QError for %s, got %v", caller, err)
+			}
+		}
+	})
+
+	t.Run("should not count the attempts by the name of the caller", func(t *testing.T) {
+		// gopher used up the attempts of their address, not theirs elsewhere.
+		elsewhere := reqctx.WithCaller(reqctx.WithAddr(context.Background(), "192.0.2.2"), "gopher")
+		if _, err := service.SubmitAnswers(elsewhere, answers, "", "", ""); err != nil {
+			t.Errorf("expected the attempts of another address to be allowed, got %v", err)
+		}
+	})
+}
+
+func TestIdempotentSubmissions(t *testing.T) {
+	s := newStore(t, 1)
+	service := qservice.New(s, qservice.WithDailyAttemptLimit(1))
+	gopher := reqctx.WithCaller(context.Background(), "gopher")
+	answers := map[store.QuestionID]store.OptionID{1: 2}
+
+	first, err := service.SubmitAnswers(gopher, answers, "key-1", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("should return the original result when retrying", func(t *testing.T) {
+		// The retry doesn't count against the daily limit either.
+		retry, err := service.SubmitAnswers(gopher, answers, "key-1", "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if retry.Correct != first.Correct || retry.Stat != first.Stat {
+			t.Errorf("expected %+v, got %+v", first, retry)
+		}
+		if retry.Solutions[1] != 2 {
+			t.Errorf("expected the solutions, got %v", retry.Solutions)
+		}
+	})
+
+	t.Run("should save the score once", func(t *testing.T) {
+		scores, err := s.Scores(context.Background(), "default", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(scores) != 1 {
+			t.Errorf("expected 1 score, got %d", len(scores))
+		}
+	})
+
+	t.Run("should reject a key reused for other answers", func(t *testing.T) {
+		_, err := service.SubmitAnswers(gopher, map[store.QuestionID]store.OptionID{1: 1}, "key-1", "", "")
+		var qErr qerr.QError
+		if !errors.As(err, &qErr) || qErr.Code != qerr.InvalidInput || qErr.Reason != "IDEMPOTENCY_KEY_REUSED" {
+			t.Fatalf("expected IDEMPOTENCY_KEY_REUSED, got %v", err)
+		}
+	})
+
+	t.Run("should reject a key reused by another caller", func(t *testing.T) {
+		gordon := reqctx.WithCaller(context.Background(), "gordon")
+		_, err := service.SubmitAnswers(gordon, answers, "key-1", "", "")
+		if !errors.Is(err, qerr.ErrInvalidInput) {
+			t.Fatalf("expected an InvalidInput QError, got %v", err)
+		}
+	})
+}
+
+func TestProgress(t *testing.T) {
+	s := newStore(t, 2)
+	service := qservice.New(s)
+	gopher := reqctx.WithCaller(context.Background(), "gopher")
+
+	t.Run("should not find progress before saving it", func(t *testing.T) {
+		if _, err := service.Progress(gopher); !errors.Is(err, qerr.ErrNotFound) {
+			t.Fatalf("expected a NotFound QError, got %v", err)
+		}
+	})
+
+	t.Run("should save and get the progress of the caller", func(t *testing.T) {
+		if err := service.SaveProgress(gopher, map[store.QuestionID]store.OptionID{1: 2}); err != nil {
+			t.Fatal(err)
+		}
+		progress, err := service.Progress(gopher)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if progress.Answers[1] != 2 || progress.UpdatedAt.IsZero() {
+			t.Errorf("expected the saved answers, got %+v", progress)
+		}
+	})
+
+	t.Run("should reject progress for unknown questions", func(t *testing.T) {
+		err := service.SaveProgress(gopher, map[store.QuestionID]store.OptionID{42: 1})
+		if !errors.Is(err, qerr.ErrInvalidInput) {
+			t.Fatalf("expected an InvalidInput QError, got %v", err)
+		}
+	})
+
+	t.Run("should not keep progress for anonymous callers", func(t *testing.T) {
+		err := service.SaveProgress(context.Background(), map[store.QuestionID]store.OptionID{1: 2})
+		if !errors.Is(err, qerr.ErrFailedPrecondition) {
+			t.Fatalf("expected a FailedPrecondition QError, got %v", err)
+		}
+	})
+
+	t.Run("should forget the progress once the answers are submitted", func(t *testing.T) {
+		if _, err := service.SubmitAnswers(gopher, map[store.QuestionID]store.OptionID{1: 2, 2: 1}, "", "", ""); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := service.Progress(gopher); !errors.Is(err, qerr.ErrNotFound) {
+			t.Fatalf("expected a NotFound QError, got %v", err)
+		}
+	})
+
+	t.Run("should submit even if the progress can't be forgotten", func(t *testing.T) {
+		var buf bytes.Buffer
+		s := &deleteProgressErrorStore{Store: newStore(t, 2)}
+		service := qservice.New(s, qservice.WithLogger(slog.New(slog.NewJSONHandler(&buf, nil))))
+		result, err := service.SubmitAnswers(gopher, map[store.QuestionID]store.OptionID{1: 2, 2: 1}, "", "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Correct != 1 {
+			t.Errorf("expected 1 correct, got %d", result.Correct)
+		}
+		if !strings.Contains(buf.String(), "failed to delete progress") {
+			t.Errorf("expected the failure to be logged, got %s", buf.String())
+		}
+	})
+}
+
+// deleteProgressErrorStore fails to delete the progress.
+type deleteProgressErrorStore struct {
+	store.Store
+}
+
+func (s *deleteProgressErrorStore) DeleteProgress(ctx context.Context, user string) error {
+	return errors.New("store is down")
+}
+
+func TestAdaptive(t *testing.T) {
+	// One question of every difficulty, all answered correctly with option 1.
+	data := store.InitialData{
+		Questions: make(map[store.QuestionID]store.Question),
+		Solutions: make(map[store.QuestionID]store.OptionID),
+	}
+	for d := store.MinDifficulty; d <= store.MaxDifficulty; d++ {
+		qID := store.QuestionID(d)
+		data.Questions[qID] = store.Question{ID: qID, Text: "Question", Difficulty: d, Options: map[store.OptionID]store.Option{
+			1: {ID: 1, Text: "Right"},
+			2: {ID: 2, Text: "Wrong"},
+		}}
+		data.Solutions[qID] = 1
+	}
+	s, err := store.NewInMemory(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := qservice.New(s)
+	gopher := reqctx.WithCaller(context.Background(), "gopher")
+
+	t.Run("should start with a medium question", func(t *testing.T) {
+		res, err := service.StartAdaptive(gopher, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Question == nil || res.Question.Difficulty != store.MediumDifficulty {
+			t.Fatalf("expected a medium question, got %+v", res.Question)
+		}
+		if res.Asked != 1 || res.Total != 3 || math.Abs(res.Ability) > 1e-9 {
+			t.Errorf("expected the first of 3 questions and no ability yet, got %+v", res)
+		}
+	})
+
+	t.Run("should ask harder questions after correct answers and easier ones after wrong answers", func(t *testing.T) {
+		res, err := service.StartAdaptive(gopher, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Total != len(data.Questions) {
+			t.Errorf("expected all the %d questions, got %d", len(data.Questions), res.Total)
+		}
+		var abilities []float64
+		for _, correct := range []bool{true, false, true} {
+			prev := res.Question.Difficulty
+			option := store.OptionID(2)
+			if correct {
+				option = 1
+			}
+			res, err = service.AnswerAdaptive(gopher, res.AttemptID, res.Question.ID, option)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if correct && res.Question.Difficulty <= prev {
+				t.Errorf("expected a harder question than %d, got %d", prev, res.Question.Difficulty)
+			}
+			if !correct && res.Question.Difficulty >= prev {
+				t.Errorf("expected an easier question than %d, got %d", prev, res.Question.Difficulty)
+			}
+			abilities = append(abilities, res.Ability)
+		}
+		if abilities[0] <= 0 || abilities[1] >= abilities[0] || abilities[2] <= abilities[1] {
+			t.Errorf("expected the ability to follow the answers, got %v", abilities)
+		}
+	})
+
+	t.Run("should give the solutions of the questions asked at the end", func(t *testing.T) {
+		res, err := service.StartAdaptive(gopher, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for !res.Finished() {
+			if res.Solutions != nil {
+				t.Fatal("expected no solutions before the end")
+			}
+			res, err = service.AnswerAdaptive(gopher, res.AttemptID, res.Question.ID, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if len(res.Answers) != 2 || len(res.Solutions) != 2 || res.Correct != 2 {
+			t.Fatalf("expected 2 correct answers with their solutions, got %+v", res)
+		}
+		if res.Ability <= 0 || res.StandardError <= 0 {
+			t.Errorf("expected a positive ability with its standard error, got %v ± %v", res.Ability, res.StandardError)
+		}
+
+		// Retrying the last answer gets the same result.
+		last := res.Answers[1]
+		retry, err := service.AnswerAdaptive(gopher, res.AttemptID, last.QuestionID, last.OptionID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if retry.Correct != res.Correct || retry.Ability != res.Ability {
+			t.Errorf("expected the same result, got %+v", retry)
+		}
+
+		_, err = service.AnswerAdaptive(gopher, res.AttemptID, last.QuestionID, 2)
+		if !errors.Is(err, qerr.ErrFailedPrecondition) {
+			t.Errorf("expected a FailedPrecondition QError once the quiz is over, got %v", err)
+		}
+	})
+
+	t.Run("should only accept answers to the current question", func(t *testing.T) {
+		res, err := service.StartAdaptive(gopher, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = service.AnswerAdaptive(gopher, res.AttemptID, res.Question.ID+1, 1)
+		var qErr qerr.QError
+		if !errors.As(err, &qErr) || qErr.Reason != "NOT_CURRENT_QUESTION" {
+			t.Errorf("expected reason NOT_CURRENT_QUESTION, got %v", err)
+		}
+		_, err = service.AnswerAdaptive(gopher, res.AttemptID, res.Question.ID, 42)
+		if !errors.Is(err, qerr.ErrInvalidInput) {
+			t.Errorf("expected an InvalidInput QError for an unknown option, got %v", err)
+		}
+	})
+
+	t.Run("should not find the attempts of other callers", func(t *testing.T) {
+		res, err := service.StartAdaptive(gopher, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gordon := reqctx.WithCaller(context.Background(), "gordon")
+		if _, err := service.AnswerAdaptive(gordon, res.AttemptID, res.Question.ID, 1); !errors.Is(err, qerr.ErrNotFound) {
+			t.Errorf("expected a NotFound QError, got %v", err)
+		}
+		if _, err := service.AnswerAdaptive(gopher, "unknown", 1, 1); !errors.Is(err, qerr.ErrNotFound) {
+			t.Errorf("expected a NotFound QError, got %v", err)
+		}
+	})
+
+	t.Run("should save concurrent answers once", func(t *testing.T) {
+		ken := reqctx.WithCaller(context.Background(), "ken")
+		res, err := service.StartAdaptive(ken, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var wg sync.WaitGroup
+		errs := make(chan error, 10)
+		for range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := service.AnswerAdaptive(ken, res.AttemptID, res.Question.ID, 1); err != nil {
+					errs <- err
+				}
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Errorf("expected the retries to get the same result, got %v", err)
+		}
+		attempts, err := s.Attempts(context.Background(), "ken", time.Time{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(attempts) != 1 {
+			t.Errorf("expected the finished quiz to be saved once, got %d attempts", len(attempts))
+		}
+	})
+}
+
+func TestItemStats(t *testing.T) {
+	s := newStore(t, 3)
+	service := qservice.New(s)
+	ctx := context.Background()
+
+	// Everyone gets question 1 right. Question 2 is answered correctly by
+	// those who do better overall, and question 3 only by the best, while
+	// most choose option 1 and nobody option 3.
+	for _, answers := range [][3]store.OptionID{
+		{2, 2, 2},
+		{2, 2, 1},
+		{2, 2, 1},
+		{2, 1, 1},
+		{2, 1, 1},
+		{2, 1, 1},
+	} {
+		if _, err := service.SubmitAnswers(ctx, map[store.QuestionID]store.OptionID{1: answers[0], 2: answers[1], 3: answers[2]}, "", "", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("should only be available to admins", func(t *testing.T) {
+		if _, err := service.ItemStats(ctx); !errors.Is(err, qerr.ErrUnauthenticated) {
+			t.Errorf("expected an Unauthenticated QError for anonymous callers, got %v", err)
+		}
+		// The name of the caller isn't verified, so it grants nothing.
+		admin := reqctx.WithCaller(ctx, "admin")
+		if _, err := service.ItemStats(admin); !errors.Is(err, qerr.ErrUnauthenticated) {
+			t.Errorf("expected an Unauthenticated QError, got %v", err)
+		}
+	})
+
+	t.Run("should analyse every question", func(t *testing.T) {
+		report, err := service.ItemStats(reqctx.WithAdmin(ctx))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Attempts != 6 || len(report.Items) != 3 {
+			t.Fatalf("expected 3 questions in 6 attempts, got %d in %d", len(report.Items), report.Attempts)
+		}
+
+		easy, good, hard := report.Items[0], report.Items[1], report.Items[2]
+		if easy.Correct != 1 || easy.Discrimination != 0 || !slices.Equal(easy.Flags, []qservice.ItemFlag{qservice.TooEasy}) {
+			t.Errorf("expected question 1 to be too easy, got %+v", easy)
+		}
+		if good.Correct != 0.5 || good.Discrimination <= 0.3 || len(good.Flags) != 0 {
+			t.Errorf("expected question 2 to discriminate well, got %+v", good)
+		}
+		wantFlags := []qservice.ItemFlag{qservice.TooHard, qservice.Misleading}
+		if !slices.Equal(hard.Flags, wantFlags) {
+			t.Errorf("expected question 3 to be flagged %v, got %v", wantFlags, hard.Flags)
+		}
+		if !slices.Equal(hard.UnusedDistractors, []store.OptionID{3}) {
+			t.Errorf("expected option 3 to be unused, got %v", hard.UnusedDistractors)
+		}
+		if opt := hard.Options[0]; opt.Count != 5 || math.Abs(opt.Share-5.0/6) > 1e-9 || opt.Correct {
+			t.Errorf("expected option 1 to be chosen 5 times, got %+v", opt)
+		}
+	})
+}
+
+func TestRankMethods(t *testing.T) {
+	// The ranks of the scores 2, 2, 0 and 3, submitted in that order: the
+	// first taker, a tie, the lowest and the highest score.
+	tests := []struct {
+		method qservice.RankMethod
+		want   []store.Stat
+	}{
+		{qservice.StrictRank, []store.Stat{0, 0, 0, 75}},
+		{qservice.InclusiveRank, []store.Stat{100, 100, 33, 100}},
+		{qservice.MidRank, []store.Stat{50, 50, 17, 88}},
+	}
+	for _, tt := range tests {
+		t.Run("should rank with the "+string(tt.method)+" method", func(t *testing.T) {
+			service := qservice.New(newStore(t, 3), qservice.WithRankMethod(tt.method))
+			var got []store.Stat
+			for _, correct := range []int{2, 2, 0, 3} {
+				answers := map[store.QuestionID]store.OptionID{1: 1, 2: 1, 3: 1}
+				for qID := store.QuestionID(1); qID <= store.QuestionID(correct); qID++ {
+					answers[qID] = 2
+				}
+				result, err := service.SubmitAnswers(context.Background(), answers, "", "", "")
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, result.Stat)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("expected the ranks %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	t.Run("should parse the names of the methods", func(t *testing.T) {
+		for _, name := range []string{"strict", "inclusive", "mid-rank"} {
+			if m, err := qservice.ParseRankMethod(name); err != nil || string(m) != name {
+				t.Errorf("expected %s to parse, got %q, %v", name, m, err)
+			}
+		}
+		if _, err := qservice.ParseRankMethod("median"); err == nil {
+			t.Error("expected an error for an unknown method")
+		}
+	})
+}
+
+func TestCohorts(t *testing.T) {
+	s := newStore(t, 3)
+	service := qservice.New(s, qservice.WithCohorts(cohorts.Membership{
+		"ada":    {"team-b"},
+		"gopher": {"team-a"},
+		"gordon": {"onboarding", "team-a"},
+	}))
+	ctx := context.Background()
+	member := func(name string) context.Context {
+		return reqctx.WithParticipant(reqctx.WithCaller(ctx, name), name)
+	}
+	submit := func(ctx context.Context, correct int, key, displayName, cohort string) (*qservice.SubmitResult, error) {
+		answers := map[store.QuestionID]store.OptionID{1: 1, 2: 1, 3: 1}
+		for qID := store.QuestionID(1); qID <= store.QuestionID(correct); qID++ {
+			answers[qID] = 2
+		}
+		return service.SubmitAnswers(ctx, answers, key, displayName, cohort)
+	}
+
+	t.Run("should rank the members within their cohort and overall", func(t *testing.T) {
+		for _, sub := range []struct {
+			name        string
+			correct     int
+			displayName string
+		}{
+			{"ada", 1, "Ada"},
+			{"gopher", 3, "The Gopher"},
+		} {
+			if _, err := submit(member(sub.name), sub.correct, "", sub.displayName, ""); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// gordon ties with ada overall, 1 of 3, and is below gopher in team-a.
+		result, err := submit(member("gordon"), 1, "key-1", "Gordon", "team-a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Cohort != "team-a" || result.CohortStat != 25 || result.Stat != 33 {
+			t.Errorf("expected to rank 25 in team-a and 33 overall, got %+v", result)
+		}
+
+		retry, err := submit(member("gordon"), 1, "key-1", "Gordon", "team-a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if retry.Cohort != result.Cohort || retry.CohortStat != result.CohortStat {
+			t.Errorf("expected the retry to get the original result, got %+v", retry)
+		}
+		if _, err := submit(member("gordon"), 1, "key-1", "Gordo", "team-a"); !errors.Is(err, qerr.ErrInvalidInput) {
+			t.Errorf("expected an InvalidInput QError reusing the key with another display name, got %v", err)
+		}
+		if _, err := submit(member("gordon"), 1, "key-1", "Gordon", "onboarding"); !errors.Is(err, qerr.ErrInvalidInput) {
+			t.Errorf("expected an InvalidInput QError reusing the key with another cohort, got %v", err)
+		}
+
+		for _, sub := range []struct {
+			ctx         context.Context
+			correct     int
+			displayName string
+			cohort      string
+		}{
+			{member("gopher"), 1, "The Gopher", "team-a"},
+			{ctx, 2, "Anonymous", ""},
+			{reqctx.WithCaller(ctx, "ken"), 2, "", ""},
+		} {
+			result, err := submit(sub.ctx, sub.correct, "", sub.displayName, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Cohort != sub.cohort || (sub.cohort == "" && result.CohortStat != 0) {
+				t.Errorf("expected %s to rank in %q, got %+v", reqctx.Caller(sub.ctx), sub.cohort, result)
+			}
+		}
+	})
+
+	t.Run("should reject invalid display names and cohorts", func(t *testing.T) {
+		for _, name := range []string{" gopher", "go\npher", strings.Repeat("g", 41)} {
+			if _, err := submit(member("gopher"), 1, "", name, ""); !errors.Is(err, qerr.ErrInvalidInput) {
+				t.Errorf("%q: expected an InvalidInput QError, got %v", name, err)
+			}
+		}
+		if _, err := submit(member("gopher"), 1, "", "", "team/a"); !errors.Is(err, qerr.ErrInvalidInput) {
+			t.Errorf("expected an InvalidInput QError, got %v", err)
+		}
+		if _, err := service.Leaderboard(ctx, "", "team/a", 0); !errors.Is(err, qerr.ErrInvalidInput) {
+			t.Errorf("expected an InvalidInput QError, got %v", err)
+		}
+		if _, err := service.Leaderboard(ctx, "", "", -1); !errors.Is(err, qerr.ErrInvalidInput) {
+			t.Errorf("expected an InvalidInput QError for a negative limit, got %v", err)
+		}
+	})
+
+	t.Run("should list the best attempt of the participants with a display name", func(t *testing.T) {
+		board, err := service.Leaderboard(reqctx.WithCaller(ctx, "gordon"), "", "", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, e := range board.Entries {
+			got = append(got, fmt.Sprintf("%d %s %t %d %d", e.Rank, e.DisplayName, e.You, e.Score, e.BetterThan))
+		}
+		// The scores are 1, 3, 1, 1, 2 and 2, and ada got 1 before gordon.
+		want := []string{"1 The Gopher false 3 92", "2 Ada false 1 25", "2 Gordon true 1 25"}
+		if board.Participants != 3 || !slices.Equal(got, want) {
+			t.Errorf("expected %v of 3 participants, got %v of %d", want, got, board.Participants)
+		}
+
+		board, err = service.Leaderboard(ctx, "", "", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if board.Participants != 3 || len(board.Entries) != 1 {
+			t.Errorf("expected 1 of 3 participants, got %d of %d", len(board.Entries), board.Participants)
+		}
+	})
+
+	t.Run("should rank the participants of a cohort", func(t *testing.T) {
+		board, err := service.Leaderboard(ctx, "", "team-a", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, e := range board.Entries {
+			got = append(got, fmt.Sprintf("%d %s %d %d %d", e.Rank, e.DisplayName, e.Score, e.CohortBetterThan, e.BetterThan))
+		}
+		// team-a scored 3, 1 and 1.
+		want := []string{"1 The Gopher 3 83 92", "2 Gordon 1 33 25"}
+		if board.Cohort != "team-a" || board.Participants != 2 || !slices.Equal(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("should only rank the participants within their own cohorts", func(t *testing.T) {
+		result, err := submit(member("gordon"), 2, "", "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Cohort != "" || result.CohortStat != 0 {
+			t.Errorf("expected no cohort rank without choosing one of several cohorts, got %+v", result)
+		}
+		for _, c := range []struct {
+			ctx    context.Context
+			cohort string
+		}{
+			{member("ada"), "team-a"},
+			{reqctx.WithCaller(ctx, "gopher"), "team-a"},
+		} {
+			if _, err := submit(c.ctx, 2, "", "", c.cohort); !errors.Is(err, qerr.ErrPermissionDenied) {
+				t.Errorf("%s: expected a PermissionDenied QError, got %v", reqctx.Caller(c.ctx), err)
+			}
+		}
+	})
+}
+
+func TestEvents(t *testing.T) {
+	newService := func(t *testing.T, events ...schedule.Event) qservice.QService {
+		return qservice.New(newStore(t, 1), qservice.WithSchedule(events))
+	}
+	// Every event has a quiz of one question, with a different correct option
+	// than the question of the store, 2.
+	quiz := func(correct store.OptionID) store.InitialData {
+		return store.InitialData{
+			Questions: map[store.QuestionID]store.Question{
+				1: {ID: 1, Text: "Which event is this?", Options: map[store.OptionID]store.Option{
+					1: {ID: 1, Text: "September"},
+					2: {ID: 2, Text: "The practice"},
+					3: {ID: 3, Text: "October"},
+				}},
+			},
+			Solutions: map[store.QuestionID]store.OptionID{1: correct},
+		}
+	}
+	now := time.Now()
+	past := schedule.Event{Name: "september", OpensAt: now.Add(-48 * time.Hour), ClosesAt: now.Add(-47 * time.Hour), Bank: quiz(1)}
+	open := schedule.Event{Name: "october", Title: "Go Quiz, October", OpensAt: now.Add(-time.Hour), ClosesAt: now.Add(time.Hour), Bank: quiz(3)}
+	upcoming := schedule.Event{Name: "november", OpensAt: now.Add(24 * time.Hour), ClosesAt: now.Add(26 * time.Hour), Bank: quiz(3)}
+	ctx := reqctx.WithCaller(context.Background(), "gopher")
+
+	reason := func(err error) string {
+		var qErr qerr.QError
+		if !errors.Is(err, qerr.ErrFailedPrecondition) || !errors.As(err, &qErr) {
+			return fmt.Sprintf("not a FailedPrecondition QError: %v", err)
+		}
+		return qErr.Reason
+	}
+
+	t.Run("should ask the quiz of the open event and withhold its solutions until it closes", func(t *testing.T) {
+		service := newService(t, past, open, upcoming)
+		questions, err := service.Questions(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if questions[1].Text != "Which event is this?" {
+			t.Errorf("expected the question of october, got %+v", questions)
+		}
+		answers := map[store.QuestionID]store.OptionID{1: 3}
+		result, err := service.SubmitAnswers(ctx, answers, "key-1", "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Quiz != "october" || result.Correct != 1 || !result.SolutionsWithheld || result.Solutions != nil || !result.SolutionsAt.Equal(open.ClosesAt) {
+			t.Errorf("expected 1 correct in october and the solutions withheld until %s, got %+v", open.ClosesAt, result)
+		}
+		retry, err := service.SubmitAnswers(ctx, answers, "key-1", "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !retry.SolutionsWithheld || retry.Solutions != nil {
+			t.Errorf("expected the retry to withhold the solutions too, got %+v", retry)
+		}
+		if _, err := service.Solutions(ctx, ""); reason(err) != "SOLUTIONS_NOT_PUBLISHED" {
+			t.Errorf("expected the solutions of october not to be published, got %s", reason(err))
+		}
+		stats, err := service.ScoreStats(ctx, qservice.StatsFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.Quiz != "october" || stats.Attempts != 1 {
+			t.Errorf("expected the stats of the attempt in october, got %+v", stats)
+		}
+
+		for quiz, want := range map[string]store.OptionID{"september": 1, "default": 2} {
+			solutions, err := service.Solutions(ctx, quiz)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if solutions[1] != want {
+				t.Errorf("expected the solutions of %s, got %v", quiz, solutions)
+			}
+		}
+
+		// Adaptive quizzes ask the questions of the store, whose solutions are
+		// never withheld.
+		adaptive, err := service.StartAdaptive(ctx, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		adaptive, err = service.AnswerAdaptive(ctx, adaptive.AttemptID, adaptive.Question.ID, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if adaptive.Correct != 1 || adaptive.Solutions[1] != 2 {
+			t.Errorf("expected the adaptive quiz to give the solutions, got %+v", adaptive)
+		}
+	})
+
+	t.Run("should publish the solutions when the event of the attempt closes, before the next one does", func(t *testing.T) {
+		now := time.Now()
+		closing := schedule.Event{Name: "october", OpensAt: now.Add(-time.Hour), ClosesAt: now.Add(100 * time.Millisecond), Bank: quiz(3)}
+		next := schedule.Event{Name: "november", OpensAt: now.Add(time.Hour), ClosesAt: now.Add(2 * time.Hour), Bank: quiz(1)}
+		service := newService(t, closing, next)
+		answers := map[store.QuestionID]store.OptionID{1: 3}
+		result, err := service.SubmitAnswers(ctx, answers, "key-2", "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result.SolutionsWithheld || !result.SolutionsAt.Equal(closing.ClosesAt) {
+			t.Errorf("expected the solutions withheld until october closes, got %+v", result)
+		}
+
+		time.Sleep(time.Until(closing.ClosesAt))
+		retry, err := service.SubmitAnswers(ctx, answers, "key-2", "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if retry.SolutionsWithheld || retry.Solutions[1] != 3 {
+			t.Errorf("expected the solutions of october once it closed, got %+v", retry)
+		}
+		if _, err := service.Solutions(ctx, "november"); reason(err) != "SOLUTIONS_NOT_PUBLISHED" {
+			t.Errorf("expected the solutions of november not to be published, got %s", reason(err))
+		}
+	})
+
+	t.Run("should reject the quiz between events", func(t *testing.T) {
+		service := newService(t, past, upcoming)
+		_, err := service.Questions(ctx)
+		if reason(err) != "QUIZ_CLOSED" {
+			t.Fatalf("expected the quiz to be closed, got %s", reason(err))
+		}
+		var qErr qerr.QError
+		if errors.As(err, &qErr) && qErr.Misc["event"] != "november" {
+			t.Errorf("expected the error to name the next event, got %v", qErr.Misc)
+		}
+		if _, err := service.SubmitAnswers(ctx, map[store.QuestionID]store.OptionID{1: 3}, "", "", ""); reason(err) != "QUIZ_CLOSED" {
+			t.Errorf("expected submissions to be rejected, got %s", reason(err))
+		}
+		if _, err := service.StartAdaptive(ctx, 0); err != nil {
+			t.Errorf("expected adaptive quizzes between events, got %v", err)
+		}
+		if _, err := service.Solutions(ctx, "november"); reason(err) != "SOLUTIONS_NOT_PUBLISHED" {
+			t.Errorf("expected the solutions of november not to be published, got %s", reason(err))
+		}
+		if _, err := service.Solutions(ctx, "december"); !errors.Is(err, qerr.ErrInvalidInput) {
+			t.Errorf("expected an InvalidInput QError for an unknown quiz, got %v", err)
+		}
+	})
+
+	t.Run("should publish the solutions of the last event that closed", func(t *testing.T) {
+		service := newService(t, past, upcoming)
+		solutions, err := service.Solutions(ctx, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if solutions[1] != 1 {
+			t.Errorf("expected the solutions of september, got %v", solutions)
+		}
+		questions, err := service.ReviewQuestions(ctx, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if questions[1].Options[1].Text != "September" {
+			t.Errorf("expected the questions of september, got %+v", questions)
+		}
+		if _, err := service.ReviewQuestions(ctx, "november"); reason(err) != "SOLUTIONS_NOT_PUBLISHED" {
+			t.Errorf("expected the questions of november not to be reviewable, got %s", reason(err))
+		}
+	})
+
+	t.Run("should list the upcoming and open events", func(t *testing.T) {
+		service := newService(t, past, open, upcoming)
+		events, err := service.Events(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, e := range events {
+			got = append(got, fmt.Sprintf("%s %s", e.Name, e.Status))
+		}
+		if want := []string{"october open", "november upcoming"}; !slices.Equal(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("should always be open without events", func(t *testing.T) {
+		service := newService(t)
+		result, err := service.SubmitAnswers(ctx, map[store.QuestionID]store.OptionID{1: 2}, "", "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.SolutionsWithheld || result.Solutions[1] != 2 {
+			t.Errorf("expected the solutions, got %+v", result)
+		}
+	})
+}
+
+func TestScoreStats(t *testing.T) {
+	s := newStore(t, 3)
+	service := qservice.New(s)
+	ctx := context.Background()
+	start := time.Now()
+
+	// gopher improves from 1 to 3 correct answers, gordon gets 2 and the
+	// anonymous callers 0 and 2.
+	for _, sub := range []struct {
+		caller  string
+		correct int
+	}{
+		{"gopher", 1},
+		{"gordon", 2},
+		{reqctx.Anonymous, 0},
+		{reqctx.Anonymous, 2},
+		{"gopher", 3},
+	} {
+		answers := map[store.QuestionID]store.OptionID{1: 1, 2: 1, 3: 1}
+		for qID := store.QuestionID(1); qID <= store.QuestionID(sub.correct); qID++ {
+			answers[qID] = 2
+		}
+		if _, err := service.SubmitAnswers(reqctx.WithCaller(ctx, sub.caller), answers, "", "", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("should describe the distribution of the scores", func(t *testing.T) {
+		stats, err := service.ScoreStats(reqctx.WithCaller(ctx, "gordon"), qservice.StatsFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.Attempts != 5 || stats.Participants != 4 || stats.Total != 3 {
+			t.Errorf("expected 5 attempts of 4 participants out of 3, got %+v", stats)
+		}
+		if !slices.Equal(stats.Histogram, []int{1, 1, 2, 1}) {
+			t.Errorf("expected the histogram [1 1 2 1], got %v", stats.Histogram)
+		}
+		if stats.Mean != 1.6 || stats.Median != 2 || math.Abs(stats.StandardDeviation-math.Sqrt(1.04)) > 1e-9 {
+			t.Errorf("expected a mean of 1.6, a median of 2 and a standard deviation of 1.02, got %+v", stats)
+		}
+		if stats.Caller == nil || stats.Caller.Score != 2 || stats.Caller.BetterThan != 60 {
+			t.Errorf("expected gordon to rank 60%%, got %+v", stats.Caller)
+		}
+	})
+
+	t.Run("should use the last attempt of the caller", func(t *testing.T) {
+		stats, err := service.ScoreStats(reqctx.WithCaller(ctx, "gopher"), qservice.StatsFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.Caller == nil || stats.Caller.Score != 3 || stats.Caller.BetterThan != 90 {
+			t.Errorf("expected gopher to rank 90%% with 3, got %+v", stats.Caller)
+		}
+
+		stats, err = service.ScoreStats(ctx, qservice.StatsFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.Caller != nil {
+			t.Errorf("expected no score for anonymous callers, got %+v", stats.Caller)
+		}
+	})
+
+	t.Run("should only count the attempts in the time window", func(t *testing.T) {
+		stats, err := service.ScoreStats(ctx, qservice.StatsFilter{Since: start, Until: time.Now().Add(time.Minute)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.Attempts != 5 {
+			t.Errorf("expected 5 attempts, got %d", stats.Attempts)
+		}
+
+		stats, err = service.ScoreStats(ctx, qservice.StatsFilter{Since: time.Now().Add(time.Minute)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.Attempts != 0 || stats.Histogram != nil || stats.Mean != 0 {
+			t.Errorf("expected no attempts, got %+v", stats)
+		}
+
+		_, err = service.ScoreStats(ctx, qservice.StatsFilter{Since: start, Until: start})
+		if !errors.Is(err, qerr.ErrInvalidInput) {
+			t.Errorf("expected an InvalidInput QError for an empty window, got %v", err)
+		}
+	})
+
+	t.Run("should describe each quiz apart", func(t *testing.T) {
+		_, err := service.ScoreStats(ctx, qservice.StatsFilter{Quiz: "trivia"})
+		if !errors.Is(err, qerr.ErrInvalidInput) {
+			t.Errorf("expected an InvalidInput QError for an unknown quiz, got %v", err)
+		}
+
+		gopher := reqctx.WithCaller(ctx, "gopher")
+		res, err := service.StartAdaptive(gopher, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for !res.Finished() {
+			res, err = service.AnswerAdaptive(gopher, res.AttemptID, res.Question.ID, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		stats, err := service.ScoreStats(gopher, qservice.StatsFilter{Quiz: "adaptive"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.Attempts != 1 || stats.Total != 2 || !slices.Equal(stats.Histogram, []int{0, 0, 1}) {
+			t.Errorf("expected the adaptive quiz only, got %+v", stats)
+		}
+		if stats, _ := service.ScoreStats(gopher, qservice.StatsFilter{}); stats.Attempts != 5 {
+			t.Errorf("expected the adaptive quiz not to count for the questionnaire, got %d attempts", stats.Attempts)
+		}
+	})
+}
+
+func TestServiceMetrics(t *testing.T) {
+	s := newStore(t, 1)
+
+	reg := prometheus.NewRegistry()
+	m, err := metrics.New(reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := qservice.New(s, qservice.WithMetrics(m))
+	ctx := context.Background()
+
+	if _, err := service.SubmitAnswers(ctx, map[store.QuestionID]store.OptionID{1: 2}, "", "", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := testutil.CollectAndCount(reg, "qstnnr_submissions_total"); n != 1 {
+		t.Fatalf("expected 1 submissions series, got %d", n)
+	}
+	// questions, solutions, scores and save_submission.
+	if n := testutil.CollectAndCount(reg, "qstnnr_store_operation_duration_seconds"); n != 4 {
+		t.Fatalf("expected 4 store operation series, got %d", n)
+	}
+}
+
 type errorStore struct {
 	store.Store
-	questionsErr  error
-	solutionsErr  error
-	saveScoreErr  error
-	allScoresErr  error
-	questionsData map[store.QuestionID]store.Question
-	solutionsData map[store.QuestionID]store.OptionID
+	questionsErr      error
+	solutionsErr      error
+	saveSubmissionErr error
+	scoresErr         error
+	pingErr           error
+	questionsData     map[store.QuestionID]store.Question
+	solutionsData     map[store.QuestionID]store.OptionID
 }
 
-func (s *errorStore) Questions() (map[store.QuestionID]store.Question, error) {
+func (s *errorStore) Questions(ctx context.Context) (map[store.QuestionID]store.Question, error) {
 	return nil, s.questionsErr
 }
 
-func (s *errorStore) Solutions() (map[store.QuestionID]store.OptionID, error) {
+func (s *errorStore) Solutions(ctx context.Context) (map[store.QuestionID]store.OptionID, error) {
 	return nil, s.solutionsErr
 }
 
-func (s *errorStore) SaveScore(score store.Score) error {
-	return s.saveScoreErr
+func (s *errorStore) SaveSubmission(ctx context.Context, submission store.Submission, attempt store.Attempt, limit store.AttemptLimit) error {
+	return s.saveSubmissionErr
+}
+
+func (s *errorStore) Scores(ctx context.Context, quiz, cohort string) ([]store.Score, error) {
+	return nil, s.scoresErr
 }
 
-func (s *errorStore) AllScores() ([]store.Score, error) {
-	return nil, s.allScoresErr
+func (s *errorStore) Ping(ctx context.Context) error {
+	return s.pingErr
 }
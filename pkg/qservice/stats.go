@@ -0,0 +1,187 @@
+package qservice
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/mateopresacastro/qstnnr/pkg/qerr"
+	"github.com/mateopresacastro/qstnnr/pkg/reqctx"
+	"github.com/mateopresacastro/qstnnr/pkg/store"
+)
+
+// RankMethod tells how the percentile rank of a score counts the scores equal
+// to it, including the score itself.
+type RankMethod string
+
+const (
+	// StrictRank is the percentage of scores lower than the score. The only
+	// participant, or those with the lowest score, rank 0.
+	StrictRank RankMethod = "strict"
+	// InclusiveRank is the percentage of scores lower than or equal to the
+	// score. The only participant, or those with the highest score, rank 100.
+	InclusiveRank RankMethod = "inclusive"
+	// MidRank counts the equal scores as half lower and half higher, so the
+	// only participant, or a tie of everyone, ranks 50. It's the default.
+	MidRank RankMethod = "mid-rank"
+)
+
+// ParseRankMethod parses the name of a rank method.
+func ParseRankMethod(s string) (RankMethod, error) {
+	switch m := RankMethod(s); m {
+	case StrictRank, InclusiveRank, MidRank:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown rank method %q, use strict, inclusive or mid-rank", s)
+}
+
+// WithRankMethod sets how the percentile ranks count the ties, MidRank by
+// default.
+func WithRankMethod(m RankMethod) Option {
+	return func(qs *QstnnrService) {
+		qs.rankMethod = m
+	}
+}
+
+// percentileRank returns the percentile rank of score among scores, which
+// must include it, from 0 to 100.
+func percentileRank(scores []store.Score, score store.Score, method RankMethod) store.Stat {
+	if len(scores) == 0 {
+		return 0
+	}
+	lower, equal := 0, 0
+	for _, s := range scores {
+		switch {
+		case s < score:
+			lower++
+		case s == score:
+			equal++
+		}
+	}
+	ranked := float64(lower)
+	switch method {
+	case InclusiveRank:
+		ranked += float64(equal)
+	case MidRank:
+		ranked += float64(equal) / 2
+	}
+	return store.Stat(math.Round(ranked / float64(len(scores)) * 100))
+}
+
+// StatsFilter selects the attempts described by the score statistics.
+type StatsFilter struct {
+	// Quiz is "default" for the questionnaire of the store, "adaptive" for the
+	// adaptive quizzes, or the name of an event. Without it, it is the quiz of
+	// the last event that opened, or default if none did.
+	Quiz string
+	// Since and Until bound the window of the attempts, submitted at or after
+	// Since and before Until. A zero time leaves its end of the window open.
+	Since time.Time
+	Until time.Time
+}
+
+// ScoreStats describes the distribution of the scores of a quiz.
+type ScoreStats struct {
+	Quiz     string
+	Attempts int
+	// Participants is the number of callers who took the quiz. Anonymous
+	// callers can't be told apart, so every attempt of theirs counts as one.
+	Participants int
+	// Total is the number of questions of the longest attempt. Adaptive
+	// quizzes can be shorter than the questionnaire.
+	Total int
+	// Histogram has the number of attempts for every score, from 0 to Total.
+	Histogram         []int
+	Mean              float64
+	Median            float64
+	StandardDeviation float64
+	// Caller is the last attempt of the caller in the window, nil if there is
+	// none or the caller is anonymous.
+	Caller *CallerScore
+}
+
+// CallerScore is the score of the caller among the others.
+type CallerScore struct {
+	Score store.Score
+	// BetterThan is the percentile rank of the score among the attempts.
+	BetterThan store.Stat
+}
+
+// ScoreStats returns the distribution of the scores of a quiz in a time window,
+// and where the caller landed in it.
+func (qs *QstnnrService) ScoreStats(ctx context.Context, filter StatsFilter) (*ScoreStats, error) {
+	if filter.Quiz != adaptiveQuiz {
+		event, err := qs.quizEvent(filter.Quiz, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		filter.Quiz = quizName(event)
+	}
+	if !filter.Since.IsZero() && !filter.Until.IsZero() && !filter.Until.After(filter.Since) {
+		qErr := qerr.Wrap(nil, qerr.InvalidInput, "the time window ends before it starts").
+			WithReason("INVALID_TIME_WINDOW").
+			WithViolations(qerr.FieldViolation{Field: "until", Description: "must be after since"})
+		return nil, ServiceError{qErr}
+	}
+
+	all, err := qs.store.AllAttempts(ctx)
+	if err != nil {
+		return nil, storeErr(err, "failed to get attempts")
+	}
+
+	caller := reqctx.Caller(ctx)
+	stats := &ScoreStats{Quiz: filter.Quiz}
+	var scores []store.Score
+	var last *store.Attempt
+	users := make(map[string]bool)
+	for _, a := range all {
+		if a.Quiz != filter.Quiz ||
+			!filter.Since.IsZero() && a.SubmittedAt.Before(filter.Since) ||
+			!filter.Until.IsZero() && !a.SubmittedAt.Before(filter.Until) {
+			continue
+		}
+		scores = append(scores, a.Score)
+		stats.Total = max(stats.Total, a.Total, a.Score)
+		if a.User == reqctx.Anonymous {
+			stats.Participants++
+		} else if !users[a.User] {
+			users[a.User] = true
+			stats.Participants++
+		}
+		if a.User == caller && caller != reqctx.Anonymous {
+			last = &a
+		}
+	}
+	stats.Attempts = len(scores)
+	if stats.Attempts == 0 {
+		return stats, nil
+	}
+
+	stats.Histogram = make([]int, stats.Total+1)
+	sum := 0.0
+	for _, s := range scores {
+		stats.Histogram[s]++
+		sum += float64(s)
+	}
+	stats.Mean = sum / float64(len(scores))
+	variance := 0.0
+	for _, s := range scores {
+		d := float64(s) - stats.Mean
+		variance += d * d
+	}
+	stats.StandardDeviation = math.Sqrt(variance / float64(len(scores)))
+
+	slices.Sort(scores)
+	mid := len(scores) / 2
+	stats.Median = float64(scores[mid])
+	if len(scores)%2 == 0 {
+		stats.Median = float64(scores[mid-1]+scores[mid]) / 2
+	}
+
+	if last != nil {
+		stats.Caller = &CallerScore{Score: last.Score, BetterThan: percentileRank(scores, last.Score, qs.rankMethod)}
+	}
+	return stats, nil
+}
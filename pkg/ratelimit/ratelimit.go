@@ -0,0 +1,228 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mateopresacastro/qstnnr/pkg/reqctx"
+	"golang.org/x/time/rate"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// RetryAfterKey is the trailer telling rate limited clients how many seconds
+// to wait before trying again.
+const RetryAfterKey = "retry-after"
+
+// idleTimeout is how long the buckets of a caller or a peer are kept after its
+// last request. A bucket idle for that long is full again anyway.
+const idleTimeout = 10 * time.Minute
+
+// Limit is a token bucket: requests are allowed at Rate per second, with
+// bursts of up to Burst requests.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Limits holds the limits per RPC, by method name, e.g. SubmitAnswers.
+// RPCs without a limit are not rate limited.
+type Limits map[string]Limit
+
+// Parse reads limits written as a comma separated list of
+// <method>=<requests per second>:<burst>, e.g. "SubmitAnswers=0.1:5".
+func Parse(s string) (Limits, error) {
+	limits := make(Limits)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		method, limit, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid rate limit %q: expected <method>=<rate>:<burst>", entry)
+		}
+		rateStr, burstStr, ok := strings.Cut(limit, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid rate limit %q: expected <method>=<rate>:<burst>", entry)
+		}
+		r, err := strconv.ParseFloat(rateStr, 64)
+		if err != nil || r <= 0 {
+			return nil, fmt.Errorf("invalid rate in %q: must be a positive number", entry)
+		}
+		burst, err := strconv.Atoi(burstStr)
+		if err != nil || burst <= 0 {
+			return nil, fmt.Errorf("invalid burst in %q: must be a positive integer", entry)
+		}
+		limits[method] = Limit{Rate: r, Burst: burst}
+	}
+	return limits, nil
+}
+
+// Limiter rate limits RPCs per caller identity and per peer address. A request
+// must be allowed by both buckets. Anonymous callers are only limited by their
+// address, as they would otherwise share a single bucket.
+type Limiter struct {
+	limits    Limits
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	mu        sync.Mutex
+}
+
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// New creates a Limiter enforcing the given limits.
+func New(limits Limits) *Limiter {
+	return &Limiter{
+		limits:    limits,
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// UnaryServerInterceptor rejects unary RPCs over their limit with ResourceExhausted.
+func (l *Limiter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := l.allow(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor rejects streaming RPCs over their limit with ResourceExhausted.
+func (l *Limiter) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := l.allow(ss.Context(), info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// allow takes a token of the method from the caller and the peer buckets. If
+// any of them is empty the error tells when to retry.
+func (l *Limiter) allow(ctx context.Context, fullMethod string) error {
+	addr := ""
+	if p, ok := peer.FromContext(ctx); ok {
+		addr = host(p.Addr)
+	}
+	if delay := l.Allow(path.Base(fullMethod), reqctx.Caller(ctx), addr); delay > 0 {
+		return rateLimited(ctx, delay)
+	}
+	return nil
+}
+
+// Allow takes a token of the method, such as SubmitAnswers, from the buckets of
+// the address and of the caller, and returns zero. If any of them is empty no
+// token is taken, and it returns how long to wait before trying again. Callers
+// that serve the same operations over other protocols, such as the web UI, use
+// it to share the limits of the RPCs.
+func (l *Limiter) Allow(method, caller, addr string) time.Duration {
+	limit, ok := l.limits[method]
+	if !ok {
+		return 0
+	}
+
+	// The address goes first, so a client making up caller names is stopped
+	// before any bucket is created for them.
+	var keys []string
+	if addr != "" {
+		keys = append(keys, method+"/peer/"+addr)
+	}
+	if caller != reqctx.Anonymous {
+		keys = append(keys, method+"/caller/"+caller)
+	}
+
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+	buckets := make([]*bucket, 0, len(keys))
+	reservations := make([]*rate.Reservation, 0, len(keys))
+	for _, key := range keys {
+		b, ok := l.buckets[key]
+		if ok {
+			b.lastSeen = now
+		} else {
+			b = &bucket{limiter: rate.NewLimiter(rate.Limit(limit.Rate), limit.Burst), lastSeen: now}
+		}
+		r := b.limiter.ReserveN(now, 1)
+		if delay := r.DelayFrom(now); delay > 0 {
+			r.CancelAt(now)
+			for _, r := range reservations {
+				r.CancelAt(now)
+			}
+			return delay
+		}
+		buckets = append(buckets, b)
+		reservations = append(reservations, r)
+	}
+	// Only the buckets of allowed requests are kept.
+	for i, key := range keys {
+		l.buckets[key] = buckets[i]
+	}
+	return 0
+}
+
+// sweep forgets the buckets that have been idle for a while, so the map doesn't
+// grow with every caller and address ever seen. It must be called with the lock held.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < idleTimeout {
+		return
+	}
+	for key, b := range l.buckets {
+		if now.Sub(b.lastSeen) >= idleTimeout {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+// rateLimited creates the ResourceExhausted error, with the delay in the
+// retry-after trailer and as a RetryInfo detail.
+func rateLimited(ctx context.Context, delay time.Duration) error {
+	seconds := int(math.Ceil(delay.Seconds()))
+	// This only fails if the trailers can't be sent anymore, and then the
+	// RetryInfo detail still carries the delay.
+	_ = grpc.SetTrailer(ctx, metadata.Pairs(RetryAfterKey, strconv.Itoa(seconds)))
+
+	msg := "too many requests"
+	st, err := status.New(codes.ResourceExhausted, msg).WithDetails(
+		&errdetails.ErrorInfo{Reason: "RATE_LIMITED", Domain: "qstnnr"},
+		&errdetails.RetryInfo{RetryDelay: durationpb.New(delay)},
+		&errdetails.RequestInfo{RequestId: reqctx.RequestID(ctx)},
+	)
+	if err != nil {
+		return status.Error(codes.ResourceExhausted, msg)
+	}
+	return st.Err()
+}
+
+// host returns the IP of a peer address without the port, so all the
+// connections of a client share the same bucket.
+func host(addr net.Addr) string {
+	if addr == nil {
+		return "unknown"
+	}
+	h, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return h
+}
@@ -0,0 +1,159 @@
+package ratelimit_test
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/mateopresacastro/qstnnr/pkg/api"
+	"github.com/mateopresacastro/qstnnr/pkg/qservice"
+	"github.com/mateopresacastro/qstnnr/pkg/ratelimit"
+	"github.com/mateopresacastro/qstnnr/pkg/reqctx"
+	"github.com/mateopresacastro/qstnnr/pkg/server"
+	"github.com/mateopresacastro/qstnnr/pkg/store"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func TestAllow(t *testing.T) {
+	limiter := ratelimit.New(ratelimit.Limits{"SubmitAnswers": {Rate: 0.001, Burst: 1}})
+
+	t.Run("should limit anonymous callers by their address", func(t *testing.T) {
+		if delay := limiter.Allow("SubmitAnswers", reqctx.Anonymous, "192.0.2.1"); delay != 0 {
+			t.Fatalf("expected the first request to be allowed, got a delay of %s", delay)
+		}
+		if delay := limiter.Allow("SubmitAnswers", reqctx.Anonymous, "192.0.2.1"); delay <= 0 {
+			t.Error("expected the second request to be rejected")
+		}
+		if delay := limiter.Allow("SubmitAnswers", "gopher", "192.0.2.1"); delay <= 0 {
+			t.Error("expected a caller from the same address to be rejected")
+		}
+		if delay := limiter.Allow("SubmitAnswers", reqctx.Anonymous, "192.0.2.2"); delay != 0 {
+			t.Errorf("expected another address to be allowed, got a delay of %s", delay)
+		}
+	})
+
+	t.Run("should not limit methods without a limit", func(t *testing.T) {
+		for range 3 {
+			if delay := limiter.Allow("GetQuestions", reqctx.Anonymous, "192.0.2.1"); delay != 0 {
+				t.Fatalf("expected no delay, got %s", delay)
+			}
+		}
+	})
+}
+
+func TestParse(t *testing.T) {
+	t.Run("should parse the limits per method", func(t *testing.T) {
+		limits, err := ratelimit.Parse("GetQuestions=5:20, SubmitAnswers=0.1:5")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := limits["GetQuestions"]; got != (ratelimit.Limit{Rate: 5, Burst: 20}) {
+			t.Errorf("expected 5:20 for GetQuestions, got %v", got)
+		}
+		if got := limits["SubmitAnswers"]; got != (ratelimit.Limit{Rate: 0.1, Burst: 5}) {
+			t.Errorf("expected 0.1:5 for SubmitAnswers, got %v", got)
+		}
+	})
+
+	t.Run("should reject invalid limits", func(t *testing.T) {
+		for _, s := range []string{"GetQuestions", "GetQuestions=5", "GetQuestions=x:1", "GetQuestions=1:0", "GetQuestions=-1:1"} {
+			if _, err := ratelimit.Parse(s); err == nil {
+				t.Errorf("expected an error for %q", s)
+			}
+		}
+	})
+}
+
+func TestLimiter(t *testing.T) {
+	s, err := store.NewInMemory(store.InitialData{
+		Questions: map[store.QuestionID]store.Question{},
+		Solutions: map[store.QuestionID]store.OptionID{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A very slow refill so the buckets stay empty during the test.
+	limiter := ratelimit.New(ratelimit.Limits{"GetQuestions": {Rate: 0.001, Burst: 2}})
+	srv, err := server.New(&server.Config{
+		Logger:      slog.Default(),
+		Service:     qservice.New(s),
+		RateLimiter: limiter,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve(ln)
+	defer srv.Stop()
+
+	conn, err := grpc.NewClient(ln.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := api.NewQuestionnaireClient(conn)
+
+	asCaller := func(caller string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), reqctx.CallerKey, caller)
+	}
+
+	t.Run("should reject requests over the burst with a retry delay", func(t *testing.T) {
+		ctx := asCaller("gopher")
+		for range 2 {
+			if _, err := client.GetQuestions(ctx, &emptypb.Empty{}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		var trailer metadata.MD
+		_, err := client.GetQuestions(ctx, &emptypb.Empty{}, grpc.Trailer(&trailer))
+		st := status.Convert(err)
+		if st.Code() != codes.ResourceExhausted {
+			t.Fatalf("expected ResourceExhausted, got %v", st.Code())
+		}
+		retryAfter := trailer.Get(ratelimit.RetryAfterKey)
+		if len(retryAfter) != 1 {
+			t.Fatalf("expected the retry-after trailer, got %v", trailer)
+		}
+		if seconds, err := strconv.Atoi(retryAfter[0]); err != nil || seconds <= 0 {
+			t.Errorf("expected a positive number of seconds, got %s", retryAfter[0])
+		}
+		var retryInfo *errdetails.RetryInfo
+		for _, d := range st.Details() {
+			if ri, ok := d.(*errdetails.RetryInfo); ok {
+				retryInfo = ri
+			}
+		}
+		if retryInfo == nil || retryInfo.RetryDelay.AsDuration() <= 0 {
+			t.Errorf("expected a RetryInfo detail, got %v", st.Details())
+		}
+	})
+
+	t.Run("should limit other callers by their address", func(t *testing.T) {
+		// All the requests come from the same address, whose bucket is empty now.
+		_, err := client.GetQuestions(asCaller("gordon"), &emptypb.Empty{})
+		if status.Code(err) != codes.ResourceExhausted {
+			t.Fatalf("expected ResourceExhausted, got %v", status.Code(err))
+		}
+	})
+
+	t.Run("should not limit methods without a limit", func(t *testing.T) {
+		for range 5 {
+			if _, err := client.GetSolutions(asCaller("gopher"), &api.GetSolutionsRequest{}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+}
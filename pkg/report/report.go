@@ -0,0 +1,261 @@
+// Package report writes quiz results as JSON, CSV, Markdown or JUnit XML, so
+// they can be kept, shared or shown as test reports in CI dashboards.
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Report formats.
+const (
+	JSON     = "json"
+	CSV      = "csv"
+	Markdown = "markdown"
+	JUnit    = "junit"
+)
+
+// Result is the result of a quiz submission.
+type Result struct {
+	User        string    `json:"user,omitempty" yaml:"user,omitempty"`
+	SubmittedAt time.Time `json:"submitted_at" yaml:"submitted_at"`
+	Correct     int32     `json:"correct" yaml:"correct"`
+	Total       int       `json:"total" yaml:"total"`
+	BetterThan  int32     `json:"better_than" yaml:"better_than"`
+	// Cohort is the group the quiz was taken with, if any, and
+	// CohortBetterThan the rank within it.
+	Cohort           string `json:"cohort,omitempty" yaml:"cohort,omitempty"`
+	CohortBetterThan int32  `json:"cohort_better_than,omitempty" yaml:"cohort_better_than,omitempty"`
+	// SolutionsWithheld tells that the quiz was taken during an event, so
+	// Answers is empty as the solutions are only published when the event
+	// closes, at SolutionsAt if it ever does.
+	SolutionsWithheld bool       `json:"solutions_withheld,omitempty" yaml:"solutions_withheld,omitempty"`
+	SolutionsAt       *time.Time `json:"solutions_at,omitempty" yaml:"solutions_at,omitempty"`
+	Answers           []Answer   `json:"answers" yaml:"answers"`
+}
+
+// Standing tells the percentile rank of the result among the participants,
+// and within its cohort if it has one.
+func (r Result) Standing() string {
+	standing := fmt.Sprintf("percentile rank %d among participants", r.BetterThan)
+	if r.Cohort != "" {
+		standing += fmt.Sprintf(" and %d in %s", r.CohortBetterThan, r.Cohort)
+	}
+	return standing
+}
+
+// Answer is the answer to a question next to the correct one.
+type Answer struct {
+	QuestionID      int32  `json:"question_id" yaml:"question_id"`
+	Question        string `json:"question" yaml:"question"`
+	OptionID        int32  `json:"option_id" yaml:"option_id"`
+	Answer          string `json:"answer" yaml:"answer"`
+	CorrectOptionID int32  `json:"correct_option_id" yaml:"correct_option_id"`
+	CorrectAnswer   string `json:"correct_answer" yaml:"correct_answer"`
+	IsCorrect       bool   `json:"is_correct" yaml:"is_correct"`
+	Explanation     string `json:"explanation,omitempty" yaml:"explanation,omitempty"`
+}
+
+// Formats returns the supported report formats.
+func Formats() []string {
+	return []string{JSON, CSV, Markdown, JUnit}
+}
+
+// ValidateFormat returns an error if format isn't supported.
+func ValidateFormat(format string) error {
+	switch format {
+	case JSON, CSV, Markdown, JUnit:
+		return nil
+	default:
+		return fmt.Errorf("invalid report format %q, use %s", format, strings.Join(Formats(), ", "))
+	}
+}
+
+// FormatFromPath returns the format for the extension of path: .json, .csv,
+// .md or .xml.
+func FormatFromPath(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return JSON, nil
+	case ".csv":
+		return CSV, nil
+	case ".md", ".markdown":
+		return Markdown, nil
+	case ".xml":
+		return JUnit, nil
+	default:
+		return "", fmt.Errorf("can't tell the report format of %s, use a .json, .csv, .md or .xml file or set the format", path)
+	}
+}
+
+// Write writes the results in the given format.
+func Write(w io.Writer, format string, results []Result) error {
+	switch format {
+	case JSON:
+		return writeJSON(w, results)
+	case CSV:
+		return writeCSV(w, results)
+	case Markdown:
+		return writeMarkdown(w, results)
+	case JUnit:
+		return writeJUnit(w, results)
+	default:
+		return ValidateFormat(format)
+	}
+}
+
+func writeJSON(w io.Writer, results []Result) error {
+	// An empty report is an empty list rather than null.
+	if results == nil {
+		results = []Result{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(results)
+}
+
+// writeCSV writes a row per answer, so results can be filtered and aggregated
+// in a spreadsheet.
+func writeCSV(w io.Writer, results []Result) error {
+	cw := csv.NewWriter(w)
+	header := []string{"submitted_at", "user", "question_id", "question", "answer", "correct_answer", "correct", "explanation"}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, r := range results {
+		for _, a := range r.Answers {
+			record := []string{
+				r.SubmittedAt.UTC().Format(time.RFC3339),
+				r.User,
+				strconv.Itoa(int(a.QuestionID)),
+				a.Question,
+				a.Answer,
+				a.CorrectAnswer,
+				strconv.FormatBool(a.IsCorrect),
+				a.Explanation,
+			}
+			if err := cw.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeMarkdown(w io.Writer, results []Result) error {
+	var b strings.Builder
+	b.WriteString("# Quiz results\n")
+	for _, r := range results {
+		fmt.Fprintf(&b, "\n## %s\n\n", r.SubmittedAt.UTC().Format("2006-01-02 15:04 MST"))
+		if r.User != "" {
+			fmt.Fprintf(&b, "%s got ", mdEscape(r.User))
+		} else {
+			b.WriteString("Got ")
+		}
+		fmt.Fprintf(&b, "%d of %d correct, %s.\n\n", r.Correct, r.Total, mdEscape(r.Standing()))
+		b.WriteString("| # | Question | Answer | Correct answer | Result | Explanation |\n")
+		b.WriteString("|---|---|---|---|---|---|\n")
+		for _, a := range r.Answers {
+			mark := "✅"
+			if !a.IsCorrect {
+				mark = "❌"
+			}
+			fmt.Fprintf(&b, "| %d | %s | %s | %s | %s | %s |\n",
+				a.QuestionID, mdEscape(a.Question), mdEscape(a.Answer), mdEscape(a.CorrectAnswer), mark, mdEscape(a.Explanation))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// mdEscape keeps text inside a table cell: pipes would end the cell and
+// newlines the row.
+func mdEscape(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnit writes a test suite per result and a test case per question,
+// failed when the answer is wrong.
+func writeJUnit(w io.Writer, results []Result) error {
+	suites := junitTestSuites{Name: "qstnnr"}
+	for _, r := range results {
+		name := "qstnnr quiz"
+		if r.User != "" {
+			name += " (" + r.User + ")"
+		}
+		suite := junitTestSuite{
+			Name:      name,
+			Timestamp: r.SubmittedAt.UTC().Format("2006-01-02T15:04:05"),
+			Tests:     len(r.Answers),
+		}
+		for _, a := range r.Answers {
+			tc := junitTestCase{
+				Name:      a.Question,
+				ClassName: fmt.Sprintf("qstnnr.question%d", a.QuestionID),
+			}
+			if a.IsCorrect {
+				tc.SystemOut = a.Explanation
+			} else {
+				suite.Failures++
+				tc.Failure = &junitFailure{
+					Message: fmt.Sprintf("answered %q, the correct answer is %q", a.Answer, a.CorrectAnswer),
+					Type:    "WrongAnswer",
+					Text:    a.Explanation,
+				}
+			}
+			suite.Cases = append(suite.Cases, tc)
+		}
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,171 @@
+package report_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mateopresacastro/qstnnr/pkg/report"
+)
+
+func TestReport(t *testing.T) {
+	results := []report.Result{
+		{
+			User:        "gopher",
+			SubmittedAt: time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC),
+			Correct:     1,
+			Total:       2,
+			BetterThan:  50,
+			Answers: []report.Answer{
+				{
+					QuestionID: 1, Question: "What is 2 + 2?",
+					OptionID: 2, Answer: "4",
+					CorrectOptionID: 2, CorrectAnswer: "4",
+					IsCorrect: true, Explanation: "Basic arithmetic.",
+				},
+				{
+					QuestionID: 2, Question: "Which one | is a pipe?",
+					OptionID: 1, Answer: "a, b",
+					CorrectOptionID: 3, CorrectAnswer: "|",
+					IsCorrect: false, Explanation: "The pipe is |.",
+				},
+			},
+		},
+	}
+
+	t.Run("should tell the format from the file extension", func(t *testing.T) {
+		cases := map[string]string{
+			"results.json": report.JSON,
+			"results.CSV":  report.CSV,
+			"results.md":   report.Markdown,
+			"junit.xml":    report.JUnit,
+		}
+		for path, want := range cases {
+			got, err := report.FormatFromPath(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != want {
+				t.Errorf("%s: expected %s, got %s", path, want, got)
+			}
+		}
+		if _, err := report.FormatFromPath("results.txt"); err == nil {
+			t.Error("expected an error for an unknown extension")
+		}
+	})
+
+	t.Run("should reject unknown formats", func(t *testing.T) {
+		if err := report.Write(&bytes.Buffer{}, "pdf", results); err == nil {
+			t.Error("expected an error")
+		}
+	})
+
+	t.Run("should write JSON", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := report.Write(&buf, report.JSON, results); err != nil {
+			t.Fatal(err)
+		}
+		var got []report.Result
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 1 || len(got[0].Answers) != 2 {
+			t.Fatalf("expected the result with 2 answers, got %+v", got)
+		}
+		if got[0].Answers[1].Explanation != "The pipe is |." {
+			t.Errorf("expected the explanation, got %q", got[0].Answers[1].Explanation)
+		}
+	})
+
+	t.Run("should write an empty JSON list without results", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := report.Write(&buf, report.JSON, nil); err != nil {
+			t.Fatal(err)
+		}
+		if strings.TrimSpace(buf.String()) != "[]" {
+			t.Errorf("expected [], got %s", buf.String())
+		}
+	})
+
+	t.Run("should write a CSV row per answer", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := report.Write(&buf, report.CSV, results); err != nil {
+			t.Fatal(err)
+		}
+		records, err := csv.NewReader(&buf).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != 3 {
+			t.Fatalf("expected a header and 2 rows, got %d records", len(records))
+		}
+		want := []string{"2026-10-19T10:00:00Z", "gopher", "2", "Which one | is a pipe?", "a, b", "|", "false", "The pipe is |."}
+		for i := range want {
+			if records[2][i] != want[i] {
+				t.Errorf("column %s: expected %q, got %q", records[0][i], want[i], records[2][i])
+			}
+		}
+	})
+
+	t.Run("should escape pipes in Markdown tables", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := report.Write(&buf, report.Markdown, results); err != nil {
+			t.Fatal(err)
+		}
+		md := buf.String()
+		if !strings.Contains(md, "gopher got 1 of 2 correct, percentile rank 50 among participants.") {
+			t.Errorf("expected the score, got:\n%s", md)
+		}
+		if !strings.Contains(md, `| 2 | Which one \| is a pipe? | a, b | \| | ❌ | The pipe is \|. |`) {
+			t.Errorf("expected the escaped row, got:\n%s", md)
+		}
+	})
+
+	t.Run("should tell the standing within the cohort", func(t *testing.T) {
+		r := results[0]
+		r.Cohort, r.CohortBetterThan = "team-a", 75
+		if got, want := r.Standing(), "percentile rank 50 among participants and 75 in team-a"; got != want {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+	})
+
+	t.Run("should write wrong answers as JUnit failures", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := report.Write(&buf, report.JUnit, results); err != nil {
+			t.Fatal(err)
+		}
+		var suites struct {
+			Tests    int `xml:"tests,attr"`
+			Failures int `xml:"failures,attr"`
+			Suites   []struct {
+				Cases []struct {
+					Name    string `xml:"name,attr"`
+					Failure *struct {
+						Message string `xml:"message,attr"`
+						Text    string `xml:",chardata"`
+					} `xml:"failure"`
+				} `xml:"testcase"`
+			} `xml:"testsuite"`
+		}
+		if err := xml.Unmarshal(buf.Bytes(), &suites); err != nil {
+			t.Fatal(err)
+		}
+		if suites.Tests != 2 || suites.Failures != 1 {
+			t.Errorf("expected 2 tests and 1 failure, got %d and %d", suites.Tests, suites.Failures)
+		}
+		cases := suites.Suites[0].Cases
+		if cases[0].Failure != nil {
+			t.Error("expected the correct answer to pass")
+		}
+		if cases[1].Failure == nil {
+			t.Fatal("expected the wrong answer to fail")
+		}
+		if cases[1].Failure.Text != "The pipe is |." {
+			t.Errorf("expected the explanation in the failure, got %q", cases[1].Failure.Text)
+		}
+	})
+}
@@ -0,0 +1,106 @@
+package reqctx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// Metadata keys used by clients to send the request-scoped values.
+const (
+	RequestIDKey = "x-request-id"
+	CallerKey    = "x-qstnnr-caller"
+	// AuthorizationKey carries the admin or participant token as
+	// "Bearer <token>".
+	AuthorizationKey = "authorization"
+)
+
+// Anonymous is the caller of requests that don't identify themselves.
+const Anonymous = "anonymous"
+
+type contextKey int
+
+const (
+	requestIDContextKey contextKey = iota
+	callerContextKey
+	addrContextKey
+	adminContextKey
+	participantContextKey
+)
+
+// WithRequestID returns a copy of ctx carrying the given request ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey, id)
+}
+
+// RequestID returns the request ID carried by ctx, or an empty string.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey).(string)
+	return id
+}
+
+// WithCaller returns a copy of ctx carrying the identity of the caller.
+//
+// The caller is whatever name the client sent in CallerKey, nothing verifies
+// it. It only tells apart the progress, history and results of the callers,
+// and must not be used to decide what a request is allowed to do.
+func WithCaller(ctx context.Context, caller string) context.Context {
+	return context.WithValue(ctx, callerContextKey, caller)
+}
+
+// Caller returns the identity of the caller carried by ctx, or Anonymous.
+func Caller(ctx context.Context) string {
+	caller, ok := ctx.Value(callerContextKey).(string)
+	if !ok || caller == "" {
+		return Anonymous
+	}
+	return caller
+}
+
+// WithAddr returns a copy of ctx carrying the address of the client, without
+// the port. Unlike the caller, the client can't choose it.
+func WithAddr(ctx context.Context, addr string) context.Context {
+	return context.WithValue(ctx, addrContextKey, addr)
+}
+
+// Addr returns the address of the client carried by ctx, or an empty string.
+func Addr(ctx context.Context) string {
+	addr, _ := ctx.Value(addrContextKey).(string)
+	return addr
+}
+
+// WithAdmin returns a copy of ctx marking the request as made by an admin. It
+// must only be used once the request presented a valid admin credential.
+func WithAdmin(ctx context.Context) context.Context {
+	return context.WithValue(ctx, adminContextKey, true)
+}
+
+// IsAdmin reports whether ctx was marked by WithAdmin.
+func IsAdmin(ctx context.Context) bool {
+	admin, _ := ctx.Value(adminContextKey).(bool)
+	return admin
+}
+
+// WithParticipant returns a copy of ctx carrying the name of the participant
+// who made the request. Unlike the caller, it must only be used once the
+// request presented the token of the participant.
+func WithParticipant(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, participantContextKey, name)
+}
+
+// Participant returns the participant carried by ctx, or an empty string if
+// the request didn't authenticate as one.
+func Participant(ctx context.Context) string {
+	name, _ := ctx.Value(participantContextKey).(string)
+	return name
+}
+
+// NewID returns a random identifier suitable for request IDs.
+func NewID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		// The random source of the operating system is broken; nothing else will work.
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,65 @@
+package reqctx_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mateopresacastro/qstnnr/pkg/reqctx"
+)
+
+func TestReqctx(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should default to an anonymous caller without request ID", func(t *testing.T) {
+		if caller := reqctx.Caller(ctx); caller != reqctx.Anonymous {
+			t.Errorf("expected %s, got %s", reqctx.Anonymous, caller)
+		}
+		if id := reqctx.RequestID(ctx); id != "" {
+			t.Errorf("expected no request ID, got %s", id)
+		}
+		if addr := reqctx.Addr(ctx); addr != "" {
+			t.Errorf("expected no address, got %s", addr)
+		}
+		if reqctx.IsAdmin(ctx) {
+			t.Error("expected the request not to be made by an admin")
+		}
+		if participant := reqctx.Participant(ctx); participant != "" {
+			t.Errorf("expected no participant, got %s", participant)
+		}
+	})
+
+	t.Run("should carry the request scoped values", func(t *testing.T) {
+		ctx := reqctx.WithCaller(reqctx.WithRequestID(ctx, "req-1"), "gopher")
+		if caller := reqctx.Caller(ctx); caller != "gopher" {
+			t.Errorf("expected gopher, got %s", caller)
+		}
+		if id := reqctx.RequestID(ctx); id != "req-1" {
+			t.Errorf("expected req-1, got %s", id)
+		}
+		if addr := reqctx.Addr(reqctx.WithAddr(ctx, "192.0.2.1")); addr != "192.0.2.1" {
+			t.Errorf("expected 192.0.2.1, got %s", addr)
+		}
+		if !reqctx.IsAdmin(reqctx.WithAdmin(ctx)) {
+			t.Error("expected the request to be made by an admin")
+		}
+		if participant := reqctx.Participant(reqctx.WithParticipant(ctx, "gordon")); participant != "gordon" {
+			t.Errorf("expected gordon, got %s", participant)
+		}
+	})
+
+	t.Run("should treat an empty caller as anonymous", func(t *testing.T) {
+		if caller := reqctx.Caller(reqctx.WithCaller(ctx, "")); caller != reqctx.Anonymous {
+			t.Errorf("expected %s, got %s", reqctx.Anonymous, caller)
+		}
+	})
+
+	t.Run("should generate unique IDs", func(t *testing.T) {
+		a, b := reqctx.NewID(), reqctx.NewID()
+		if len(a) != 32 {
+			t.Errorf("expected 32 hex characters, got %d", len(a))
+		}
+		if a == b {
+			t.Error("expected different IDs")
+		}
+	})
+}
@@ -0,0 +1,165 @@
+// Package schedule loads the events of the questionnaire: windows of time in
+// which a quiz of their own can be taken, such as a monthly quiz night.
+// Schedules are read from YAML or JSON files, and the questions of every event
+// from a quiz file next to it.
+package schedule
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/mateopresacastro/qstnnr/pkg/bank"
+	"github.com/mateopresacastro/qstnnr/pkg/decode"
+	"github.com/mateopresacastro/qstnnr/pkg/store"
+)
+
+// File is the format of a schedule file.
+type File struct {
+	Events []FileEvent `yaml:"events" json:"events"`
+}
+
+// FileEvent is an event in a schedule file. The times are in RFC 3339, such
+// as 2026-11-05T18:00:00Z, and either can be left out to leave that end of the
+// window open. Questions is the quiz file of the event, relative to the
+// schedule file.
+type FileEvent struct {
+	Name      string `yaml:"name" json:"name"`
+	Title     string `yaml:"title" json:"title"`
+	OpensAt   string `yaml:"opens_at" json:"opens_at"`
+	ClosesAt  string `yaml:"closes_at" json:"closes_at"`
+	Questions string `yaml:"questions" json:"questions"`
+}
+
+// Event is a window of time in which the quiz of the event can be taken.
+type Event struct {
+	Name  string
+	Title string
+	// OpensAt is when the event starts and ClosesAt when it ends, exclusive.
+	// A zero time leaves its end of the window open.
+	OpensAt  time.Time
+	ClosesAt time.Time
+	// QuestionsFile is the quiz file of the event as written in the schedule,
+	// and Bank its questions and solutions, read by Load.
+	QuestionsFile string
+	Bank          store.InitialData
+}
+
+// Status is the state of an event at a given time.
+type Status string
+
+const (
+	Upcoming Status = "upcoming"
+	Open     Status = "open"
+	Closed   Status = "closed"
+)
+
+// Status returns the state of the event at now.
+func (e Event) Status(now time.Time) Status {
+	switch {
+	case !e.OpensAt.IsZero() && now.Before(e.OpensAt):
+		return Upcoming
+	case !e.ClosesAt.IsZero() && !now.Before(e.ClosesAt):
+		return Closed
+	default:
+		return Open
+	}
+}
+
+// Load reads the schedule file at path and the quiz files of its events.
+func Load(path string) ([]Event, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading the schedule file: %w", err)
+	}
+	events, err := Parse(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for i, e := range events {
+		file := e.QuestionsFile
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(filepath.Dir(path), file)
+		}
+		if events[i].Bank, err = bank.Load(file); err != nil {
+			return nil, fmt.Errorf("event %q: %w", e.Name, err)
+		}
+	}
+	return events, nil
+}
+
+// Parse reads a schedule file in YAML or JSON and returns its events ordered
+// by their opening time, without reading their quiz files. Every event needs
+// a unique name and a quiz file of its own, and their windows can't overlap,
+// so at most one is open at any time. The names default and adaptive are
+// taken by the quizzes outside the events.
+func Parse(b []byte) ([]Event, error) {
+	var f File
+	if err := decode.File(b, &f); err != nil {
+		return nil, fmt.Errorf("invalid schedule file: %w", err)
+	}
+	if len(f.Events) == 0 {
+		return nil, errors.New("the schedule has no events")
+	}
+
+	names := make(map[string]bool, len(f.Events))
+	files := make(map[string]string, len(f.Events))
+	events := make([]Event, 0, len(f.Events))
+	for _, fe := range f.Events {
+		if fe.Name == "" {
+			return nil, errors.New("every event needs a name")
+		}
+		if fe.Name == "default" || fe.Name == "adaptive" {
+			return nil, fmt.Errorf("invalid event name %q: it names a quiz outside the events", fe.Name)
+		}
+		if names[fe.Name] {
+			return nil, fmt.Errorf("duplicate event name %q", fe.Name)
+		}
+		names[fe.Name] = true
+		if fe.Questions == "" {
+			return nil, fmt.Errorf("event %q needs a quiz file with its questions", fe.Name)
+		}
+		// The solutions of an event are published when it closes, so its
+		// questions can't be asked again by another one.
+		if other, ok := files[filepath.Clean(fe.Questions)]; ok {
+			return nil, fmt.Errorf("events %q and %q have the same questions", other, fe.Name)
+		}
+		files[filepath.Clean(fe.Questions)] = fe.Name
+
+		e := Event{Name: fe.Name, Title: fe.Title, QuestionsFile: fe.Questions}
+		var err error
+		if e.OpensAt, err = parseTime(fe.OpensAt); err != nil {
+			return nil, fmt.Errorf("event %q: invalid opens_at: %w", fe.Name, err)
+		}
+		if e.ClosesAt, err = parseTime(fe.ClosesAt); err != nil {
+			return nil, fmt.Errorf("event %q: invalid closes_at: %w", fe.Name, err)
+		}
+		if !e.OpensAt.IsZero() && !e.ClosesAt.IsZero() && !e.ClosesAt.After(e.OpensAt) {
+			return nil, fmt.Errorf("event %q closes before it opens", fe.Name)
+		}
+		events = append(events, e)
+	}
+
+	// A zero opening time sorts first, as the event has always been open.
+	slices.SortFunc(events, func(a, b Event) int {
+		return cmp.Or(a.OpensAt.Compare(b.OpensAt), cmp.Compare(a.Name, b.Name))
+	})
+	for i := 1; i < len(events); i++ {
+		prev, e := events[i-1], events[i]
+		if prev.ClosesAt.IsZero() || e.OpensAt.IsZero() || prev.ClosesAt.After(e.OpensAt) {
+			return nil, fmt.Errorf("events %q and %q overlap", prev.Name, e.Name)
+		}
+	}
+	return events, nil
+}
+
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
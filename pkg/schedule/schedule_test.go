@@ -0,0 +1,141 @@
+package schedule_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mateopresacastro/qstnnr/pkg/schedule"
+)
+
+func TestSchedule(t *testing.T) {
+	t.Run("should read the events ordered by opening time", func(t *testing.T) {
+		events, err := schedule.Parse([]byte(`
+events:
+  - name: november
+    title: Go Quiz, November 2026
+    opens_at: 2026-11-05T18:00:00Z
+    closes_at: 2026-11-05T20:00:00Z
+    questions: november.yaml
+  - name: october
+    opens_at: 2026-10-01T18:00:00Z
+    closes_at: 2026-10-01T20:00:00Z
+    questions: quizzes/october.yaml`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(events) != 2 || events[0].Name != "october" || events[1].Name != "november" {
+			t.Fatalf("expected october and november, got %+v", events)
+		}
+		if events[0].QuestionsFile != "quizzes/october.yaml" {
+			t.Errorf("expected the questions of October in quizzes/october.yaml, got %q", events[0].QuestionsFile)
+		}
+		want := time.Date(2026, 11, 5, 20, 0, 0, 0, time.UTC)
+		if !events[1].ClosesAt.Equal(want) || events[1].Title != "Go Quiz, November 2026" {
+			t.Errorf("expected the November event to close at %s, got %+v", want, events[1])
+		}
+	})
+
+	t.Run("should read JSON schedule files and the questions of the events", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "schedule.json")
+		file := `{"events": [{"name": "launch", "opens_at": "2026-10-01T18:00:00+02:00", "questions": "launch.json"}]}`
+		if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		quiz := `{"questions": [{"id": 1, "text": "Is Go fun?", "options": [{"id": 1, "text": "Yes", "correct": true}, {"id": 2, "text": "No"}]}]}`
+		if err := os.WriteFile(filepath.Join(dir, "launch.json"), []byte(quiz), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		events, err := schedule.Load(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(events) != 1 || !events[0].ClosesAt.IsZero() {
+			t.Fatalf("expected one event that never closes, got %+v", events)
+		}
+		if q := events[0].Bank.Questions[1]; q.Text != "Is Go fun?" || events[0].Bank.Solutions[1] != 1 {
+			t.Errorf("expected the question of the event and its solution, got %+v", events[0].Bank)
+		}
+		if want := time.Date(2026, 10, 1, 16, 0, 0, 0, time.UTC); !events[0].OpensAt.Equal(want) {
+			t.Errorf("expected the event to open at %s, got %s", want, events[0].OpensAt)
+		}
+	})
+
+	t.Run("should tell the status of an event", func(t *testing.T) {
+		opens := time.Date(2026, 10, 1, 18, 0, 0, 0, time.UTC)
+		closes := opens.Add(2 * time.Hour)
+		cases := []struct {
+			event schedule.Event
+			now   time.Time
+			want  schedule.Status
+		}{
+			{event: schedule.Event{OpensAt: opens, ClosesAt: closes}, now: opens.Add(-time.Second), want: schedule.Upcoming},
+			{event: schedule.Event{OpensAt: opens, ClosesAt: closes}, now: opens, want: schedule.Open},
+			{event: schedule.Event{OpensAt: opens, ClosesAt: closes}, now: closes, want: schedule.Closed},
+			{event: schedule.Event{ClosesAt: closes}, now: opens.AddDate(-1, 0, 0), want: schedule.Open},
+			{event: schedule.Event{OpensAt: opens}, now: closes.AddDate(1, 0, 0), want: schedule.Open},
+		}
+		for _, c := range cases {
+			if got := c.event.Status(c.now); got != c.want {
+				t.Errorf("%+v at %s: expected %s, got %s", c.event, c.now, c.want, got)
+			}
+		}
+	})
+
+	t.Run("should fail to load an event without its quiz file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "schedule.yaml")
+		if err := os.WriteFile(path, []byte(`events: [{name: launch, questions: launch.yaml}]`), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := schedule.Load(path); err == nil || !strings.Contains(err.Error(), `event "launch"`) {
+			t.Errorf("expected an error about the questions of launch, got %v", err)
+		}
+	})
+
+	t.Run("should reject invalid schedules", func(t *testing.T) {
+		cases := []struct {
+			schedule string
+			err      string
+		}{
+			{schedule: `events: []`, err: "no events"},
+			{schedule: `events: [{opens_at: 2026-10-01T18:00:00Z, questions: a.yaml}]`, err: "needs a name"},
+			{schedule: `events: [{name: a, questions: a.yaml}, {name: a, questions: b.yaml}]`, err: "duplicate event name"},
+			{schedule: `events: [{name: default, questions: a.yaml}]`, err: "invalid event name"},
+			{schedule: `events: [{name: a}]`, err: "needs a quiz file"},
+			{schedule: `events: [{name: a, questions: a.yaml}, {name: b, questions: ./a.yaml}]`, err: `events "a" and "b" have the same questions`},
+			{schedule: `events: [{name: a, opens_at: 1 October, questions: a.yaml}]`, err: "invalid opens_at"},
+			{schedule: `
+events:
+  - name: a
+    opens_at: 2026-10-01T18:00:00Z
+    closes_at: 2026-10-01T18:00:00Z
+    questions: a.yaml`, err: "closes before it opens"},
+			{schedule: `
+events:
+  - name: a
+    opens_at: 2026-10-01T18:00:00Z
+    closes_at: 2026-10-01T20:00:00Z
+    questions: a.yaml
+  - name: b
+    opens_at: 2026-10-01T19:00:00Z
+    questions: b.yaml`, err: "overlap"},
+			{schedule: `
+events:
+  - name: a
+    opens_at: 2026-10-01T18:00:00Z
+    questions: a.yaml
+  - name: b
+    opens_at: 2026-11-01T18:00:00Z
+    questions: b.yaml`, err: "overlap"},
+		}
+		for _, c := range cases {
+			_, err := schedule.Parse([]byte(c.schedule))
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Errorf("expected an error about %s, got %v", c.err, err)
+			}
+		}
+	})
+}
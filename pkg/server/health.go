@@ -0,0 +1,49 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/mateopresacastro/qstnnr/pkg/api"
+	"github.com/mateopresacastro/qstnnr/pkg/qservice"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// healthServer implements grpc.health.v1.Health. It checks the store every time
+// a client asks for the status, so Check reflects the current state of the service.
+type healthServer struct {
+	*health.Server
+	service qservice.QService
+	logger  *slog.Logger
+}
+
+func newHealthServer(service qservice.QService, logger *slog.Logger) *healthServer {
+	return &healthServer{Server: health.NewServer(), service: service, logger: logger}
+}
+
+// Check refreshes the serving status and returns it.
+func (h *healthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	h.refresh(ctx)
+	return h.Server.Check(ctx, req)
+}
+
+// Watch refreshes the serving status before streaming it. Later changes are only
+// sent when another Check refreshes them or the server shuts down.
+func (h *healthServer) Watch(req *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	h.refresh(stream.Context())
+	return h.Server.Watch(req, stream)
+}
+
+// refresh sets the serving status of the server and the questionnaire service
+// depending on the health of the store. Once the server is shutting down the
+// status stays NOT_SERVING.
+func (h *healthServer) refresh(ctx context.Context) {
+	status := healthpb.HealthCheckResponse_SERVING
+	if err := h.service.Ping(ctx); err != nil {
+		h.logger.Warn("store is unhealthy", "err", err)
+		status = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	h.SetServingStatus("", status)
+	h.SetServingStatus(api.Questionnaire_ServiceDesc.ServiceName, status)
+}
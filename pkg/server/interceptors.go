@@ -0,0 +1,131 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"net"
+	"strings"
+
+	"github.com/mateopresacastro/qstnnr/pkg/reqctx"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// requestScopeUnaryInterceptor makes the request ID, the caller identity and
+// the client address available to the handlers and the service through the context.
+func requestScopeUnaryInterceptor(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	return handler(withRequestScope(ctx), req)
+}
+
+// requestScopeStreamInterceptor is the streaming counterpart of requestScopeUnaryInterceptor.
+func requestScopeStreamInterceptor(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &scopedStream{ServerStream: ss, ctx: withRequestScope(ss.Context())})
+}
+
+// withRequestScope reads the request ID and the caller from the incoming metadata,
+// and the address from the peer. A request ID is generated if the client didn't send one, and it is sent back
+// in the response header so clients can refer to it.
+func withRequestScope(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	id := firstValue(md, reqctx.RequestIDKey)
+	if id == "" {
+		id = reqctx.NewID()
+	}
+	// This only fails if the headers were already sent, which can't happen yet.
+	_ = grpc.SetHeader(ctx, metadata.Pairs(reqctx.RequestIDKey, id))
+
+	ctx = reqctx.WithRequestID(ctx, id)
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		addr := p.Addr.String()
+		if host, _, err := net.SplitHostPort(addr); err == nil {
+			addr = host
+		}
+		ctx = reqctx.WithAddr(ctx, addr)
+	}
+	return reqctx.WithCaller(ctx, firstValue(md, reqctx.CallerKey))
+}
+
+// tokenAuth marks the requests bearing one of the admin tokens as made by an
+// admin, see reqctx.WithAdmin, and those bearing the token of a participant as
+// made by them, see reqctx.WithParticipant. Requests with any other token are
+// rejected with Unauthenticated, and requests without one go through as
+// regular callers.
+type tokenAuth struct {
+	// hashes of the admin tokens, so comparing them takes the same time
+	// whatever their length.
+	hashes [][sha256.Size]byte
+	// participants by the hash of their token.
+	participants map[[sha256.Size]byte]string
+}
+
+func newTokenAuth(adminTokens []string, participants map[[sha256.Size]byte]string) *tokenAuth {
+	a := &tokenAuth{participants: participants}
+	for _, t := range adminTokens {
+		a.hashes = append(a.hashes, sha256.Sum256([]byte(t)))
+	}
+	return a
+}
+
+func (a *tokenAuth) unaryInterceptor(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := a.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a *tokenAuth) streamInterceptor(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.authenticate(ss.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &scopedStream{ServerStream: ss, ctx: ctx})
+}
+
+func (a *tokenAuth) authenticate(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	auth := firstValue(md, reqctx.AuthorizationKey)
+	if auth == "" {
+		return ctx, nil
+	}
+	token, ok := strings.CutPrefix(auth, "Bearer ")
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "the authorization must be a bearer token")
+	}
+	hash := sha256.Sum256([]byte(token))
+	admin := 0
+	for _, h := range a.hashes {
+		admin |= subtle.ConstantTimeCompare(hash[:], h[:])
+	}
+	if admin == 1 {
+		return reqctx.WithAdmin(ctx), nil
+	}
+	// Looking up the hash only tells how much of the hash matched, not of
+	// the token.
+	if name, ok := a.participants[hash]; ok {
+		return reqctx.WithParticipant(ctx, name), nil
+	}
+	return nil, status.Error(codes.Unauthenticated, "invalid token")
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// scopedStream overrides the context of a server stream.
+type scopedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *scopedStream) Context() context.Context {
+	return s.ctx
+}
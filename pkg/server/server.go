@@ -2,18 +2,32 @@ package server
 
 import (
 	"context"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
+	"slices"
+	"time"
 
 	"github.com/mateopresacastro/qstnnr/pkg/api"
+	"github.com/mateopresacastro/qstnnr/pkg/bugs"
+	"github.com/mateopresacastro/qstnnr/pkg/metrics"
 	"github.com/mateopresacastro/qstnnr/pkg/qerr"
 	"github.com/mateopresacastro/qstnnr/pkg/qservice"
+	"github.com/mateopresacastro/qstnnr/pkg/ratelimit"
+	"github.com/mateopresacastro/qstnnr/pkg/reqctx"
 	"github.com/mateopresacastro/qstnnr/pkg/store"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
 	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 // To assert implementation
@@ -22,35 +36,104 @@ var _ api.QuestionnaireServer = (*server)(nil)
 // server implements the gRPC questionnaire service.
 type server struct {
 	api.QuestionnaireServer
-	service qservice.QService
-	logger  *slog.Logger
+	service   qservice.QService
+	logger    *slog.Logger
+	bugs      bugs.Reporter
+	version   string
+	startedAt time.Time
+}
+
+// Server is the gRPC server exposing the questionnaire, health and reflection services.
+type Server struct {
+	*grpc.Server
+	health *healthServer
 }
 
 // ServerConfig holds the configuration for the gRPC server.
 type Config struct {
 	Logger  *slog.Logger
 	Service qservice.QService
+	Version string
+	// Metrics records the requests handled by the server. Optional.
+	Metrics *metrics.Metrics
+	// RateLimiter rejects the requests over their limit. Optional.
+	RateLimiter *ratelimit.Limiter
+	// BugReporter receives the unexpected errors. Defaults to logging them.
+	BugReporter bugs.Reporter
+	// AdminTokens are the bearer tokens of the admins. Without them, nobody
+	// can use the admin RPCs.
+	AdminTokens []string
+	// Participants is the name of every participant by the SHA-256 of their
+	// token, see cohorts.Roster. Without them, nobody is ranked in a cohort.
+	Participants map[[sha256.Size]byte]string
 }
 
 // New creates a new gRPC server with the given configuration.
-func New(cfg *Config) (*grpc.Server, error) {
-	server := &server{service: cfg.Service, logger: cfg.Logger}
-	grpcsrv := grpc.NewServer()
+func New(cfg *Config) (*Server, error) {
+	server := &server{
+		service:   cfg.Service,
+		logger:    cfg.Logger,
+		bugs:      cfg.BugReporter,
+		version:   cfg.Version,
+		startedAt: time.Now(),
+	}
+	if server.version == "" {
+		server.version = "dev"
+	}
+	if server.bugs == nil {
+		server.bugs = bugs.NewLogReporter(cfg.Logger)
+	}
+	auth := newTokenAuth(cfg.AdminTokens, cfg.Participants)
+	unary := []grpc.UnaryServerInterceptor{requestScopeUnaryInterceptor, auth.unaryInterceptor}
+	stream := []grpc.StreamServerInterceptor{requestScopeStreamInterceptor, auth.streamInterceptor}
+	if cfg.Metrics != nil {
+		unary = append(unary, cfg.Metrics.UnaryServerInterceptor())
+		stream = append(stream, cfg.Metrics.StreamServerInterceptor())
+	}
+	// After the metrics so the rejected requests are counted too.
+	if cfg.RateLimiter != nil {
+		unary = append(unary, cfg.RateLimiter.UnaryServerInterceptor())
+		stream = append(stream, cfg.RateLimiter.StreamServerInterceptor())
+	}
+	grpcsrv := grpc.NewServer(
+		// Spans are only exported when a global tracer provider has been set up.
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+	)
 	api.RegisterQuestionnaireServer(grpcsrv, server)
-	return grpcsrv, nil
+
+	health := newHealthServer(cfg.Service, cfg.Logger)
+	healthpb.RegisterHealthServer(grpcsrv, health)
+	reflection.Register(grpcsrv)
+
+	return &Server{Server: grpcsrv, health: health}, nil
+}
+
+// GracefulStop reports NOT_SERVING to health checks and then stops the server,
+// waiting for the pending RPCs to finish.
+func (s *Server) GracefulStop() {
+	s.health.Shutdown()
+	s.Server.GracefulStop()
+}
+
+// Stop reports NOT_SERVING to health checks and then stops the server immediately.
+func (s *Server) Stop() {
+	s.health.Shutdown()
+	s.Server.Stop()
 }
 
 // GetQuestions returns all questions with their options.
 func (s *server) GetQuestions(ctx context.Context, _ *emptypb.Empty) (*api.GetQuestionsResponse, error) {
-	qsts, err := s.service.Questions()
+	qsts, err := s.service.Questions(ctx)
 	if err != nil {
-		return nil, s.handleError(err)
+		return nil, s.handleError(ctx, err)
 	}
 
 	var questions []*api.Question
 	for qID, q := range qsts {
 		var options []*api.Option
-		var question = &api.Question{Id: int32(qID), Text: q.Text, Options: options}
+		var question = &api.Question{Id: int32(qID), Text: q.Text, Options: options, Difficulty: int32(q.Difficulty)}
 		for oID, o := range q.Options {
 			question.Options = append(question.Options, &api.Option{Id: int32(oID), Text: o.Text})
 		}
@@ -66,40 +149,259 @@ func (s *server) SubmitAnswers(ctx context.Context, req *api.SubmitAnswersReques
 	for _, a := range req.Answers {
 		answers[store.QuestionID(a.QuestionId)] = store.OptionID(a.OptionId)
 	}
-	result, err := s.service.SubmitAnswers(answers)
+	result, err := s.service.SubmitAnswers(ctx, answers, req.GetIdempotencyKey(), req.GetDisplayName(), req.GetCohort())
 	if err != nil {
-		return nil, s.handleError(err)
+		return nil, s.handleError(ctx, err)
 	}
 
-	processed, err := s.processSolutions(result.Solutions)
+	processed, err := s.processSolutions(ctx, result.Quiz, result.Solutions)
 	if err != nil {
-		return nil, s.handleError(err)
+		return nil, s.handleError(ctx, err)
 	}
-	return &api.SubmitAnswersResponse{
-		Solutions:  processed,
-		BetterThan: int32(result.Stat),
-		Correct:    int32(result.Correct),
-	}, nil
+	res := &api.SubmitAnswersResponse{
+		Quiz:              result.Quiz,
+		Solutions:         processed,
+		BetterThan:        int32(result.Stat),
+		Correct:           int32(result.Correct),
+		Cohort:            result.Cohort,
+		CohortBetterThan:  int32(result.CohortStat),
+		SolutionsWithheld: result.SolutionsWithheld,
+	}
+	if !result.SolutionsAt.IsZero() {
+		res.SolutionsPublishedAt = timestamppb.New(result.SolutionsAt)
+	}
+	return res, nil
 }
 
-// GetSolutions returns the correct answers for all questions.
-func (s *server) GetSolutions(ctx context.Context, req *emptypb.Empty) (*api.GetSolutionsResponse, error) {
-	solutions, err := s.service.Solutions()
+// GetSolutions returns the correct answers for all questions of a quiz.
+func (s *server) GetSolutions(ctx context.Context, req *api.GetSolutionsRequest) (*api.GetSolutionsResponse, error) {
+	solutions, err := s.service.Solutions(ctx, req.GetQuiz())
 	if err != nil {
-		return nil, s.handleError(err)
+		return nil, s.handleError(ctx, err)
 	}
 
-	processed, err := s.processSolutions(solutions)
+	processed, err := s.processSolutions(ctx, req.GetQuiz(), solutions)
 	if err != nil {
-		return nil, s.handleError(err)
+		return nil, s.handleError(ctx, err)
 	}
 
 	return &api.GetSolutionsResponse{Solutions: processed}, nil
 }
 
-// processSolutions converts internal solution format to API response format.
-func (s *server) processSolutions(ss map[store.QuestionID]store.OptionID) ([]*api.Solution, error) {
-	qsts, err := s.service.Questions()
+// GetServerInfo returns the version of the server and when it was started.
+func (s *server) GetServerInfo(ctx context.Context, _ *emptypb.Empty) (*api.GetServerInfoResponse, error) {
+	return &api.GetServerInfoResponse{
+		Version:   s.version,
+		StartedAt: timestamppb.New(s.startedAt),
+	}, nil
+}
+
+// SaveProgress saves the answers of the caller's quiz in progress.
+func (s *server) SaveProgress(ctx context.Context, req *api.SaveProgressRequest) (*emptypb.Empty, error) {
+	answers := make(map[store.QuestionID]store.OptionID)
+	for _, a := range req.Answers {
+		answers[store.QuestionID(a.QuestionId)] = store.OptionID(a.OptionId)
+	}
+	if err := s.service.SaveProgress(ctx, answers); err != nil {
+		return nil, s.handleError(ctx, err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// GetProgress returns the answers of the caller's quiz in progress, ordered by question ID.
+func (s *server) GetProgress(ctx context.Context, _ *emptypb.Empty) (*api.GetProgressResponse, error) {
+	progress, err := s.service.Progress(ctx)
+	if err != nil {
+		return nil, s.handleError(ctx, err)
+	}
+	var answers []*api.Answer
+	for _, qID := range slices.Sorted(maps.Keys(progress.Answers)) {
+		answers = append(answers, &api.Answer{
+			QuestionId: int32(qID),
+			OptionId:   int32(progress.Answers[qID]),
+		})
+	}
+	return &api.GetProgressResponse{
+		Answers:   answers,
+		UpdatedAt: timestamppb.New(progress.UpdatedAt),
+	}, nil
+}
+
+// StartAdaptiveQuiz starts an adaptive quiz and returns its first question.
+func (s *server) StartAdaptiveQuiz(ctx context.Context, req *api.StartAdaptiveQuizRequest) (*api.AdaptiveQuizResponse, error) {
+	result, err := s.service.StartAdaptive(ctx, int(req.GetMaxQuestions()))
+	if err != nil {
+		return nil, s.handleError(ctx, err)
+	}
+	res, err := s.adaptiveResponse(ctx, result)
+	if err != nil {
+		return nil, s.handleError(ctx, err)
+	}
+	return res, nil
+}
+
+// AnswerAdaptiveQuestion answers the current question of an adaptive quiz and
+// returns the next one, or the results once the quiz is over.
+func (s *server) AnswerAdaptiveQuestion(ctx context.Context, req *api.AnswerAdaptiveQuestionRequest) (*api.AdaptiveQuizResponse, error) {
+	answer := req.GetAnswer()
+	result, err := s.service.AnswerAdaptive(ctx, req.GetAttemptId(),
+		store.QuestionID(answer.GetQuestionId()), store.OptionID(answer.GetOptionId()))
+	if err != nil {
+		return nil, s.handleError(ctx, err)
+	}
+	res, err := s.adaptiveResponse(ctx, result)
+	if err != nil {
+		return nil, s.handleError(ctx, err)
+	}
+	return res, nil
+}
+
+// GetItemStats analyses how every question performs in the submitted attempts.
+func (s *server) GetItemStats(ctx context.Context, _ *emptypb.Empty) (*api.GetItemStatsResponse, error) {
+	report, err := s.service.ItemStats(ctx)
+	if err != nil {
+		return nil, s.handleError(ctx, err)
+	}
+	res := &api.GetItemStatsResponse{Attempts: int32(report.Attempts)}
+	for _, item := range report.Items {
+		stats := &api.ItemStats{
+			QuestionId:     int32(item.Question.ID),
+			QuestionText:   item.Question.Text,
+			Responses:      int32(item.Responses),
+			Correct:        item.Correct,
+			Discrimination: item.Discrimination,
+		}
+		for _, opt := range item.Options {
+			stats.Options = append(stats.Options, &api.OptionStats{
+				OptionId: int32(opt.Option.ID),
+				Text:     opt.Option.Text,
+				Correct:  opt.Correct,
+				Count:    int32(opt.Count),
+				Share:    opt.Share,
+			})
+		}
+		for _, oID := range item.UnusedDistractors {
+			stats.UnusedDistractors = append(stats.UnusedDistractors, int32(oID))
+		}
+		for _, f := range item.Flags {
+			stats.Flags = append(stats.Flags, string(f))
+		}
+		res.Items = append(res.Items, stats)
+	}
+	return res, nil
+}
+
+// GetStats gets the distribution of the scores of a quiz in a time window.
+func (s *server) GetStats(ctx context.Context, req *api.GetStatsRequest) (*api.GetStatsResponse, error) {
+	filter := qservice.StatsFilter{Quiz: req.GetQuiz()}
+	if req.Since != nil {
+		filter.Since = req.Since.AsTime()
+	}
+	if req.Until != nil {
+		filter.Until = req.Until.AsTime()
+	}
+	stats, err := s.service.ScoreStats(ctx, filter)
+	if err != nil {
+		return nil, s.handleError(ctx, err)
+	}
+	res := &api.GetStatsResponse{
+		Quiz:              stats.Quiz,
+		Attempts:          int32(stats.Attempts),
+		Participants:      int32(stats.Participants),
+		Total:             int32(stats.Total),
+		Mean:              stats.Mean,
+		Median:            stats.Median,
+		StandardDeviation: stats.StandardDeviation,
+	}
+	for _, n := range stats.Histogram {
+		res.Histogram = append(res.Histogram, int32(n))
+	}
+	if c := stats.Caller; c != nil {
+		res.Caller = &api.CallerScore{Score: int32(c.Score), BetterThan: int32(c.BetterThan)}
+	}
+	return res, nil
+}
+
+// GetLeaderboard gets the participants with the best scores.
+func (s *server) GetLeaderboard(ctx context.Context, req *api.GetLeaderboardRequest) (*api.GetLeaderboardResponse, error) {
+	board, err := s.service.Leaderboard(ctx, req.GetQuiz(), req.GetCohort(), int(req.GetLimit()))
+	if err != nil {
+		return nil, s.handleError(ctx, err)
+	}
+	res := &api.GetLeaderboardResponse{Quiz: board.Quiz, Cohort: board.Cohort, Participants: int32(board.Participants)}
+	for _, e := range board.Entries {
+		res.Entries = append(res.Entries, &api.LeaderboardEntry{
+			Rank:             int32(e.Rank),
+			DisplayName:      e.DisplayName,
+			You:              e.You,
+			Score:            int32(e.Score),
+			Total:            int32(e.Total),
+			SubmittedAt:      timestamppb.New(e.SubmittedAt),
+			BetterThan:       int32(e.BetterThan),
+			CohortBetterThan: int32(e.CohortBetterThan),
+		})
+	}
+	return res, nil
+}
+
+// ListEvents lists the upcoming and open events.
+func (s *server) ListEvents(ctx context.Context, _ *emptypb.Empty) (*api.ListEventsResponse, error) {
+	events, err := s.service.Events(ctx)
+	if err != nil {
+		return nil, s.handleError(ctx, err)
+	}
+	res := &api.ListEventsResponse{}
+	for _, e := range events {
+		event := &api.Event{Name: e.Name, Title: e.Title, Status: string(e.Status)}
+		if !e.OpensAt.IsZero() {
+			event.OpensAt = timestamppb.New(e.OpensAt)
+		}
+		if !e.ClosesAt.IsZero() {
+			event.ClosesAt = timestamppb.New(e.ClosesAt)
+		}
+		res.Events = append(res.Events, event)
+	}
+	return res, nil
+}
+
+// adaptiveResponse converts the state of an adaptive quiz to the API format,
+// with the options of the next question ordered by ID.
+func (s *server) adaptiveResponse(ctx context.Context, result *qservice.AdaptiveResult) (*api.AdaptiveQuizResponse, error) {
+	res := &api.AdaptiveQuizResponse{
+		AttemptId:     result.AttemptID,
+		Asked:         int32(result.Asked),
+		Total:         int32(result.Total),
+		Correct:       int32(result.Correct),
+		Ability:       result.Ability,
+		StandardError: result.StandardError,
+	}
+	if q := result.Question; q != nil {
+		res.Question = &api.Question{Id: int32(q.ID), Text: q.Text, Difficulty: int32(q.Difficulty)}
+		for _, oID := range slices.Sorted(maps.Keys(q.Options)) {
+			res.Question.Options = append(res.Question.Options, &api.Option{Id: int32(oID), Text: q.Options[oID].Text})
+		}
+		return res, nil
+	}
+
+	for _, a := range result.Answers {
+		res.Answers = append(res.Answers, &api.Answer{QuestionId: int32(a.QuestionID), OptionId: int32(a.OptionID)})
+	}
+	solutions, err := s.processSolutions(ctx, qservice.DefaultQuiz, result.Solutions)
+	if err != nil {
+		return nil, err
+	}
+	res.Solutions = solutions
+	return res, nil
+}
+
+// processSolutions converts internal solution format to API response format,
+// with the questions of the quiz they solve.
+func (s *server) processSolutions(ctx context.Context, quiz string, ss map[store.QuestionID]store.OptionID) ([]*api.Solution, error) {
+	// The solutions of the submissions made during an event are withheld.
+	if len(ss) == 0 {
+		return nil, nil
+	}
+	qsts, err := s.service.ReviewQuestions(ctx, quiz)
 	if err != nil {
 		return nil, err
 	}
@@ -109,6 +411,7 @@ func (s *server) processSolutions(ss map[store.QuestionID]store.OptionID) ([]*ap
 		s := &api.Solution{
 			Question: q, CorrectOptionId: int32(oID),
 			CorrectOptionText: qsts[qID].Options[oID].Text,
+			Explanation:       qsts[qID].Explanation,
 		}
 		processed = append(processed, s)
 	}
@@ -116,41 +419,105 @@ func (s *server) processSolutions(ss map[store.QuestionID]store.OptionID) ([]*ap
 }
 
 // handleError centralizes the error handling. It maps domain level error codes
-// to gRPC codes and reports bugs.
-func (s *server) handleError(err error) error {
-	unknownError := status.Error(codes.Unknown, "an unexpected error occurred")
+// to gRPC codes and reports bugs. The status carries the request ID and, for
+// domain errors, the reason and the invalid fields as error details.
+func (s *server) handleError(ctx context.Context, err error) error {
+	unknownError := s.status(ctx, codes.Unknown, "an unexpected error occurred", nil)
+
+	// The client went away or ran out of time. The store gave up because of it,
+	// so this is neither a bug nor a domain error.
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return status.FromContextError(ctxErr).Err()
+	}
 
 	// If this is not a ServiceError we know is not a known edge case and is a real bug.
-	serviceErr, ok := err.(qservice.ServiceError)
-	if !ok {
-		s.reportBug(err)
+	var serviceErr qservice.ServiceError
+	if !errors.As(err, &serviceErr) {
+		s.reportBug(ctx, err)
 		return unknownError
 	}
 
 	// Get the inner error from ServiceError and check if it's a QError
-	if qErr, ok := errors.Unwrap(serviceErr).(qerr.QError); ok {
+	var qErr qerr.QError
+	if errors.As(serviceErr, &qErr) {
 		grpcCode, ok := errorCodeToGRPC[qErr.Code]
 		if !ok {
-			s.reportBug(fmt.Errorf("error mapping domain error code %d to gRPC error code", qErr.Code))
+			s.reportBug(ctx, fmt.Errorf("error mapping domain error code %d to gRPC error code", qErr.Code))
 			return unknownError
 		}
-		return status.Error(grpcCode, qErr.Message)
+		return s.status(ctx, grpcCode, qErr.Message, &qErr)
 	}
 
-	return status.Error(codes.Internal, serviceErr.Error())
+	return s.status(ctx, codes.Internal, serviceErr.Error(), nil)
 }
 
-// reportBug logs unexpected errors for debugging. Here we could send this bug to a
-// centralized destination.
-func (s *server) reportBug(err error) {
-	msg := "there was an unnespected issue; please report this as a bug"
-	s.logger.Error(msg, "err", fmt.Sprintf("%#v", err))
+// status creates a gRPC status error with the error details of qErr, if any,
+// and the request ID.
+func (s *server) status(ctx context.Context, code codes.Code, msg string, qErr *qerr.QError) error {
+	details := []protoadapt.MessageV1{
+		&errdetails.RequestInfo{RequestId: reqctx.RequestID(ctx)},
+	}
+
+	if qErr != nil {
+		reason := qErr.Reason
+		if reason == "" {
+			reason = qErr.Code.String()
+		}
+		info := &errdetails.ErrorInfo{Reason: reason, Domain: errorDomain}
+		if len(qErr.Misc) > 0 {
+			info.Metadata = make(map[string]string, len(qErr.Misc))
+			for k, v := range qErr.Misc {
+				info.Metadata[k] = fmt.Sprint(v)
+			}
+		}
+		details = append(details, info)
+
+		if len(qErr.Violations) > 0 {
+			badRequest := &errdetails.BadRequest{}
+			for _, v := range qErr.Violations {
+				badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+					Field:       v.Field,
+					Description: v.Description,
+				})
+			}
+			details = append(details, badRequest)
+		}
+	}
+
+	st, err := status.New(code, msg).WithDetails(details...)
+	if err != nil {
+		// The details are well known messages, so this is a bug. Return the bare status.
+		s.reportBug(ctx, fmt.Errorf("adding error details: %w", err))
+		return status.Error(code, msg)
+	}
+	return st.Err()
+}
+
+// errorDomain is the domain of the ErrorInfo details sent by the server.
+const errorDomain = "qstnnr"
+
+// reportBug sends unexpected errors to the bug reporter, along with the RPC
+// method that triggered them.
+func (s *server) reportBug(ctx context.Context, err error) {
+	method, _ := grpc.Method(ctx)
+	bug := bugs.New(ctx, method, err)
+	// The report must not be cut short because the client went away.
+	if err := s.bugs.Report(context.WithoutCancel(ctx), bug); err != nil {
+		s.logger.Error("error reporting bug", "err", err, "fingerprint", bug.Fingerprint)
+	}
 }
 
 // errorCodeToGRPC maps domain level errors to gRPC errors.
 var errorCodeToGRPC = map[qerr.ErrorCode]codes.Code{
-	qerr.Unknown:      codes.Unknown,
-	qerr.InvalidInput: codes.InvalidArgument,
-	qerr.NotFound:     codes.NotFound,
-	qerr.Internal:     codes.Internal,
+	qerr.Unknown:            codes.Unknown,
+	qerr.InvalidInput:       codes.InvalidArgument,
+	qerr.NotFound:           codes.NotFound,
+	qerr.Internal:           codes.Internal,
+	qerr.AlreadyExists:      codes.AlreadyExists,
+	qerr.FailedPrecondition: codes.FailedPrecondition,
+	qerr.PermissionDenied:   codes.PermissionDenied,
+	qerr.Unauthenticated:    codes.Unauthenticated,
+	qerr.ResourceExhausted:  codes.ResourceExhausted,
+	qerr.DeadlineExceeded:   codes.DeadlineExceeded,
+	qerr.Unavailable:        codes.Unavailable,
 }
@@ -2,17 +2,34 @@ package server_test
 
 import (
 	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net"
 	"testing"
+	"time"
 
 	"github.com/mateopresacastro/qstnnr/pkg/api"
+	"github.com/mateopresacastro/qstnnr/pkg/bugs"
+	"github.com/mateopresacastro/qstnnr/pkg/cohorts"
 	"github.com/mateopresacastro/qstnnr/pkg/qservice"
+	"github.com/mateopresacastro/qstnnr/pkg/reqctx"
+	"github.com/mateopresacastro/qstnnr/pkg/schedule"
 	"github.com/mateopresacastro/qstnnr/pkg/server"
 	"github.com/mateopresacastro/qstnnr/pkg/store"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
 )
@@ -33,8 +50,9 @@ func TestServer(t *testing.T) {
 
 	questions := map[store.QuestionID]store.Question{
 		1: {
-			ID:   1,
-			Text: "What is the capital of France?",
+			ID:          1,
+			Text:        "What is the capital of France?",
+			Explanation: "Paris has been the capital of France since 987.",
 			Options: map[store.OptionID]store.Option{
 				1: {ID: 1, Text: "London"},
 				2: {ID: 2, Text: "Paris"},
@@ -43,8 +61,9 @@ func TestServer(t *testing.T) {
 			},
 		},
 		2: {
-			ID:   2,
-			Text: "Which planet is known as the Red Planet?",
+			ID:         2,
+			Text:       "Which planet is known as the Red Planet?",
+			Difficulty: 4,
 			Options: map[store.OptionID]store.Option{
 				1: {ID: 1, Text: "Venus"},
 				2: {ID: 2, Text: "Mars"},
@@ -80,11 +99,13 @@ func TestServer(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	service := qservice.New(s)
+	service := qservice.New(s, qservice.WithCohorts(cohorts.Membership{"gopher": {"team-a"}}))
 
 	cfg := &server.Config{
-		Logger:  slog.Default(),
-		Service: service,
+		Logger:       slog.Default(),
+		Service:      service,
+		AdminTokens:  []string{"s3cret"},
+		Participants: map[[sha256.Size]byte]string{sha256.Sum256([]byte("gopher-token")): "gopher"},
 	}
 
 	server, err := server.New(cfg)
@@ -155,8 +176,8 @@ func TestServer(t *testing.T) {
 		if len(resp.Solutions) != 3 {
 			t.Errorf("expected 3 solution, got %d", len(resp.Solutions))
 		}
-		if resp.BetterThan != 100 {
-			t.Errorf("expected stats 100, got %d", resp.BetterThan)
+		if resp.BetterThan != 50 {
+			t.Errorf("expected stats 50 for the first submission, got %d", resp.BetterThan)
 		}
 
 		for _, sol := range resp.Solutions {
@@ -180,7 +201,7 @@ func TestServer(t *testing.T) {
 	})
 
 	t.Run("Should get solutions", func(t *testing.T) {
-		resp, err := client.GetSolutions(ctx, &emptypb.Empty{})
+		resp, err := client.GetSolutions(ctx, &api.GetSolutionsRequest{})
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -194,6 +215,9 @@ func TestServer(t *testing.T) {
 				if sol.CorrectOptionId != 2 {
 					t.Errorf("question 1: expected correct option ID 2, got %d", sol.CorrectOptionId)
 				}
+				if sol.Explanation != "Paris has been the capital of France since 987." {
+					t.Errorf("question 1: expected the explanation, got '%s'", sol.Explanation)
+				}
 			case 2:
 				if sol.CorrectOptionText != "Mars" {
 					t.Errorf("question 2: expected correct option 'Mars', got '%s'", sol.CorrectOptionText)
@@ -239,6 +263,241 @@ func TestServer(t *testing.T) {
 		}
 	})
 
+	t.Run("Should return error details for invalid answers", func(t *testing.T) {
+		ctx := metadata.AppendToOutgoingContext(ctx, reqctx.RequestIDKey, "req-42")
+		_, err := client.SubmitAnswers(ctx, &api.SubmitAnswersRequest{
+			Answers: []*api.Answer{
+				{QuestionId: 1, OptionId: 1},
+				{QuestionId: 7, OptionId: 1},
+				{QuestionId: 9, OptionId: 1},
+			},
+		})
+
+		st, ok := status.FromError(err)
+		if !ok {
+			t.Fatal("expected gRPC status error")
+		}
+
+		var (
+			badRequest  *errdetails.BadRequest
+			errorInfo   *errdetails.ErrorInfo
+			requestInfo *errdetails.RequestInfo
+		)
+		for _, detail := range st.Details() {
+			switch d := detail.(type) {
+			case *errdetails.BadRequest:
+				badRequest = d
+			case *errdetails.ErrorInfo:
+				errorInfo = d
+			case *errdetails.RequestInfo:
+				requestInfo = d
+			}
+		}
+
+		if badRequest == nil || len(badRequest.FieldViolations) != 2 {
+			t.Fatalf("expected 2 field violations, got %v", badRequest)
+		}
+		for i, qID := range []int{7, 9} {
+			v := badRequest.FieldViolations[i]
+			if v.Field != "answers.question_id" {
+				t.Errorf("expected field answers.question_id, got %s", v.Field)
+			}
+			if want := fmt.Sprintf("couldn't find question with id: %d", qID); v.Description != want {
+				t.Errorf("expected description %q, got %q", want, v.Description)
+			}
+		}
+
+		if errorInfo == nil {
+			t.Fatal("expected ErrorInfo")
+		}
+		if errorInfo.Reason != "UNKNOWN_QUESTION" || errorInfo.Domain != "qstnnr" {
+			t.Errorf("unexpected ErrorInfo: %v", errorInfo)
+		}
+		if errorInfo.Metadata["question_id"] != "7" {
+			t.Errorf("expected question_id 7 in the metadata, got %v", errorInfo.Metadata)
+		}
+
+		if requestInfo == nil || requestInfo.RequestId != "req-42" {
+			t.Errorf("expected RequestInfo with the request ID, got %v", requestInfo)
+		}
+	})
+
+	t.Run("Should return the original result when retrying with the same idempotency key", func(t *testing.T) {
+		req := &api.SubmitAnswersRequest{
+			Answers: []*api.Answer{
+				{QuestionId: 1, OptionId: 2},
+				{QuestionId: 2, OptionId: 1},
+				{QuestionId: 3, OptionId: 2},
+			},
+			IdempotencyKey: "retry-key",
+		}
+		first, err := client.SubmitAnswers(ctx, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		retry, err := client.SubmitAnswers(ctx, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if retry.Correct != first.Correct || retry.BetterThan != first.BetterThan {
+			t.Errorf("expected %d correct and better than %d, got %d and %d",
+				first.Correct, first.BetterThan, retry.Correct, retry.BetterThan)
+		}
+		if len(retry.Solutions) != len(first.Solutions) {
+			t.Errorf("expected %d solutions, got %d", len(first.Solutions), len(retry.Solutions))
+		}
+	})
+
+	t.Run("Should save and get the progress of the caller", func(t *testing.T) {
+		ctx := metadata.AppendToOutgoingContext(ctx, reqctx.CallerKey, "gopher")
+		_, err := client.SaveProgress(ctx, &api.SaveProgressRequest{
+			Answers: []*api.Answer{{QuestionId: 2, OptionId: 1}, {QuestionId: 1, OptionId: 2}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := client.GetProgress(ctx, &emptypb.Empty{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Answers) != 2 || res.Answers[0].QuestionId != 1 || res.Answers[1].QuestionId != 2 {
+			t.Errorf("expected the answers ordered by question ID, got %v", res.Answers)
+		}
+		if res.UpdatedAt.AsTime().IsZero() {
+			t.Error("expected the time of the last update")
+		}
+	})
+
+	t.Run("Should return NotFound without progress", func(t *testing.T) {
+		ctx := metadata.AppendToOutgoingContext(ctx, reqctx.CallerKey, "gordon")
+		_, err := client.GetProgress(ctx, &emptypb.Empty{})
+		if status.Code(err) != codes.NotFound {
+			t.Errorf("expected NotFound, got %v", status.Code(err))
+		}
+	})
+
+	t.Run("Should take an adaptive quiz", func(t *testing.T) {
+		ctx := metadata.AppendToOutgoingContext(ctx, reqctx.CallerKey, "gopher")
+		res, err := client.StartAdaptiveQuiz(ctx, &api.StartAdaptiveQuizRequest{MaxQuestions: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Question.GetId() != 1 || len(res.Question.Options) != 4 || res.Question.Options[0].Id != 1 {
+			t.Fatalf("expected the first medium question with its options in order, got %v", res.Question)
+		}
+
+		res, err = client.AnswerAdaptiveQuestion(ctx, &api.AnswerAdaptiveQuestionRequest{
+			AttemptId: res.AttemptId,
+			Answer:    &api.Answer{QuestionId: 1, OptionId: 2},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Question.GetDifficulty() != 4 || res.Asked != 2 || res.Ability <= 0 {
+			t.Fatalf("expected a harder question after a correct answer, got %v", res)
+		}
+
+		res, err = client.AnswerAdaptiveQuestion(ctx, &api.AnswerAdaptiveQuestionRequest{
+			AttemptId: res.AttemptId,
+			Answer:    &api.Answer{QuestionId: 2, OptionId: 1},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Question != nil || res.Correct != 1 || len(res.Answers) != 2 || len(res.Solutions) != 2 {
+			t.Errorf("expected the results of the 2 questions, got %v", res)
+		}
+	})
+
+	t.Run("Should return NotFound for unknown adaptive quizzes", func(t *testing.T) {
+		_, err := client.AnswerAdaptiveQuestion(ctx, &api.AnswerAdaptiveQuestionRequest{
+			AttemptId: "unknown",
+			Answer:    &api.Answer{QuestionId: 1, OptionId: 2},
+		})
+		if status.Code(err) != codes.NotFound {
+			t.Errorf("expected NotFound, got %v", status.Code(err))
+		}
+	})
+
+	t.Run("Should get the item stats as an admin", func(t *testing.T) {
+		_, err := client.GetItemStats(metadata.AppendToOutgoingContext(ctx, reqctx.CallerKey, "admin"), &emptypb.Empty{})
+		if status.Code(err) != codes.Unauthenticated {
+			t.Errorf("expected Unauthenticated without a token, got %v", status.Code(err))
+		}
+		_, err = client.GetItemStats(metadata.AppendToOutgoingContext(ctx, reqctx.AuthorizationKey, "Bearer guess"), &emptypb.Empty{})
+		if status.Code(err) != codes.Unauthenticated {
+			t.Errorf("expected Unauthenticated with an invalid token, got %v", status.Code(err))
+		}
+
+		res, err := client.GetItemStats(metadata.AppendToOutgoingContext(ctx, reqctx.AuthorizationKey, "Bearer s3cret"), &emptypb.Empty{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Attempts == 0 || len(res.Items) != 3 || res.Items[0].QuestionId != 1 {
+			t.Fatalf("expected the stats of the 3 questions, got %v", res)
+		}
+		if opts := res.Items[0].Options; len(opts) != 4 || opts[0].OptionId != 1 || !opts[1].Correct {
+			t.Errorf("expected the options of question 1 in order, got %v", opts)
+		}
+	})
+
+	t.Run("Should get the score distribution", func(t *testing.T) {
+		res, err := client.GetStats(ctx, &api.GetStatsRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Quiz != "default" || res.Attempts == 0 || len(res.Histogram) != int(res.Total)+1 {
+			t.Fatalf("expected a histogram of the questionnaire, got %v", res)
+		}
+		sum := int32(0)
+		for _, n := range res.Histogram {
+			sum += n
+		}
+		if sum != res.Attempts {
+			t.Errorf("expected the histogram to add up to %d attempts, got %d", res.Attempts, sum)
+		}
+
+		_, err = client.GetStats(ctx, &api.GetStatsRequest{Quiz: "trivia"})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("expected InvalidArgument for an unknown quiz, got %v", status.Code(err))
+		}
+	})
+
+	t.Run("Should rank within a cohort and on the leaderboard", func(t *testing.T) {
+		ctx := metadata.AppendToOutgoingContext(ctx, reqctx.CallerKey, "gopher")
+		req := &api.SubmitAnswersRequest{
+			Answers:     []*api.Answer{{QuestionId: 1, OptionId: 2}, {QuestionId: 2, OptionId: 1}, {QuestionId: 3, OptionId: 1}},
+			DisplayName: "The Gopher",
+			Cohort:      "team-a",
+		}
+		_, err := client.SubmitAnswers(ctx, req)
+		if status.Code(err) != codes.PermissionDenied {
+			t.Errorf("expected PermissionDenied for a cohort without the token of a member, got %v", err)
+		}
+
+		ctx = metadata.AppendToOutgoingContext(ctx, reqctx.AuthorizationKey, "Bearer gopher-token")
+		res, err := client.SubmitAnswers(ctx, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Cohort != "team-a" || res.CohortBetterThan != 50 {
+			t.Errorf("expected to rank 50 as the only one in team-a, got %v", res)
+		}
+
+		board, err := client.GetLeaderboard(ctx, &api.GetLeaderboardRequest{Cohort: "team-a"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(board.Entries) != 1 || board.Entries[0].DisplayName != "The Gopher" || !board.Entries[0].You || board.Entries[0].Rank != 1 || board.Entries[0].SubmittedAt == nil {
+			t.Errorf("expected gopher to lead team-a, got %v", board.Entries)
+		}
+
+		_, err = client.GetLeaderboard(ctx, &api.GetLeaderboardRequest{Limit: 1000})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("expected InvalidArgument for a limit over 100, got %v", status.Code(err))
+		}
+	})
+
 	t.Run("Should return InvalidArgument when submitting empty answers", func(t *testing.T) {
 		_, err := client.SubmitAnswers(ctx, &api.SubmitAnswersRequest{
 			Answers: []*api.Answer{}, // Empty answers
@@ -257,3 +516,592 @@ func TestServer(t *testing.T) {
 		}
 	})
 }
+
+func TestEvents(t *testing.T) {
+	s, err := store.NewInMemory(store.InitialData{
+		Questions: map[store.QuestionID]store.Question{
+			1: {ID: 1, Text: "What is 2 + 2?", Options: map[store.OptionID]store.Option{
+				1: {ID: 1, Text: "3"},
+				2: {ID: 2, Text: "4"},
+			}},
+		},
+		Solutions: map[store.QuestionID]store.OptionID{1: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := func(t *testing.T, events ...schedule.Event) api.QuestionnaireClient {
+		t.Helper()
+		ln, err := net.Listen("tcp", ":0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		srv, err := server.New(&server.Config{
+			Logger:  slog.Default(),
+			Service: qservice.New(s, qservice.WithSchedule(events)),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		go srv.Serve(ln)
+		t.Cleanup(srv.Stop)
+
+		conn, err := grpc.NewClient(ln.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return api.NewQuestionnaireClient(conn)
+	}
+
+	ctx := context.Background()
+	// Every event asks its own question, whose correct option is the name of
+	// the event.
+	quiz := func(name string) store.InitialData {
+		return store.InitialData{
+			Questions: map[store.QuestionID]store.Question{
+				1: {ID: 1, Text: "Which event is this?", Options: map[store.OptionID]store.Option{
+					1: {ID: 1, Text: "another"},
+					2: {ID: 2, Text: name},
+				}},
+			},
+			Solutions: map[store.QuestionID]store.OptionID{1: 2},
+		}
+	}
+	now := time.Now().Truncate(time.Second)
+	past := schedule.Event{Name: "september", OpensAt: now.Add(-48 * time.Hour), ClosesAt: now.Add(-47 * time.Hour), Bank: quiz("september")}
+	open := schedule.Event{Name: "october", Title: "Go Quiz, October", OpensAt: now.Add(-time.Hour), ClosesAt: now.Add(time.Hour), Bank: quiz("october")}
+	upcoming := schedule.Event{Name: "november", OpensAt: now.Add(24 * time.Hour), Bank: quiz("november")}
+	answers := []*api.Answer{{QuestionId: 1, OptionId: 2}}
+
+	t.Run("Should ask the questions of the open event and withhold its solutions until it closes", func(t *testing.T) {
+		client := start(t, past, open, upcoming)
+		questions, err := client.GetQuestions(ctx, &emptypb.Empty{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(questions.Questions) != 1 || questions.Questions[0].Text != "Which event is this?" {
+			t.Errorf("expected the question of october, got %v", questions.Questions)
+		}
+
+		res, err := client.SubmitAnswers(ctx, &api.SubmitAnswersRequest{Answers: answers})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Correct != 1 || res.Quiz != "october" || !res.SolutionsWithheld || len(res.Solutions) != 0 ||
+			!res.SolutionsPublishedAt.AsTime().Equal(open.ClosesAt) {
+			t.Errorf("expected the solutions of october withheld until it closes, got %v", res)
+		}
+		_, err = client.GetSolutions(ctx, &api.GetSolutionsRequest{})
+		if status.Code(err) != codes.FailedPrecondition {
+			t.Errorf("expected FailedPrecondition for the solutions of october, got %v", status.Code(err))
+		}
+		sols, err := client.GetSolutions(ctx, &api.GetSolutionsRequest{Quiz: "september"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(sols.Solutions) != 1 || sols.Solutions[0].CorrectOptionText != "september" {
+			t.Errorf("expected the solutions of september, got %v", sols.Solutions)
+		}
+
+		// Adaptive quizzes ask the questions of the store, not of the events.
+		adaptive, err := client.StartAdaptiveQuiz(ctx, &api.StartAdaptiveQuizRequest{MaxQuestions: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		adaptive, err = client.AnswerAdaptiveQuestion(ctx, &api.AnswerAdaptiveQuestionRequest{
+			AttemptId: adaptive.AttemptId,
+			Answer:    &api.Answer{QuestionId: adaptive.Question.Id, OptionId: 2},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if adaptive.Correct != 1 || len(adaptive.Solutions) != 1 || adaptive.Solutions[0].CorrectOptionText != "4" {
+			t.Errorf("expected the solutions of the adaptive quiz, got %v", adaptive)
+		}
+
+		list, err := client.ListEvents(ctx, &emptypb.Empty{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list.Events) != 2 || list.Events[0].Name != "october" || list.Events[0].Status != "open" ||
+			list.Events[1].Status != "upcoming" || list.Events[1].ClosesAt != nil {
+			t.Errorf("expected october open and november upcoming, got %v", list.Events)
+		}
+	})
+
+	t.Run("Should reject the quiz outside the events", func(t *testing.T) {
+		client := start(t, past, upcoming)
+		_, err := client.GetQuestions(ctx, &emptypb.Empty{})
+		if status.Code(err) != codes.FailedPrecondition {
+			t.Fatalf("expected FailedPrecondition, got %v", err)
+		}
+		var info *errdetails.ErrorInfo
+		for _, d := range status.Convert(err).Details() {
+			if i, ok := d.(*errdetails.ErrorInfo); ok {
+				info = i
+			}
+		}
+		if info == nil || info.Reason != "QUIZ_CLOSED" || info.Metadata["event"] != "november" {
+			t.Errorf("expected reason QUIZ_CLOSED until november, got %v", info)
+		}
+		_, err = client.SubmitAnswers(ctx, &api.SubmitAnswersRequest{Answers: answers})
+		if status.Code(err) != codes.FailedPrecondition {
+			t.Errorf("expected FailedPrecondition for the submission, got %v", status.Code(err))
+		}
+		_, err = client.GetSolutions(ctx, &api.GetSolutionsRequest{Quiz: "november"})
+		if status.Code(err) != codes.FailedPrecondition {
+			t.Errorf("expected FailedPrecondition for the solutions of november, got %v", status.Code(err))
+		}
+		_, err = client.GetSolutions(ctx, &api.GetSolutionsRequest{Quiz: "december"})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("expected InvalidArgument for an unknown quiz, got %v", status.Code(err))
+		}
+	})
+
+	t.Run("Should publish the solutions of the last event once it closes", func(t *testing.T) {
+		client := start(t, past, upcoming)
+		res, err := client.GetSolutions(ctx, &api.GetSolutionsRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Solutions) != 1 || res.Solutions[0].CorrectOptionText != "september" {
+			t.Errorf("expected the solutions of september, got %v", res.Solutions)
+		}
+	})
+}
+
+func TestHealth(t *testing.T) {
+	data := store.InitialData{
+		Questions: map[store.QuestionID]store.Question{},
+		Solutions: map[store.QuestionID]store.OptionID{},
+	}
+	s, err := store.NewInMemory(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := func(t *testing.T, s store.Store) (*server.Server, *grpc.ClientConn) {
+		t.Helper()
+		ln, err := net.Listen("tcp", ":0")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		srv, err := server.New(&server.Config{
+			Logger:  slog.Default(),
+			Service: qservice.New(s),
+			Version: "v1.2.3",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		go srv.Serve(ln)
+
+		conn, err := grpc.NewClient(ln.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return srv, conn
+	}
+
+	ctx := context.Background()
+
+	t.Run("Should report SERVING", func(t *testing.T) {
+		srv, conn := start(t, s)
+		defer srv.GracefulStop()
+
+		for _, service := range []string{"", "api.Questionnaire"} {
+			resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Status != healthpb.HealthCheckResponse_SERVING {
+				t.Errorf("service %q: expected SERVING, got %v", service, resp.Status)
+			}
+		}
+	})
+
+	t.Run("Should report NOT_SERVING if the store is unhealthy", func(t *testing.T) {
+		srv, conn := start(t, &unhealthyStore{Store: s})
+		defer srv.GracefulStop()
+
+		resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Status != healthpb.HealthCheckResponse_NOT_SERVING {
+			t.Errorf("expected NOT_SERVING, got %v", resp.Status)
+		}
+	})
+
+	t.Run("Should report NOT_SERVING while shutting down", func(t *testing.T) {
+		srv, conn := start(t, s)
+
+		watchCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		stream, err := healthpb.NewHealthClient(conn).Watch(watchCtx, &healthpb.HealthCheckRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := stream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Status != healthpb.HealthCheckResponse_SERVING {
+			t.Fatalf("expected SERVING, got %v", resp.Status)
+		}
+
+		stopped := make(chan struct{})
+		go func() {
+			srv.GracefulStop()
+			close(stopped)
+		}()
+
+		resp, err = stream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Status != healthpb.HealthCheckResponse_NOT_SERVING {
+			t.Errorf("expected NOT_SERVING, got %v", resp.Status)
+		}
+
+		// The open stream is the last pending RPC.
+		cancel()
+		<-stopped
+	})
+
+	t.Run("Should return the server info", func(t *testing.T) {
+		srv, conn := start(t, s)
+		defer srv.GracefulStop()
+
+		info, err := api.NewQuestionnaireClient(conn).GetServerInfo(ctx, &emptypb.Empty{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Version != "v1.2.3" {
+			t.Errorf("expected version v1.2.3, got %s", info.Version)
+		}
+		if info.StartedAt.AsTime().IsZero() {
+			t.Error("expected the start time to be set")
+		}
+	})
+
+	t.Run("Should register server reflection", func(t *testing.T) {
+		srv, conn := start(t, s)
+		defer srv.GracefulStop()
+
+		stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer stream.CloseSend()
+
+		req := &reflectionpb.ServerReflectionRequest{
+			MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+		}
+		if err := stream.Send(req); err != nil {
+			t.Fatal(err)
+		}
+		resp, err := stream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		services := make(map[string]bool)
+		for _, s := range resp.GetListServicesResponse().GetService() {
+			services[s.Name] = true
+		}
+		for _, want := range []string{"api.Questionnaire", "grpc.health.v1.Health"} {
+			if !services[want] {
+				t.Errorf("expected %s to be listed by reflection", want)
+			}
+		}
+	})
+}
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer provider.Shutdown(context.Background())
+
+	s, err := store.NewInMemory(store.InitialData{
+		Questions: map[store.QuestionID]store.Question{
+			1: {ID: 1, Text: "What is 2 + 2?", Options: map[store.OptionID]store.Option{
+				1: {ID: 1, Text: "3"},
+				2: {ID: 2, Text: "4"},
+			}},
+		},
+		Solutions: map[store.QuestionID]store.OptionID{1: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ln, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, err := server.New(&server.Config{Logger: slog.Default(), Service: qservice.New(s)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve(ln)
+	defer srv.GracefulStop()
+
+	conn, err := grpc.NewClient(
+		ln.Addr().String(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// The client span plays the role of the CLI command span.
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "qstnnr take")
+	_, err = api.NewQuestionnaireClient(conn).SubmitAnswers(ctx, &api.SubmitAnswersRequest{
+		Answers: []*api.Answer{{QuestionId: 1, OptionId: 2}},
+	})
+	parent.End()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	traceID := parent.SpanContext().TraceID()
+	spans := make(map[string][]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		if span.SpanContext().TraceID() != traceID {
+			t.Errorf("span %s is not part of the client trace", span.Name())
+		}
+		spans[span.Name()] = append(spans[span.Name()], span)
+	}
+
+	// isChild reports whether a span called name has a parent called parentName.
+	isChild := func(name, parentName string) bool {
+		for _, span := range spans[name] {
+			for _, p := range spans[parentName] {
+				if span.Parent().SpanID() == p.SpanContext().SpanID() {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	parents := map[string]string{
+		"api.Questionnaire/SubmitAnswers": "qstnnr take",
+		"QstnnrService.SubmitAnswers":     "api.Questionnaire/SubmitAnswers",
+		"QstnnrService.stats":             "QstnnrService.SubmitAnswers",
+		"Store.Questions":                 "QstnnrService.SubmitAnswers",
+		"Store.Solutions":                 "QstnnrService.SubmitAnswers",
+		"Store.SaveSubmission":            "QstnnrService.SubmitAnswers",
+		"Store.Scores":                    "QstnnrService.stats",
+	}
+	for name, parentName := range parents {
+		if !isChild(name, parentName) {
+			t.Errorf("expected %s to be a child of %s", name, parentName)
+		}
+	}
+}
+
+func TestRequestScope(t *testing.T) {
+	ln, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := &scopeService{}
+	srv, err := server.New(&server.Config{Logger: slog.Default(), Service: service})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve(ln)
+	defer srv.GracefulStop()
+
+	conn, err := grpc.NewClient(ln.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := api.NewQuestionnaireClient(conn)
+
+	t.Run("Should pass the caller and request ID to the service", func(t *testing.T) {
+		ctx := metadata.AppendToOutgoingContext(context.Background(),
+			reqctx.CallerKey, "gopher",
+			reqctx.RequestIDKey, "req-1",
+		)
+		var header metadata.MD
+		if _, err := client.GetQuestions(ctx, &emptypb.Empty{}, grpc.Header(&header)); err != nil {
+			t.Fatal(err)
+		}
+		if service.caller != "gopher" {
+			t.Errorf("expected caller gopher, got %s", service.caller)
+		}
+		if service.requestID != "req-1" {
+			t.Errorf("expected request ID req-1, got %s", service.requestID)
+		}
+		if got := header.Get(reqctx.RequestIDKey); len(got) != 1 || got[0] != "req-1" {
+			t.Errorf("expected the request ID in the response header, got %v", got)
+		}
+	})
+
+	t.Run("Should generate a request ID for anonymous callers", func(t *testing.T) {
+		var header metadata.MD
+		if _, err := client.GetQuestions(context.Background(), &emptypb.Empty{}, grpc.Header(&header)); err != nil {
+			t.Fatal(err)
+		}
+		if service.caller != reqctx.Anonymous {
+			t.Errorf("expected caller %s, got %s", reqctx.Anonymous, service.caller)
+		}
+		if service.requestID == "" {
+			t.Fatal("expected a generated request ID")
+		}
+		if got := header.Get(reqctx.RequestIDKey); len(got) != 1 || got[0] != service.requestID {
+			t.Errorf("expected the generated request ID in the response header, got %v", got)
+		}
+	})
+}
+
+func TestBugReporting(t *testing.T) {
+	ln, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reporter := &recordingReporter{}
+	srv, err := server.New(&server.Config{
+		Logger:      slog.Default(),
+		Service:     &buggyService{},
+		BugReporter: reporter,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve(ln)
+	defer srv.GracefulStop()
+
+	conn, err := grpc.NewClient(ln.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := api.NewQuestionnaireClient(conn)
+
+	t.Run("Should report unexpected errors with the RPC method", func(t *testing.T) {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), reqctx.RequestIDKey, "req-1")
+		_, err := client.GetQuestions(ctx, &emptypb.Empty{})
+		if status.Code(err) != codes.Unknown {
+			t.Errorf("expected Unknown, got %v", status.Code(err))
+		}
+		if len(reporter.bugs) != 1 {
+			t.Fatalf("expected 1 bug, got %d", len(reporter.bugs))
+		}
+		bug := reporter.bugs[0]
+		if bug.Method != api.Questionnaire_GetQuestions_FullMethodName {
+			t.Errorf("expected method %s, got %s", api.Questionnaire_GetQuestions_FullMethodName, bug.Method)
+		}
+		if bug.RequestID != "req-1" {
+			t.Errorf("expected request ID req-1, got %s", bug.RequestID)
+		}
+		if bug.Message != "boom" || bug.StackTrace == "" || bug.Fingerprint == "" {
+			t.Errorf("expected the error and its stack trace, got %+v", bug)
+		}
+	})
+
+	t.Run("Should map wrapped service errors without reporting them", func(t *testing.T) {
+		s, err := store.NewInMemory(store.InitialData{
+			Questions: map[store.QuestionID]store.Question{},
+			Solutions: map[store.QuestionID]store.OptionID{},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		past := schedule.Event{Name: "september", ClosesAt: time.Now().Add(-time.Hour)}
+		ln, err := net.Listen("tcp", ":0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		reporter := &recordingReporter{}
+		srv, err := server.New(&server.Config{
+			Logger:      slog.Default(),
+			Service:     &wrappingService{qservice.New(s, qservice.WithSchedule([]schedule.Event{past}))},
+			BugReporter: reporter,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		go srv.Serve(ln)
+		defer srv.GracefulStop()
+
+		conn, err := grpc.NewClient(ln.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		_, err = api.NewQuestionnaireClient(conn).GetQuestions(context.Background(), &emptypb.Empty{})
+		if status.Code(err) != codes.FailedPrecondition {
+			t.Errorf("expected FailedPrecondition, got %v", err)
+		}
+		if len(reporter.bugs) != 0 {
+			t.Errorf("expected no bugs, got %+v", reporter.bugs)
+		}
+	})
+}
+
+// recordingReporter records the bugs it receives.
+type recordingReporter struct {
+	bugs []bugs.Bug
+}
+
+func (r *recordingReporter) Report(ctx context.Context, bug bugs.Bug) error {
+	r.bugs = append(r.bugs, bug)
+	return nil
+}
+
+// buggyService fails with errors that are not known edge cases.
+type buggyService struct {
+	qservice.QService
+}
+
+func (s *buggyService) Questions(ctx context.Context) (map[store.QuestionID]store.Question, error) {
+	return nil, errors.New("boom")
+}
+
+// wrappingService adds context to the errors of the service.
+type wrappingService struct {
+	qservice.QService
+}
+
+func (s *wrappingService) Questions(ctx context.Context) (map[store.QuestionID]store.Question, error) {
+	qsts, err := s.QService.Questions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing the questions: %w", err)
+	}
+	return qsts, nil
+}
+
+// scopeService records the request scoped values it receives.
+type scopeService struct {
+	qservice.QService
+	caller    string
+	requestID string
+}
+
+func (s *scopeService) Questions(ctx context.Context) (map[store.QuestionID]store.Question, error) {
+	s.caller = reqctx.Caller(ctx)
+	s.requestID = reqctx.RequestID(ctx)
+	return map[store.QuestionID]store.Question{}, nil
+}
+
+type unhealthyStore struct {
+	store.Store
+}
+
+func (s *unhealthyStore) Ping(ctx context.Context) error {
+	return store.StoreError{}
+}
@@ -0,0 +1,197 @@
+// Package srs schedules reviews with spaced repetition, following the SM-2
+// algorithm: every successful recall pushes the next review further away, by a
+// factor that depends on how easy the recall was, while a failed one starts
+// over with short intervals.
+package srs
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"time"
+)
+
+// Grade is the quality of a recall, from 0 for a complete blackout to 5 for a
+// perfect answer. Grades under 3 are failed recalls.
+type Grade int
+
+// Grades used for multiple choice questions: a wrong answer is failed and a
+// correct one is graded by how hard it was to recall.
+const (
+	Again Grade = 1
+	Hard  Grade = 3
+	Good  Grade = 4
+	Easy  Grade = 5
+)
+
+const (
+	// DefaultEase is the ease factor of new cards.
+	DefaultEase = 2.5
+	// MinEase keeps the intervals of hard cards growing.
+	MinEase = 1.3
+	// MatureInterval is the interval, in days, from which a card is considered
+	// well learned.
+	MatureInterval = 21
+)
+
+// Card is the review schedule of a question.
+type Card struct {
+	// Repetitions is the number of successful recalls in a row.
+	Repetitions int `json:"repetitions"`
+	// Interval is the number of days until the next review.
+	Interval int     `json:"interval"`
+	Ease     float64 `json:"ease"`
+	// Due is the day of the next review.
+	Due        time.Time `json:"due"`
+	Added      time.Time `json:"added"`
+	LastReview time.Time `json:"last_review"`
+	LastGrade  Grade     `json:"last_grade"`
+	Reviews    int       `json:"reviews"`
+	// Recalls and Lapses count the successful and failed reviews of learned
+	// cards, those with at least one successful recall, to measure retention.
+	Recalls int `json:"recalls"`
+	Lapses  int `json:"lapses"`
+}
+
+// NewCard returns the card of a question never reviewed.
+func NewCard(now time.Time) Card {
+	return Card{Ease: DefaultEase, Due: day(now), Added: now}
+}
+
+// Review returns the card scheduled after a review with the given grade.
+func (c Card) Review(g Grade, now time.Time) Card {
+	learned := c.Repetitions > 0
+	if g < Hard {
+		if learned {
+			c.Lapses++
+		}
+		c.Repetitions = 0
+		c.Interval = 1
+	} else {
+		if learned {
+			c.Recalls++
+		}
+		switch c.Repetitions {
+		case 0:
+			c.Interval = 1
+		case 1:
+			c.Interval = 6
+		default:
+			c.Interval = int(math.Round(float64(c.Interval) * c.Ease))
+		}
+		c.Repetitions++
+	}
+	// The ease changes with every review, failed ones included.
+	q := float64(5 - g)
+	c.Ease = max(c.Ease+0.1-q*(0.08+q*0.02), MinEase)
+
+	c.Reviews++
+	c.LastGrade = g
+	c.LastReview = now
+	c.Due = day(now).AddDate(0, 0, c.Interval)
+	return c
+}
+
+// IsDue reports whether the card has to be reviewed on the day of now.
+func (c Card) IsDue(now time.Time) bool {
+	return !c.Due.After(day(now))
+}
+
+// Failed reports whether the last review of the card failed.
+func (c Card) Failed() bool {
+	return c.Reviews > 0 && c.LastGrade < Hard
+}
+
+// Deck holds the cards of a user by question ID. Questions without a card have
+// never been reviewed.
+type Deck map[int]Card
+
+// Queue returns the questions to review on the day of now, out of the given
+// ones: the cards due, those failed last time first and then the most overdue,
+// followed by up to newPerDay new questions a day.
+func (d Deck) Queue(ids []int, now time.Time, newPerDay int) []int {
+	var due, unseen []int
+	for _, id := range ids {
+		card, ok := d[id]
+		switch {
+		case !ok:
+			unseen = append(unseen, id)
+		case card.IsDue(now):
+			due = append(due, id)
+		}
+	}
+	slices.SortFunc(due, func(a, b int) int {
+		ca, cb := d[a], d[b]
+		if ca.Failed() != cb.Failed() {
+			if ca.Failed() {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Or(ca.Due.Compare(cb.Due), cmp.Compare(a, b))
+	})
+	slices.Sort(unseen)
+
+	added := 0
+	for _, card := range d {
+		if day(card.Added).Equal(day(now)) {
+			added++
+		}
+	}
+	n := min(max(newPerDay-added, 0), len(unseen))
+	return append(due, unseen[:n]...)
+}
+
+// Stats describes how well the questions are learned.
+type Stats struct {
+	Questions int `json:"questions" yaml:"questions"`
+	// New questions have never been reviewed.
+	New int `json:"new" yaml:"new"`
+	// Learning cards have an interval shorter than MatureInterval, mature
+	// ones have a longer one.
+	Learning int `json:"learning" yaml:"learning"`
+	Mature   int `json:"mature" yaml:"mature"`
+	DueToday int `json:"due_today" yaml:"due_today"`
+	Reviews  int `json:"reviews" yaml:"reviews"`
+	// Retention is the share of successful reviews of learned cards, from 0
+	// to 1. It's 0 before any learned card is reviewed.
+	Retention float64 `json:"retention" yaml:"retention"`
+	// NextDue is the next day with reviews after today, nil if there are none.
+	NextDue *time.Time `json:"next_due,omitempty" yaml:"next_due,omitempty"`
+}
+
+// Stats returns the statistics of the deck for the given questions.
+func (d Deck) Stats(ids []int, now time.Time) Stats {
+	s := Stats{Questions: len(ids)}
+	recalls, lapses := 0, 0
+	for _, id := range ids {
+		card, ok := d[id]
+		if !ok {
+			s.New++
+			continue
+		}
+		if card.Interval >= MatureInterval {
+			s.Mature++
+		} else {
+			s.Learning++
+		}
+		if card.IsDue(now) {
+			s.DueToday++
+		} else if s.NextDue == nil || card.Due.Before(*s.NextDue) {
+			s.NextDue = &card.Due
+		}
+		s.Reviews += card.Reviews
+		recalls += card.Recalls
+		lapses += card.Lapses
+	}
+	if recalls+lapses > 0 {
+		s.Retention = float64(recalls) / float64(recalls+lapses)
+	}
+	return s
+}
+
+// day returns the start of the day of t, in its location.
+func day(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
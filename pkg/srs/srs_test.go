@@ -0,0 +1,113 @@
+package srs_test
+
+import (
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/mateopresacastro/qstnnr/pkg/srs"
+)
+
+func TestSRS(t *testing.T) {
+	now := time.Date(2026, 10, 19, 15, 30, 0, 0, time.UTC)
+	today := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+
+	t.Run("should grow the intervals with successful recalls", func(t *testing.T) {
+		card := srs.NewCard(now)
+		var intervals []int
+		for range 4 {
+			card = card.Review(srs.Good, now)
+			intervals = append(intervals, card.Interval)
+		}
+		// 1 and 6 days, then the previous interval times the ease, which stays
+		// at 2.5 with Good grades.
+		want := []int{1, 6, 15, 38}
+		if !slices.Equal(intervals, want) {
+			t.Errorf("expected intervals %v, got %v", want, intervals)
+		}
+		if card.Ease != srs.DefaultEase {
+			t.Errorf("expected the ease to stay at %v, got %v", srs.DefaultEase, card.Ease)
+		}
+		if want := today.AddDate(0, 0, 38); !card.Due.Equal(want) {
+			t.Errorf("expected the card due on %v, got %v", want, card.Due)
+		}
+	})
+
+	t.Run("should adjust the ease with the grade", func(t *testing.T) {
+		easy := srs.NewCard(now).Review(srs.Easy, now)
+		if math.Abs(easy.Ease-2.6) > 1e-9 {
+			t.Errorf("expected the ease to grow to 2.6, got %v", easy.Ease)
+		}
+		hard := srs.NewCard(now).Review(srs.Hard, now)
+		if math.Abs(hard.Ease-2.36) > 1e-9 {
+			t.Errorf("expected the ease to drop to 2.36, got %v", hard.Ease)
+		}
+		card := srs.NewCard(now)
+		for range 10 {
+			card = card.Review(srs.Again, now)
+		}
+		if card.Ease != srs.MinEase {
+			t.Errorf("expected the ease to stop at %v, got %v", srs.MinEase, card.Ease)
+		}
+	})
+
+	t.Run("should start over after a lapse", func(t *testing.T) {
+		card := srs.NewCard(now).Review(srs.Good, now).Review(srs.Good, now).Review(srs.Again, now)
+		if card.Repetitions != 0 || card.Interval != 1 {
+			t.Errorf("expected the card to start over, got %d repetitions and an interval of %d", card.Repetitions, card.Interval)
+		}
+		if card.Recalls != 1 || card.Lapses != 1 {
+			t.Errorf("expected 1 recall and 1 lapse, got %d and %d", card.Recalls, card.Lapses)
+		}
+		if !card.Failed() {
+			t.Error("expected the card to be failed")
+		}
+	})
+
+	t.Run("should queue failed cards first, then overdue and new ones", func(t *testing.T) {
+		yesterday := now.AddDate(0, 0, -1)
+		lastWeek := now.AddDate(0, 0, -7)
+		deck := srs.Deck{
+			// Most overdue.
+			1: srs.NewCard(lastWeek).Review(srs.Good, lastWeek.AddDate(0, 0, -1)),
+			// Failed yesterday, due today.
+			2: srs.NewCard(yesterday).Review(srs.Again, yesterday),
+			// Overdue.
+			3: srs.NewCard(lastWeek).Review(srs.Good, lastWeek),
+			// Not due yet.
+			4: srs.NewCard(now).Review(srs.Easy, now),
+		}
+		got := deck.Queue([]int{1, 2, 3, 4, 5, 6, 7}, now, 2)
+		// Card 4 was added today, so only one new question fits today.
+		want := []int{2, 1, 3, 5}
+		if !slices.Equal(got, want) {
+			t.Errorf("expected the queue %v, got %v", want, got)
+		}
+	})
+
+	t.Run("should compute the statistics", func(t *testing.T) {
+		card := srs.NewCard(now)
+		for range 5 {
+			card = card.Review(srs.Good, now)
+		}
+		deck := srs.Deck{
+			1: card,
+			2: srs.NewCard(now).Review(srs.Good, now).Review(srs.Again, now),
+		}
+		stats := deck.Stats([]int{1, 2, 3}, now)
+		if stats.Questions != 3 || stats.New != 1 || stats.Learning != 1 || stats.Mature != 1 {
+			t.Errorf("unexpected counts: %+v", stats)
+		}
+		if stats.Reviews != 7 {
+			t.Errorf("expected 7 reviews, got %d", stats.Reviews)
+		}
+		// 4 recalls of the first card and the lapse of the second one.
+		if math.Abs(stats.Retention-0.8) > 1e-9 {
+			t.Errorf("expected a retention of 0.8, got %v", stats.Retention)
+		}
+		if want := today.AddDate(0, 0, 1); stats.NextDue == nil || !stats.NextDue.Equal(want) {
+			t.Errorf("expected the next review on %v, got %v", want, stats.NextDue)
+		}
+	})
+}
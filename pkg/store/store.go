@@ -1,25 +1,108 @@
 package store
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
 	"sync"
+	"time"
 )
 
 // Store defines the interface for persistent storage operations
 // of questions, solutions, and scores.
 type Store interface {
-	Questions() (map[QuestionID]Question, error)
-	Solutions() (map[QuestionID]OptionID, error)
-	SaveScore(score Score) error
-	AllScores() ([]Score, error)
+	Questions(ctx context.Context) (map[QuestionID]Question, error)
+	Solutions(ctx context.Context) (map[QuestionID]OptionID, error)
+	SaveAttempt(ctx context.Context, attempt Attempt) error
+	Scores(ctx context.Context, quiz, cohort string) ([]Score, error)
+	Attempts(ctx context.Context, user string, since time.Time) ([]Attempt, error)
+	AllAttempts(ctx context.Context) ([]Attempt, error)
+	SaveSubmission(ctx context.Context, submission Submission, attempt Attempt, limit AttemptLimit) error
+	Submission(ctx context.Context, key string) (Submission, error)
+	DeleteExpired(ctx context.Context) error
+	SaveProgress(ctx context.Context, progress Progress) error
+	Progress(ctx context.Context, user string) (Progress, error)
+	DeleteProgress(ctx context.Context, user string) error
+	SaveAdaptiveAttempt(ctx context.Context, attempt AdaptiveAttempt) error
+	AdaptiveAttempt(ctx context.Context, id string) (AdaptiveAttempt, error)
+	Ping(ctx context.Context) error
 }
 
+// ErrSubmissionNotFound is the cause of the StoreError returned when there is
+// no submission for an idempotency key, or it is past the retention window.
+var ErrSubmissionNotFound = errors.New("submission not found")
+
+// ErrSubmissionExists is the cause of the StoreError returned when saving a
+// submission with an idempotency key that is already taken.
+var ErrSubmissionExists = errors.New("submission already exists")
+
+// ErrAttemptLimit is the cause of the StoreError returned when saving an
+// attempt over its AttemptLimit.
+var ErrAttemptLimit = errors.New("attempt limit reached")
+
+// ErrProgressNotFound is the cause of the StoreError returned when a user has
+// no quiz in progress.
+var ErrProgressNotFound = errors.New("progress not found")
+
+// ErrAdaptiveAttemptNotFound is the cause of the StoreError returned when there
+// is no adaptive attempt with an ID.
+var ErrAdaptiveAttemptNotFound = errors.New("adaptive attempt not found")
+
+// ErrAdaptiveAttemptConflict is the cause of the StoreError returned when saving
+// an adaptive attempt that was saved since it was read.
+var ErrAdaptiveAttemptConflict = errors.New("adaptive attempt was updated concurrently")
+
+// DefaultKeyRetention is how long the idempotency keys of the submissions are
+// kept by default.
+const DefaultKeyRetention = 24 * time.Hour
+
+// DefaultAdaptiveRetention is how long the adaptive attempts are kept after
+// their last answer by default.
+const DefaultAdaptiveRetention = 24 * time.Hour
+
 type memoryStore struct {
-	questions map[QuestionID]Question
-	solutions map[QuestionID]OptionID
-	scores    []Score
-	mu        sync.RWMutex
+	questions    map[QuestionID]Question
+	solutions    map[QuestionID]OptionID
+	attempts     []Attempt
+	byUser       map[string][]int // indexes of the attempts
+	byAddr       map[string][]int
+	scores       map[scoresKey][]Score
+	submissions  map[string]Submission
+	progress     map[string]Progress
+	adaptive     map[string]AdaptiveAttempt
+	keyRetention time.Duration
+	// adaptiveRetention is how long the adaptive attempts are kept after
+	// their last update.
+	adaptiveRetention time.Duration
+	mu                sync.RWMutex
+}
+
+// scoresKey indexes the scores of the attempts by quiz and cohort, an empty
+// cohort holding those of all the attempts of the quiz.
+type scoresKey struct {
+	quiz   string
+	cohort string
+}
+
+// InMemoryOption configures optional behaviour of the in-memory store.
+type InMemoryOption func(*memoryStore)
+
+// WithKeyRetention sets how long the idempotency keys of the submissions are
+// kept. Retrying a submission after that scores it again.
+func WithKeyRetention(d time.Duration) InMemoryOption {
+	return func(s *memoryStore) {
+		s.keyRetention = d
+	}
+}
+
+// WithAdaptiveRetention sets how long the adaptive attempts are kept after
+// their last answer. An adaptive quiz left for longer can't be continued.
+func WithAdaptiveRetention(d time.Duration) InMemoryOption {
+	return func(s *memoryStore) {
+		s.adaptiveRetention = d
+	}
 }
 
 // QuestionID uniquely identifies a question in the store.
@@ -34,11 +117,106 @@ type Score = int
 // Stat represents a percentile score comparing against other submissions.
 type Stat = int
 
+// Attempt records a submission of a user.
+type Attempt struct {
+	User string
+	// Quiz names the quiz taken, as there are several ways to take it, such as
+	// the whole questionnaire or an adaptive quiz.
+	Quiz string
+	// Cohort is the group the user was in when taking the quiz, such as a
+	// team or an onboarding batch. Empty if none.
+	Cohort string
+	// DisplayName is the name the user is shown with on the leaderboard, empty
+	// to be left out of it.
+	DisplayName string
+	// Addr is the address the attempt was submitted from, if known, to limit
+	// the attempts per address.
+	Addr  string
+	Score Score
+	// Total is the number of questions asked.
+	Total       int
+	SubmittedAt time.Time
+	// Answers are the options chosen, to analyse how every question performs.
+	Answers map[QuestionID]OptionID
+}
+
+// AttemptLimit caps the attempts of a quiz submitted at or after Since from
+// Addr. An empty Addr isn't limited, and a zero Max means no limit.
+type AttemptLimit struct {
+	Max   int
+	Since time.Time
+	Addr  string
+}
+
+// Submission is the result of a submission, kept by its idempotency key so
+// that retries get the same result.
+type Submission struct {
+	Key    string
+	Caller string
+	// Quiz is the quiz submitted, to publish the solutions of an event when
+	// it closes.
+	Quiz string
+	// AnswersHash identifies the answers, so a key reused for different
+	// answers can be told apart from a retry.
+	AnswersHash string
+	Correct     Score
+	Stat        Stat
+	// Cohort is the cohort of the caller, if any, and CohortStat the rank
+	// within it.
+	Cohort      string
+	CohortStat  Stat
+	SubmittedAt time.Time
+}
+
+// Progress holds the answers of a user's quiz in progress.
+type Progress struct {
+	User      string
+	Answers   map[QuestionID]OptionID
+	UpdatedAt time.Time
+}
+
+// AdaptiveAttempt is an adaptive quiz, where every question is chosen after
+// the answer to the previous one.
+type AdaptiveAttempt struct {
+	ID   string
+	User string
+	// MaxQuestions is the number of questions asked before the quiz is over.
+	MaxQuestions int
+	// Answers are in the order the questions were asked.
+	Answers []AdaptiveAnswer
+	// Current is the question to answer next, zero once the quiz is over.
+	Current   QuestionID
+	StartedAt time.Time
+	UpdatedAt time.Time
+	// Version is the number of times the attempt was saved, zero for a new
+	// one, so concurrent updates don't overwrite each other.
+	Version int
+}
+
+// AdaptiveAnswer is the answer to a question of an adaptive quiz.
+type AdaptiveAnswer struct {
+	QuestionID QuestionID
+	OptionID   OptionID
+	Correct    bool
+}
+
+// Difficulty tells how hard a question is, from MinDifficulty to MaxDifficulty.
+// Zero means it is not known, and counts as MediumDifficulty.
+type Difficulty int
+
+const (
+	MinDifficulty    Difficulty = 1
+	MediumDifficulty Difficulty = 3
+	MaxDifficulty    Difficulty = 5
+)
+
 // Question represents a multiple choice question with its available options.
 type Question struct {
-	ID      QuestionID
-	Text    string
-	Options map[OptionID]Option
+	ID          QuestionID
+	Text        string
+	Explanation string
+	Difficulty  Difficulty
+	Options     map[OptionID]Option
 }
 
 // Option represents a single answer choice for a question.
@@ -70,55 +248,332 @@ type InitialData struct {
 }
 
 // StoreError indicates an expected error condition in the store operations,
-// as opposed to unexpected errors that would indicate bugs.
+// as opposed to unexpected errors that would indicate bugs. A canceled or
+// expired context is an expected condition too.
 type StoreError struct {
 	error
 }
 
+// Unwrap gives access to the cause, e.g. context.Canceled.
+func (e StoreError) Unwrap() error {
+	return e.error
+}
+
+func (e StoreError) Error() string {
+	if e.error == nil {
+		return "store error"
+	}
+	return e.error.Error()
+}
+
 // NewInMemory initiates an implementation of the Store interface
 // with the given data.
-func NewInMemory(data InitialData) (Store, error) {
+func NewInMemory(data InitialData, opts ...InMemoryOption) (Store, error) {
 	if data.Questions == nil || data.Solutions == nil {
 		return nil, StoreError{errors.New("questions and solutions maps cannot be nil")}
 	}
-	return &memoryStore{
-		questions: data.Questions,
-		solutions: data.Solutions,
-		scores:    make([]Score, 0),
-		mu:        sync.RWMutex{},
-	}, nil
+	s := &memoryStore{
+		questions:         data.Questions,
+		solutions:         data.Solutions,
+		byUser:            make(map[string][]int),
+		byAddr:            make(map[string][]int),
+		scores:            make(map[scoresKey][]Score),
+		submissions:       make(map[string]Submission),
+		progress:          make(map[string]Progress),
+		adaptive:          make(map[string]AdaptiveAttempt),
+		keyRetention:      DefaultKeyRetention,
+		adaptiveRetention: DefaultAdaptiveRetention,
+		mu:                sync.RWMutex{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
 }
 
 // GetQuestions returns all available questions from the store.
-func (s *memoryStore) Questions() (map[QuestionID]Question, error) {
+func (s *memoryStore) Questions(ctx context.Context) (map[QuestionID]Question, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, StoreError{err}
+	}
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	return s.questions, nil
 }
 
 // GetSolutions returns the correct answers for all questions from the store.
-func (s *memoryStore) Solutions() (map[QuestionID]OptionID, error) {
+func (s *memoryStore) Solutions(ctx context.Context) (map[QuestionID]OptionID, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, StoreError{err}
+	}
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	return s.solutions, nil
 }
 
-// SaveScore stores a new score in the store. Returns an error if the score is negative.
-func (s *memoryStore) SaveScore(score Score) error {
-	if score < 0 {
-		return StoreError{fmt.Errorf("score cannot be negative: %d", score)}
+// SaveAttempt records a submission of a user. Returns an error if the score is negative.
+func (s *memoryStore) SaveAttempt(ctx context.Context, attempt Attempt) error {
+	if err := ctx.Err(); err != nil {
+		return StoreError{err}
+	}
+	if err := validateAttempt(attempt); err != nil {
+		return err
 	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.scores = append(s.scores, score)
+	s.saveAttempt(attempt)
 	return nil
 }
 
-// GetAllScores returns a copy of all stored scores.
-func (s *memoryStore) AllScores() ([]Score, error) {
+func validateAttempt(attempt Attempt) error {
+	if attempt.User == "" {
+		return StoreError{errors.New("attempt user cannot be empty")}
+	}
+	if attempt.Score < 0 {
+		return StoreError{fmt.Errorf("score cannot be negative: %d", attempt.Score)}
+	}
+	return nil
+}
+
+// saveAttempt records an attempt. The caller must hold the lock.
+func (s *memoryStore) saveAttempt(attempt Attempt) {
+	attempt.Answers = maps.Clone(attempt.Answers)
+	s.byUser[attempt.User] = append(s.byUser[attempt.User], len(s.attempts))
+	if attempt.Addr != "" {
+		s.byAddr[attempt.Addr] = append(s.byAddr[attempt.Addr], len(s.attempts))
+	}
+	s.attempts = append(s.attempts, attempt)
+	all := scoresKey{quiz: attempt.Quiz}
+	s.scores[all] = append(s.scores[all], attempt.Score)
+	if attempt.Cohort != "" {
+		inCohort := scoresKey{quiz: attempt.Quiz, cohort: attempt.Cohort}
+		s.scores[inCohort] = append(s.scores[inCohort], attempt.Score)
+	}
+}
+
+// Scores returns a copy of the scores of the attempts of a quiz in a cohort,
+// or of all of them if the cohort is empty, in the order they were saved.
+func (s *memoryStore) Scores(ctx context.Context, quiz, cohort string) ([]Score, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, StoreError{err}
+	}
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	scoresCopy := make([]Score, len(s.scores))
-	copy(scoresCopy, s.scores)
-	return scoresCopy, nil
+	return slices.Clone(s.scores[scoresKey{quiz: quiz, cohort: cohort}]), nil
+}
+
+// Attempts returns the attempts of a user submitted at or after since.
+func (s *memoryStore) Attempts(ctx context.Context, user string, since time.Time) ([]Attempt, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, StoreError{err}
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var attempts []Attempt
+	for _, i := range s.byUser[user] {
+		if a := s.attempts[i]; !a.SubmittedAt.Before(since) {
+			a.Answers = maps.Clone(a.Answers)
+			attempts = append(attempts, a)
+		}
+	}
+	return attempts, nil
+}
+
+// AllAttempts returns a copy of the attempts of all the users, in the order
+// they were saved.
+func (s *memoryStore) AllAttempts(ctx context.Context) ([]Attempt, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, StoreError{err}
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	attempts := make([]Attempt, len(s.attempts))
+	for i, a := range s.attempts {
+		a.Answers = maps.Clone(a.Answers)
+		attempts[i] = a
+	}
+	return attempts, nil
+}
+
+// SaveSubmission records the attempt of a submission and, if the submission
+// has an idempotency key, keeps it by the key, both at once. Returns an error
+// wrapping ErrSubmissionExists if the key is taken and still retained, or
+// ErrAttemptLimit if the attempt is over the limit, and then saves neither.
+func (s *memoryStore) SaveSubmission(ctx context.Context, submission Submission, attempt Attempt, limit AttemptLimit) error {
+	if err := ctx.Err(); err != nil {
+		return StoreError{err}
+	}
+	if err := validateAttempt(attempt); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if submission.Key != "" {
+		if sub, ok := s.submissions[submission.Key]; ok && !s.expired(sub) {
+			return StoreError{fmt.Errorf("%w: %s", ErrSubmissionExists, submission.Key)}
+		}
+	}
+	if limit.Max > 0 && limit.Addr != "" && s.countAttempts(s.byAddr[limit.Addr], attempt.Quiz, limit.Since) >= limit.Max {
+		return StoreError{fmt.Errorf("%w: %d attempts from %s", ErrAttemptLimit, limit.Max, limit.Addr)}
+	}
+	if submission.Key != "" {
+		s.submissions[submission.Key] = submission
+	}
+	s.saveAttempt(attempt)
+	return nil
+}
+
+// countAttempts counts the attempts of a quiz submitted at or after since
+// among those at the indexes. The caller must hold the lock.
+func (s *memoryStore) countAttempts(indexes []int, quiz string, since time.Time) int {
+	n := 0
+	for _, i := range indexes {
+		if a := s.attempts[i]; a.Quiz == quiz && !a.SubmittedAt.Before(since) {
+			n++
+		}
+	}
+	return n
+}
+
+// Submission returns the submission with the given idempotency key. Returns an
+// error wrapping ErrSubmissionNotFound if there is none within the retention window.
+func (s *memoryStore) Submission(ctx context.Context, key string) (Submission, error) {
+	if err := ctx.Err(); err != nil {
+		return Submission{}, StoreError{err}
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sub, ok := s.submissions[key]
+	if !ok || s.expired(sub) {
+		return Submission{}, StoreError{fmt.Errorf("%w: %s", ErrSubmissionNotFound, key)}
+	}
+	return sub, nil
+}
+
+// DeleteExpired forgets the submissions and the adaptive attempts past their
+// retention windows, so they don't pile up. It is meant to be called
+// periodically.
+func (s *memoryStore) DeleteExpired(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return StoreError{err}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, sub := range s.submissions {
+		if s.expired(sub) {
+			delete(s.submissions, key)
+		}
+	}
+	for id, attempt := range s.adaptive {
+		if s.adaptiveExpired(attempt) {
+			delete(s.adaptive, id)
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) expired(sub Submission) bool {
+	return time.Since(sub.SubmittedAt) > s.keyRetention
+}
+
+// SaveProgress replaces the progress of a user.
+func (s *memoryStore) SaveProgress(ctx context.Context, progress Progress) error {
+	if err := ctx.Err(); err != nil {
+		return StoreError{err}
+	}
+	if progress.User == "" {
+		return StoreError{errors.New("progress user cannot be empty")}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.progress[progress.User] = Progress{
+		User:      progress.User,
+		Answers:   maps.Clone(progress.Answers),
+		UpdatedAt: progress.UpdatedAt,
+	}
+	return nil
+}
+
+// Progress returns a copy of the progress of a user. Returns an error wrapping
+// ErrProgressNotFound if the user has no quiz in progress.
+func (s *memoryStore) Progress(ctx context.Context, user string) (Progress, error) {
+	if err := ctx.Err(); err != nil {
+		return Progress{}, StoreError{err}
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	progress, ok := s.progress[user]
+	if !ok {
+		return Progress{}, StoreError{fmt.Errorf("%w: %s", ErrProgressNotFound, user)}
+	}
+	progress.Answers = maps.Clone(progress.Answers)
+	return progress, nil
+}
+
+// DeleteProgress forgets the progress of a user, if any.
+func (s *memoryStore) DeleteProgress(ctx context.Context, user string) error {
+	if err := ctx.Err(); err != nil {
+		return StoreError{err}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.progress, user)
+	return nil
+}
+
+// SaveAdaptiveAttempt saves an adaptive attempt as of the version it was read
+// at, zero for a new attempt, and bumps its version. Returns an error wrapping
+// ErrAdaptiveAttemptConflict, and saves nothing, if it was saved since.
+func (s *memoryStore) SaveAdaptiveAttempt(ctx context.Context, attempt AdaptiveAttempt) error {
+	if err := ctx.Err(); err != nil {
+		return StoreError{err}
+	}
+	if attempt.ID == "" {
+		return StoreError{errors.New("adaptive attempt ID cannot be empty")}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// An expired attempt is gone, even if the janitor didn't delete it yet.
+	saved, ok := s.adaptive[attempt.ID]
+	if ok && s.adaptiveExpired(saved) {
+		saved = AdaptiveAttempt{}
+	}
+	if saved.Version != attempt.Version {
+		return StoreError{fmt.Errorf("%w: %s", ErrAdaptiveAttemptConflict, attempt.ID)}
+	}
+	attempt.Answers = slices.Clone(attempt.Answers)
+	attempt.Version++
+	s.adaptive[attempt.ID] = attempt
+	return nil
+}
+
+// AdaptiveAttempt returns a copy of the adaptive attempt with the given ID.
+// Returns an error wrapping ErrAdaptiveAttemptNotFound if there is none within
+// the retention window.
+func (s *memoryStore) AdaptiveAttempt(ctx context.Context, id string) (AdaptiveAttempt, error) {
+	if err := ctx.Err(); err != nil {
+		return AdaptiveAttempt{}, StoreError{err}
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	attempt, ok := s.adaptive[id]
+	if !ok || s.adaptiveExpired(attempt) {
+		return AdaptiveAttempt{}, StoreError{fmt.Errorf("%w: %s", ErrAdaptiveAttemptNotFound, id)}
+	}
+	attempt.Answers = slices.Clone(attempt.Answers)
+	return attempt, nil
+}
+
+func (s *memoryStore) adaptiveExpired(attempt AdaptiveAttempt) bool {
+	return time.Since(attempt.UpdatedAt) > s.adaptiveRetention
+}
+
+// Ping reports whether the store is able to serve requests. The in-memory
+// store is always available.
+func (s *memoryStore) Ping(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return StoreError{err}
+	}
+	return nil
 }
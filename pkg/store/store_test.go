@@ -1,7 +1,12 @@
 package store_test
 
 import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/mateopresacastro/qstnnr/pkg/store"
 )
@@ -60,9 +65,10 @@ func TestStore(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	ctx := context.Background()
 
 	t.Run("should get questions", func(t *testing.T) {
-		qs, err := s.Questions()
+		qs, err := s.Questions(ctx)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -75,7 +81,7 @@ func TestStore(t *testing.T) {
 	})
 
 	t.Run("should get solutions", func(t *testing.T) {
-		sols, err := s.Solutions()
+		sols, err := s.Solutions(ctx)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -91,13 +97,16 @@ func TestStore(t *testing.T) {
 		// Valid scores
 		scores := []store.Score{2, 3, 1}
 		for _, score := range scores {
-			if err := s.SaveScore(score); err != nil {
+			if err := s.SaveAttempt(ctx, store.Attempt{User: "scorer", Quiz: "scores", Score: score}); err != nil {
 				t.Fatalf("failed to save score %d: %v", score, err)
 			}
 		}
+		if err := s.SaveAttempt(ctx, store.Attempt{User: "scorer", Quiz: "other", Score: 4}); err != nil {
+			t.Fatal(err)
+		}
 
 		// Get scores
-		savedScores, err := s.AllScores()
+		savedScores, err := s.Scores(ctx, "scores", "")
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -112,22 +121,44 @@ func TestStore(t *testing.T) {
 		}
 	})
 
+	t.Run("should get the scores of a cohort", func(t *testing.T) {
+		for i, cohort := range []string{"gophers", "", "gophers", "crabs"} {
+			if err := s.SaveAttempt(ctx, store.Attempt{User: "scorer", Quiz: "cohorts", Cohort: cohort, Score: i}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		gophers, err := s.Scores(ctx, "cohorts", "gophers")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(gophers, []store.Score{0, 2}) {
+			t.Errorf("expected the scores 0 and 2 in gophers, got %v", gophers)
+		}
+		all, err := s.Scores(ctx, "cohorts", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(all, []store.Score{0, 1, 2, 3}) {
+			t.Errorf("expected all the scores, got %v", all)
+		}
+	})
+
 	t.Run("should not save negative scores", func(t *testing.T) {
-		err := s.SaveScore(-1)
+		err := s.SaveAttempt(ctx, store.Attempt{User: "scorer", Score: -1})
 		if err == nil {
 			t.Fatal("expected error when saving negative score")
 		}
 	})
 
 	t.Run("error should be of correct type", func(t *testing.T) {
-		err := s.SaveScore(-1)
+		err := s.SaveAttempt(ctx, store.Attempt{User: "scorer", Score: -1})
 		if _, ok := err.(store.StoreError); !ok {
 			t.Fatal("error is not of correct type")
 		}
 	})
 
 	t.Run("should return copy of scores", func(t *testing.T) {
-		scores1, err := s.AllScores()
+		scores1, err := s.Scores(ctx, "scores", "")
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -136,7 +167,7 @@ func TestStore(t *testing.T) {
 		scores1[0] = 999
 
 		// Get scores again
-		scores2, err := s.AllScores()
+		scores2, err := s.Scores(ctx, "scores", "")
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -146,4 +177,308 @@ func TestStore(t *testing.T) {
 			t.Fatal("modification of returned scores affected the store")
 		}
 	})
+
+	t.Run("should save and get attempts by user", func(t *testing.T) {
+		now := time.Now()
+		attempts := []store.Attempt{
+			{User: "gopher", Score: 1, SubmittedAt: now.Add(-48 * time.Hour)},
+			{User: "gopher", Score: 2, SubmittedAt: now},
+			{User: "gordon", Score: 3, SubmittedAt: now},
+		}
+		for _, a := range attempts {
+			if err := s.SaveAttempt(ctx, a); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		got, err := s.Attempts(ctx, "gopher", now.Add(-24*time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 1 || got[0].Score != 2 {
+			t.Fatalf("expected the last attempt of gopher, got %v", got)
+		}
+
+		all, err := s.AllAttempts(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// After the attempts saved for the scores.
+		if len(all) != 11 || all[8].User != "gopher" || all[10].User != "gordon" {
+			t.Fatalf("expected the 3 attempts in order, got %v", all)
+		}
+	})
+
+	t.Run("should not save attempts without user", func(t *testing.T) {
+		err := s.SaveAttempt(ctx, store.Attempt{Score: 1, SubmittedAt: time.Now()})
+		if _, ok := err.(store.StoreError); !ok {
+			t.Fatalf("expected StoreError, got %v", err)
+		}
+	})
+
+	t.Run("should save and get submissions by key", func(t *testing.T) {
+		sub := store.Submission{Key: "key-1", Caller: "gopher", Correct: 2, Stat: 50, SubmittedAt: time.Now()}
+		attempt := store.Attempt{User: "gopher", Quiz: "submissions", Score: 2, SubmittedAt: sub.SubmittedAt}
+		if err := s.SaveSubmission(ctx, sub, attempt, store.AttemptLimit{}); err != nil {
+			t.Fatal(err)
+		}
+		got, err := s.Submission(ctx, "key-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != sub {
+			t.Errorf("expected %v, got %v", sub, got)
+		}
+
+		err = s.SaveSubmission(ctx, sub, attempt, store.AttemptLimit{})
+		if _, ok := err.(store.StoreError); !ok || !errors.Is(err, store.ErrSubmissionExists) {
+			t.Errorf("expected ErrSubmissionExists, got %v", err)
+		}
+		// The attempt is saved with the key, and not again with a taken key.
+		scores, err := s.Scores(ctx, "submissions", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(scores) != 1 {
+			t.Errorf("expected the attempt to be saved once, got %d scores", len(scores))
+		}
+
+		// Submissions without a key only save the attempt.
+		if err := s.SaveSubmission(ctx, store.Submission{}, attempt, store.AttemptLimit{}); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.SaveSubmission(ctx, store.Submission{Key: "key-3"}, store.Attempt{Score: 1}, store.AttemptLimit{}); err == nil {
+			t.Error("expected an error saving an attempt without user")
+		}
+		if _, err := s.Submission(ctx, "key-3"); !errors.Is(err, store.ErrSubmissionNotFound) {
+			t.Errorf("expected the key of an invalid attempt not to be saved, got %v", err)
+		}
+
+		_, err = s.Submission(ctx, "key-2")
+		if _, ok := err.(store.StoreError); !ok || !errors.Is(err, store.ErrSubmissionNotFound) {
+			t.Errorf("expected ErrSubmissionNotFound, got %v", err)
+		}
+	})
+
+	t.Run("should forget submissions after the retention window", func(t *testing.T) {
+		s, err := store.NewInMemory(store.InitialData{
+			Questions: questions,
+			Solutions: solutions,
+		}, store.WithKeyRetention(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		attempt := store.Attempt{User: "gopher", SubmittedAt: time.Now()}
+		old := store.Submission{Key: "key-1", SubmittedAt: time.Now().Add(-2 * time.Hour)}
+		if err := s.SaveSubmission(ctx, old, attempt, store.AttemptLimit{}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.Submission(ctx, "key-1"); !errors.Is(err, store.ErrSubmissionNotFound) {
+			t.Fatalf("expected ErrSubmissionNotFound, got %v", err)
+		}
+		// The expired key can be used again.
+		if err := s.SaveSubmission(ctx, store.Submission{Key: "key-1", SubmittedAt: time.Now()}, attempt, store.AttemptLimit{}); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := s.SaveSubmission(ctx, store.Submission{Key: "key-2", SubmittedAt: time.Now().Add(-2 * time.Hour)}, attempt, store.AttemptLimit{}); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.DeleteExpired(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.Submission(ctx, "key-1"); err != nil {
+			t.Errorf("expected the retained key to be kept, got %v", err)
+		}
+		if _, err := s.Submission(ctx, "key-2"); !errors.Is(err, store.ErrSubmissionNotFound) {
+			t.Errorf("expected ErrSubmissionNotFound, got %v", err)
+		}
+	})
+
+	t.Run("should limit the attempts by address", func(t *testing.T) {
+		s, err := store.NewInMemory(store.InitialData{Questions: questions, Solutions: solutions})
+		if err != nil {
+			t.Fatal(err)
+		}
+		now := time.Now()
+		save := func(user, addr string, limit store.AttemptLimit) error {
+			attempt := store.Attempt{User: user, Quiz: "limited", Addr: addr, SubmittedAt: now}
+			return s.SaveSubmission(ctx, store.Submission{}, attempt, limit)
+		}
+		limit := func(addr string) store.AttemptLimit {
+			return store.AttemptLimit{Max: 2, Since: now.Add(-time.Hour), Addr: addr}
+		}
+
+		// Attempts before the window or of other quizzes don't count.
+		old := store.Attempt{User: "gopher", Quiz: "limited", Addr: "192.0.2.1", SubmittedAt: now.Add(-2 * time.Hour)}
+		if err := s.SaveAttempt(ctx, old); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.SaveAttempt(ctx, store.Attempt{User: "gopher", Quiz: "other", Addr: "192.0.2.1", SubmittedAt: now}); err != nil {
+			t.Fatal(err)
+		}
+		for range 2 {
+			if err := save("gopher", "192.0.2.1", limit("192.0.2.1")); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := save("gordon", "192.0.2.1", limit("192.0.2.1")); !errors.Is(err, store.ErrAttemptLimit) {
+			t.Errorf("expected ErrAttemptLimit for another user from the address, got %v", err)
+		}
+		if err := save("gopher", "192.0.2.2", limit("192.0.2.2")); err != nil {
+			t.Errorf("expected the attempts of another address to be allowed, got %v", err)
+		}
+		if err := save("gopher", "192.0.2.1", store.AttemptLimit{}); err != nil {
+			t.Errorf("expected attempts without a limit to be allowed, got %v", err)
+		}
+	})
+
+	t.Run("should not save concurrent attempts over the limit", func(t *testing.T) {
+		s, err := store.NewInMemory(store.InitialData{Questions: questions, Solutions: solutions})
+		if err != nil {
+			t.Fatal(err)
+		}
+		limit := store.AttemptLimit{Max: 3, Addr: "192.0.2.1"}
+		var wg sync.WaitGroup
+		for range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				attempt := store.Attempt{User: "gopher", Addr: "192.0.2.1", SubmittedAt: time.Now()}
+				s.SaveSubmission(ctx, store.Submission{}, attempt, limit)
+			}()
+		}
+		wg.Wait()
+		attempts, err := s.Attempts(ctx, "gopher", time.Time{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(attempts) != 3 {
+			t.Errorf("expected 3 attempts, got %d", len(attempts))
+		}
+	})
+
+	t.Run("should save, get and delete progress by user", func(t *testing.T) {
+		answers := map[store.QuestionID]store.OptionID{1: 2}
+		progress := store.Progress{User: "gopher", Answers: answers, UpdatedAt: time.Now()}
+		if err := s.SaveProgress(ctx, progress); err != nil {
+			t.Fatal(err)
+		}
+		// The store keeps its own copy of the answers.
+		answers[2] = 2
+
+		got, err := s.Progress(ctx, "gopher")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got.Answers) != 1 || got.Answers[1] != 2 {
+			t.Errorf("expected the saved answers, got %v", got.Answers)
+		}
+
+		if err := s.DeleteProgress(ctx, "gopher"); err != nil {
+			t.Fatal(err)
+		}
+		_, err = s.Progress(ctx, "gopher")
+		if _, ok := err.(store.StoreError); !ok || !errors.Is(err, store.ErrProgressNotFound) {
+			t.Errorf("expected ErrProgressNotFound, got %v", err)
+		}
+	})
+
+	t.Run("should save and get adaptive attempts by ID", func(t *testing.T) {
+		attempt := store.AdaptiveAttempt{
+			ID:           "attempt-1",
+			User:         "gopher",
+			MaxQuestions: 2,
+			Answers:      []store.AdaptiveAnswer{{QuestionID: 1, OptionID: 2, Correct: true}},
+			Current:      2,
+			UpdatedAt:    time.Now(),
+		}
+		if err := s.SaveAdaptiveAttempt(ctx, attempt); err != nil {
+			t.Fatal(err)
+		}
+		// The store keeps its own copy of the answers.
+		attempt.Answers[0].Correct = false
+
+		got, err := s.AdaptiveAttempt(ctx, "attempt-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got.Answers) != 1 || !got.Answers[0].Correct || got.Current != 2 {
+			t.Errorf("expected the saved attempt, got %+v", got)
+		}
+
+		_, err = s.AdaptiveAttempt(ctx, "attempt-2")
+		if _, ok := err.(store.StoreError); !ok || !errors.Is(err, store.ErrAdaptiveAttemptNotFound) {
+			t.Errorf("expected ErrAdaptiveAttemptNotFound, got %v", err)
+		}
+	})
+
+	t.Run("should only save adaptive attempts as of their saved version", func(t *testing.T) {
+		got, err := s.AdaptiveAttempt(ctx, "attempt-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Version != 1 {
+			t.Fatalf("expected version 1, got %d", got.Version)
+		}
+		stale := got
+		got.Current = 0
+		if err := s.SaveAdaptiveAttempt(ctx, got); err != nil {
+			t.Fatal(err)
+		}
+		err = s.SaveAdaptiveAttempt(ctx, stale)
+		if _, ok := err.(store.StoreError); !ok || !errors.Is(err, store.ErrAdaptiveAttemptConflict) {
+			t.Errorf("expected ErrAdaptiveAttemptConflict, got %v", err)
+		}
+		// A new attempt can't replace one with the same ID either.
+		if err := s.SaveAdaptiveAttempt(ctx, store.AdaptiveAttempt{ID: "attempt-1", UpdatedAt: time.Now()}); !errors.Is(err, store.ErrAdaptiveAttemptConflict) {
+			t.Errorf("expected ErrAdaptiveAttemptConflict, got %v", err)
+		}
+		if got, err := s.AdaptiveAttempt(ctx, "attempt-1"); err != nil || got.Version != 2 || got.Current != 0 {
+			t.Errorf("expected the second version, got %+v, %v", got, err)
+		}
+	})
+
+	t.Run("should forget adaptive attempts after the retention window", func(t *testing.T) {
+		s, err := store.NewInMemory(store.InitialData{
+			Questions: questions,
+			Solutions: solutions,
+		}, store.WithAdaptiveRetention(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for id, updatedAt := range map[string]time.Time{"idle": time.Now().Add(-2 * time.Hour), "active": time.Now()} {
+			if err := s.SaveAdaptiveAttempt(ctx, store.AdaptiveAttempt{ID: id, UpdatedAt: updatedAt}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := s.DeleteExpired(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.AdaptiveAttempt(ctx, "idle"); !errors.Is(err, store.ErrAdaptiveAttemptNotFound) {
+			t.Errorf("expected ErrAdaptiveAttemptNotFound, got %v", err)
+		}
+		if _, err := s.AdaptiveAttempt(ctx, "active"); err != nil {
+			t.Errorf("expected the active attempt to be kept, got %v", err)
+		}
+	})
+
+	t.Run("should respect canceled contexts", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := s.Questions(ctx)
+		if _, ok := err.(store.StoreError); !ok {
+			t.Fatalf("expected StoreError, got %v", err)
+		}
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+		if _, err := s.Scores(ctx, "scores", ""); !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled getting the scores, got %v", err)
+		}
+		if err := s.Ping(ctx); !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled on ping, got %v", err)
+		}
+	})
 }
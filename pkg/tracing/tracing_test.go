@@ -0,0 +1,141 @@
+package tracing_test
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mateopresacastro/qstnnr/pkg/tracing"
+	"go.opentelemetry.io/otel"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc"
+)
+
+func TestTracing(t *testing.T) {
+	ctx := context.Background()
+	// Setup installs a global tracer provider and propagator, restore them for the other tests.
+	provider, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(provider)
+		otel.SetTextMapPropagator(propagator)
+	})
+
+	t.Run("should export spans to stdout", func(t *testing.T) {
+		var buf bytes.Buffer
+		shutdown, err := tracing.Setup(ctx, tracing.Config{
+			ServiceName: "test",
+			Exporter:    tracing.ExporterStdout,
+			Output:      &buf,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, span := otel.Tracer("test").Start(ctx, "stdout-span")
+		span.End()
+
+		if err := shutdown(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buf.String(), `"Name":"stdout-span"`) {
+			t.Fatalf("expected the span to be written, got: %s", buf.String())
+		}
+	})
+
+	t.Run("should export spans to an OTLP collector", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		collector := &collector{}
+		srv := grpc.NewServer()
+		coltracepb.RegisterTraceServiceServer(srv, collector)
+		go srv.Serve(ln)
+		defer srv.Stop()
+
+		shutdown, err := tracing.Setup(ctx, tracing.Config{
+			ServiceName: "test",
+			Version:     "v1.2.3",
+			Exporter:    tracing.ExporterOTLP,
+			Endpoint:    "http://" + ln.Addr().String(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, span := otel.Tracer("test").Start(ctx, "otlp-span")
+		span.End()
+
+		if err := shutdown(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		names, service := collector.received()
+		if len(names) != 1 || names[0] != "otlp-span" {
+			t.Fatalf("expected the collector to receive otlp-span, got %v", names)
+		}
+		if service != "test" {
+			t.Errorf("expected service name test, got %q", service)
+		}
+	})
+
+	t.Run("should install the trace context propagator", func(t *testing.T) {
+		shutdown, err := tracing.Setup(ctx, tracing.Config{Exporter: tracing.ExporterNone})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer shutdown(ctx)
+
+		fields := otel.GetTextMapPropagator().Fields()
+		found := false
+		for _, f := range fields {
+			if f == "traceparent" {
+				found = true
+			}
+		}
+		if !found {
+			t.Fatalf("expected traceparent to be propagated, got %v", fields)
+		}
+	})
+
+	t.Run("should fail with an unknown exporter", func(t *testing.T) {
+		if _, err := tracing.Setup(ctx, tracing.Config{Exporter: "zipkin"}); err == nil {
+			t.Fatal("expected error with an unknown exporter")
+		}
+	})
+}
+
+// collector is a stand-in for an OTLP collector that keeps the received spans.
+type collector struct {
+	coltracepb.UnimplementedTraceServiceServer
+	mu      sync.Mutex
+	names   []string
+	service string
+}
+
+func (c *collector) Export(ctx context.Context, req *coltracepb.ExportTraceServiceRequest) (*coltracepb.ExportTraceServiceResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, rs := range req.ResourceSpans {
+		for _, attr := range rs.Resource.Attributes {
+			if attr.Key == "service.name" {
+				c.service = attr.Value.GetStringValue()
+			}
+		}
+		for _, ss := range rs.ScopeSpans {
+			for _, span := range ss.Spans {
+				c.names = append(c.names, span.Name)
+			}
+		}
+	}
+	return &coltracepb.ExportTraceServiceResponse{}, nil
+}
+
+func (c *collector) received() ([]string, string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.names, c.service
+}
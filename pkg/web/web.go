@@ -0,0 +1,363 @@
+package web
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+
+	"github.com/mateopresacastro/qstnnr/pkg/bugs"
+	"github.com/mateopresacastro/qstnnr/pkg/qerr"
+	"github.com/mateopresacastro/qstnnr/pkg/qservice"
+	"github.com/mateopresacastro/qstnnr/pkg/ratelimit"
+	"github.com/mateopresacastro/qstnnr/pkg/reqctx"
+	"github.com/mateopresacastro/qstnnr/pkg/store"
+)
+
+//go:embed templates
+var templatesFS embed.FS
+
+// pages lists the templates that are rendered inside the shared layout.
+var pages = []string{"index", "quiz", "results", "error"}
+
+// handler serves the browser based quiz UI.
+type handler struct {
+	service   qservice.QService
+	logger    *slog.Logger
+	bugs      bugs.Reporter
+	limiter   *ratelimit.Limiter
+	templates map[string]*template.Template
+}
+
+// Config holds the configuration for the web UI.
+type Config struct {
+	Logger  *slog.Logger
+	Service qservice.QService
+	// BugReporter receives the unexpected errors. Defaults to logging them.
+	BugReporter bugs.Reporter
+	// RateLimiter, if set, limits the pages as the RPCs they call, sharing
+	// the buckets of the gRPC server so neither can be used to get around it.
+	RateLimiter *ratelimit.Limiter
+}
+
+// New creates an http.Handler serving the web UI with the given configuration.
+func New(cfg *Config) (http.Handler, error) {
+	h := &handler{
+		service:   cfg.Service,
+		logger:    cfg.Logger,
+		bugs:      cfg.BugReporter,
+		limiter:   cfg.RateLimiter,
+		templates: make(map[string]*template.Template),
+	}
+	if h.bugs == nil {
+		h.bugs = bugs.NewLogReporter(cfg.Logger)
+	}
+
+	funcs := template.FuncMap{"inc": func(i int) int { return i + 1 }}
+	layout, err := template.New("layout.html").Funcs(funcs).ParseFS(templatesFS, "templates/layout.html")
+	if err != nil {
+		return nil, fmt.Errorf("parsing layout: %w", err)
+	}
+	for _, page := range pages {
+		tmpl, err := template.Must(layout.Clone()).ParseFS(templatesFS, "templates/"+page+".html")
+		if err != nil {
+			return nil, fmt.Errorf("parsing template %s: %w", page, err)
+		}
+		h.templates[page] = tmpl
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", h.limit("GetQuestions", h.index))
+	mux.HandleFunc("GET /quiz", h.limit("GetQuestions", h.quiz))
+	mux.HandleFunc("POST /quiz", h.sameOrigin(h.limit("SubmitAnswers", h.submit)))
+	return withRequestScope(mux), nil
+}
+
+// withRequestScope makes the request ID and the client address available to
+// the handlers and the service. The request ID is taken from the X-Request-Id
+// header if the client sent one.
+func withRequestScope(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(reqctx.RequestIDKey)
+		if id == "" {
+			id = reqctx.NewID()
+		}
+		w.Header().Set(reqctx.RequestIDKey, id)
+		addr := r.RemoteAddr
+		if host, _, err := net.SplitHostPort(addr); err == nil {
+			addr = host
+		}
+		ctx := reqctx.WithAddr(reqctx.WithRequestID(r.Context(), id), addr)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// limit rejects the requests over the rate limit of the RPC method, by client
+// address, with 429 Too Many Requests and a Retry-After header.
+func (h *handler) limit(method string, next http.HandlerFunc) http.HandlerFunc {
+	if h.limiter == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		delay := h.limiter.Allow(method, reqctx.Anonymous, reqctx.Addr(r.Context()))
+		if delay > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+			h.renderError(w, r, http.StatusTooManyRequests, "too many requests, try again later")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// sameOrigin rejects the requests sent by the pages of other sites with 403
+// Forbidden, so they can't submit answers from the browsers of their visitors
+// and use up the attempts and the rate limits of their address. Browsers tell
+// where a request comes from with Sec-Fetch-Site or, before it, Origin.
+// Requests with neither don't come from a browser and are allowed.
+func (h *handler) sameOrigin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Header.Get("Sec-Fetch-Site") {
+		case "same-origin", "none":
+			next(w, r)
+			return
+		case "":
+		default:
+			h.renderError(w, r, http.StatusForbidden, "answers can only be submitted from this site")
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" {
+			if u, err := url.Parse(origin); err != nil || u.Host != r.Host {
+				h.renderError(w, r, http.StatusForbidden, "answers can only be submitted from this site")
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+// quizView is the data rendered by the index and quiz pages.
+type quizView struct {
+	Title     string
+	Questions []questionView
+	Error     string
+	// IdempotencyKey identifies the form, so submitting it twice scores it once.
+	IdempotencyKey string
+}
+
+type questionView struct {
+	ID      store.QuestionID
+	Text    string
+	Options []store.Option
+}
+
+// resultsView is the data rendered by the results page.
+type resultsView struct {
+	Title      string
+	Correct    int
+	Total      int
+	BetterThan int
+	Questions  []reviewView
+	// SolutionsWithheld hides the solutions of the submissions made during an
+	// event until SolutionsAt, when it closes, empty if it never does.
+	SolutionsWithheld bool
+	SolutionsAt       string
+}
+
+type reviewView struct {
+	Text          string
+	Chosen        string
+	CorrectAnswer string
+	Explanation   string
+	IsCorrect     bool
+}
+
+// quizTitle is the title of the questionnaire served by the store.
+const quizTitle = "Go Quiz"
+
+// index lists the quizzes the user can pick from.
+func (h *handler) index(w http.ResponseWriter, r *http.Request) {
+	qsts, err := h.service.Questions(r.Context())
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+	h.render(w, r, http.StatusOK, "index", quizView{Title: quizTitle, Questions: sortQuestions(qsts)})
+}
+
+// quiz renders the form with all the questions and their options.
+func (h *handler) quiz(w http.ResponseWriter, r *http.Request) {
+	qsts, err := h.service.Questions(r.Context())
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+	view := quizView{Title: quizTitle, Questions: sortQuestions(qsts), IdempotencyKey: reqctx.NewID()}
+	h.render(w, r, http.StatusOK, "quiz", view)
+}
+
+// submit evaluates the submitted form and renders the results.
+func (h *handler) submit(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		h.renderError(w, r, http.StatusBadRequest, "could not read the submitted answers")
+		return
+	}
+
+	qsts, err := h.service.Questions(r.Context())
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	answers := make(map[store.QuestionID]store.OptionID)
+	for qID := range qsts {
+		value := r.PostForm.Get(fieldName(qID))
+		if value == "" {
+			continue
+		}
+		oID, err := strconv.Atoi(value)
+		if err != nil {
+			h.renderError(w, r, http.StatusBadRequest, fmt.Sprintf("invalid option for question %d", qID))
+			return
+		}
+		answers[qID] = store.OptionID(oID)
+	}
+
+	key := r.PostForm.Get("idempotency_key")
+	result, err := h.service.SubmitAnswers(r.Context(), answers, key, "", "")
+	if err != nil {
+		var qErr qerr.QError
+		if errors.As(err, &qErr) && qErr.Code == qerr.InvalidInput {
+			// Let the user fix the answers instead of showing an error page.
+			view := quizView{Title: quizTitle, Questions: sortQuestions(qsts), Error: qErr.Message, IdempotencyKey: key}
+			h.render(w, r, http.StatusBadRequest, "quiz", view)
+			return
+		}
+		h.handleError(w, r, err)
+		return
+	}
+
+	view := resultsView{
+		Title:      quizTitle,
+		Correct:    result.Correct,
+		Total:      len(qsts),
+		BetterThan: result.Stat,
+	}
+	if result.SolutionsWithheld {
+		view.SolutionsWithheld = true
+		if !result.SolutionsAt.IsZero() {
+			view.SolutionsAt = result.SolutionsAt.UTC().Format("January 2, 2006 at 15:04 MST")
+		}
+		h.render(w, r, http.StatusOK, "results", view)
+		return
+	}
+	for _, q := range sortQuestions(qsts) {
+		correctID := result.Solutions[q.ID]
+		view.Questions = append(view.Questions, reviewView{
+			Text:          q.Text,
+			Chosen:        qsts[q.ID].Options[answers[q.ID]].Text,
+			CorrectAnswer: qsts[q.ID].Options[correctID].Text,
+			Explanation:   qsts[q.ID].Explanation,
+			IsCorrect:     answers[q.ID] == correctID,
+		})
+	}
+	h.render(w, r, http.StatusOK, "results", view)
+}
+
+// render executes the given page template. Rendering errors are bugs since
+// the templates are embedded in the binary.
+func (h *handler) render(w http.ResponseWriter, r *http.Request, status int, page string, data any) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := h.templates[page].ExecuteTemplate(w, "layout", data); err != nil {
+		h.reportBug(r, err)
+	}
+}
+
+func (h *handler) renderError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	h.render(w, r, status, "error", struct {
+		Title   string
+		Status  int
+		Message string
+	}{Title: http.StatusText(status), Status: status, Message: message})
+}
+
+// handleError maps domain level error codes to HTTP status codes and reports bugs.
+func (h *handler) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	// The browser went away, there is nobody to render the error for.
+	if r.Context().Err() != nil {
+		return
+	}
+
+	// If this is not a ServiceError we know is not a known edge case and is a real bug.
+	var serviceErr qservice.ServiceError
+	if !errors.As(err, &serviceErr) {
+		h.reportBug(r, err)
+		h.renderError(w, r, http.StatusInternalServerError, "an unexpected error occurred")
+		return
+	}
+
+	var qErr qerr.QError
+	if errors.As(serviceErr, &qErr) {
+		httpStatus, ok := errorCodeToHTTP[qErr.Code]
+		if !ok {
+			h.reportBug(r, fmt.Errorf("error mapping domain error code %d to HTTP status", qErr.Code))
+			h.renderError(w, r, http.StatusInternalServerError, "an unexpected error occurred")
+			return
+		}
+		h.renderError(w, r, httpStatus, qErr.Message)
+		return
+	}
+
+	h.renderError(w, r, http.StatusInternalServerError, serviceErr.Error())
+}
+
+// reportBug sends unexpected errors to the bug reporter, along with the route
+// that triggered them.
+func (h *handler) reportBug(r *http.Request, err error) {
+	bug := bugs.New(r.Context(), r.Method+" "+r.URL.Path, err)
+	if err := h.bugs.Report(context.WithoutCancel(r.Context()), bug); err != nil {
+		h.logger.Error("error reporting bug", "err", err, "fingerprint", bug.Fingerprint)
+	}
+}
+
+// errorCodeToHTTP maps domain level errors to HTTP status codes.
+var errorCodeToHTTP = map[qerr.ErrorCode]int{
+	qerr.Unknown:            http.StatusInternalServerError,
+	qerr.InvalidInput:       http.StatusBadRequest,
+	qerr.NotFound:           http.StatusNotFound,
+	qerr.Internal:           http.StatusInternalServerError,
+	qerr.AlreadyExists:      http.StatusConflict,
+	qerr.FailedPrecondition: http.StatusBadRequest,
+	qerr.PermissionDenied:   http.StatusForbidden,
+	qerr.Unauthenticated:    http.StatusUnauthorized,
+	qerr.ResourceExhausted:  http.StatusTooManyRequests,
+	qerr.DeadlineExceeded:   http.StatusGatewayTimeout,
+	qerr.Unavailable:        http.StatusServiceUnavailable,
+}
+
+// sortQuestions returns the questions ordered by ID with their options ordered by ID.
+func sortQuestions(qsts map[store.QuestionID]store.Question) []questionView {
+	var questions []questionView
+	for _, q := range qsts {
+		view := questionView{ID: q.ID, Text: q.Text}
+		for _, o := range q.Options {
+			view.Options = append(view.Options, o)
+		}
+		sort.Slice(view.Options, func(i, j int) bool { return view.Options[i].ID < view.Options[j].ID })
+		questions = append(questions, view)
+	}
+	sort.Slice(questions, func(i, j int) bool { return questions[i].ID < questions[j].ID })
+	return questions
+}
+
+func fieldName(qID store.QuestionID) string {
+	return fmt.Sprintf("q%d", qID)
+}
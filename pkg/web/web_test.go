@@ -0,0 +1,300 @@
+package web_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mateopresacastro/qstnnr/pkg/qservice"
+	"github.com/mateopresacastro/qstnnr/pkg/ratelimit"
+	"github.com/mateopresacastro/qstnnr/pkg/reqctx"
+	"github.com/mateopresacastro/qstnnr/pkg/schedule"
+	"github.com/mateopresacastro/qstnnr/pkg/store"
+	"github.com/mateopresacastro/qstnnr/pkg/web"
+)
+
+func TestWeb(t *testing.T) {
+	questions := map[store.QuestionID]store.Question{
+		1: {
+			ID:          1,
+			Text:        "What is the capital of France?",
+			Explanation: "Paris has been the capital of France since 987.",
+			Options: map[store.OptionID]store.Option{
+				1: {ID: 1, Text: "London"},
+				2: {ID: 2, Text: "Paris"},
+			},
+		},
+		2: {
+			ID:   2,
+			Text: "Which planet is known as the Red Planet?",
+			Options: map[store.OptionID]store.Option{
+				1: {ID: 1, Text: "Venus"},
+				2: {ID: 2, Text: "Mars"},
+			},
+		},
+	}
+
+	solutions := map[store.QuestionID]store.OptionID{
+		1: 2, // Paris
+		2: 2, // Mars
+	}
+
+	s, err := store.NewInMemory(store.InitialData{
+		Questions: questions,
+		Solutions: solutions,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler, err := web.New(&web.Config{Logger: slog.Default(), Service: qservice.New(s)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	get := func(t *testing.T, path string) (int, string) {
+		t.Helper()
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, string(body)
+	}
+
+	post := func(t *testing.T, form url.Values) (int, string) {
+		t.Helper()
+		resp, err := http.PostForm(srv.URL+"/quiz", form)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, string(body)
+	}
+
+	t.Run("should list the quiz", func(t *testing.T) {
+		status, body := get(t, "/")
+		if status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", status)
+		}
+		if !strings.Contains(body, `href="/quiz"`) {
+			t.Fatal("expected a link to start the quiz")
+		}
+	})
+
+	t.Run("should render the questions in order", func(t *testing.T) {
+		status, body := get(t, "/quiz")
+		if status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", status)
+		}
+		first := strings.Index(body, "What is the capital of France?")
+		second := strings.Index(body, "Which planet is known as the Red Planet?")
+		if first == -1 || second == -1 || first > second {
+			t.Fatal("expected both questions ordered by ID")
+		}
+		if !strings.Contains(body, `name="q1" value="2"`) {
+			t.Fatal("expected radio inputs for the options")
+		}
+	})
+
+	t.Run("should not use external resources", func(t *testing.T) {
+		_, body := get(t, "/quiz")
+		if strings.Contains(body, "http://") || strings.Contains(body, "https://") {
+			t.Fatal("expected the page to be self contained")
+		}
+	})
+
+	t.Run("should submit answers and render results", func(t *testing.T) {
+		status, body := post(t, url.Values{"q1": {"2"}, "q2": {"1"}})
+		if status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", status)
+		}
+		if !strings.Contains(body, "1 of 2") {
+			t.Error("expected the number of correct answers")
+		}
+		if !strings.Contains(body, "percentile rank is <strong>50</strong>") {
+			t.Error("expected the percentile")
+		}
+		if !strings.Contains(body, "Paris has been the capital of France since 987.") {
+			t.Error("expected the explanation")
+		}
+		if !strings.Contains(body, "Your answer: Venus") {
+			t.Error("expected the wrong answer to be shown")
+		}
+	})
+
+	t.Run("should ask again for missing answers", func(t *testing.T) {
+		status, body := post(t, url.Values{"q1": {"2"}})
+		if status != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d", status)
+		}
+		if !strings.Contains(body, "number of answers (1) must match number of questions (2)") {
+			t.Error("expected the validation message")
+		}
+		if !strings.Contains(body, "<form") {
+			t.Error("expected the quiz form to be rendered again")
+		}
+	})
+
+	t.Run("should reject malformed options", func(t *testing.T) {
+		status, _ := post(t, url.Values{"q1": {"paris"}, "q2": {"2"}})
+		if status != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d", status)
+		}
+	})
+
+	t.Run("should return not found for unknown pages", func(t *testing.T) {
+		status, _ := get(t, "/nope")
+		if status != http.StatusNotFound {
+			t.Fatalf("expected status 404, got %d", status)
+		}
+	})
+
+	t.Run("should share the rate limits of the RPCs", func(t *testing.T) {
+		limiter := ratelimit.New(ratelimit.Limits{"SubmitAnswers": {Rate: 0.001, Burst: 1}})
+		handler, err := web.New(&web.Config{Logger: slog.Default(), Service: qservice.New(s), RateLimiter: limiter})
+		if err != nil {
+			t.Fatal(err)
+		}
+		srv := httptest.NewServer(handler)
+		defer srv.Close()
+
+		// The bucket of the address is shared with the gRPC server.
+		if delay := limiter.Allow("SubmitAnswers", reqctx.Anonymous, "127.0.0.1"); delay != 0 {
+			t.Fatalf("expected the RPC to be allowed, got a delay of %s", delay)
+		}
+		resp, err := http.PostForm(srv.URL+"/quiz", url.Values{"q1": {"2"}, "q2": {"1"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") == "" {
+			t.Errorf("expected status 429 with Retry-After, got %d and %q", resp.StatusCode, resp.Header.Get("Retry-After"))
+		}
+
+		resp, err = http.Get(srv.URL + "/quiz")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("expected the pages without a limit to be served, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("should reject submissions from other sites", func(t *testing.T) {
+		submit := func(t *testing.T, header, value string) int {
+			t.Helper()
+			form := url.Values{"q1": {"2"}, "q2": {"2"}}
+			req, err := http.NewRequest(http.MethodPost, srv.URL+"/quiz", strings.NewReader(form.Encode()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.Header.Set(header, value)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			return resp.StatusCode
+		}
+		for _, tt := range []struct {
+			header, value string
+			want          int
+		}{
+			{"Sec-Fetch-Site", "cross-site", http.StatusForbidden},
+			{"Sec-Fetch-Site", "same-site", http.StatusForbidden},
+			{"Origin", "https://evil.example", http.StatusForbidden},
+			{"Origin", "null", http.StatusForbidden},
+			{"Sec-Fetch-Site", "same-origin", http.StatusOK},
+			{"Origin", srv.URL, http.StatusOK},
+		} {
+			if got := submit(t, tt.header, tt.value); got != tt.want {
+				t.Errorf("expected status %d with %s %s, got %d", tt.want, tt.header, tt.value, got)
+			}
+		}
+	})
+
+	t.Run("should map wrapped service errors", func(t *testing.T) {
+		past := schedule.Event{Name: "september", ClosesAt: time.Now().Add(-time.Hour)}
+		service := &wrappingService{qservice.New(s, qservice.WithSchedule([]schedule.Event{past}))}
+		handler, err := web.New(&web.Config{Logger: slog.Default(), Service: service})
+		if err != nil {
+			t.Fatal(err)
+		}
+		srv := httptest.NewServer(handler)
+		defer srv.Close()
+
+		resp, err := http.Get(srv.URL + "/quiz")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusBadRequest || !strings.Contains(string(body), "the quiz is closed") {
+			t.Errorf("expected status 400 and the quiz closed, got %d and %s", resp.StatusCode, body)
+		}
+	})
+
+	t.Run("should withhold the solutions during an event", func(t *testing.T) {
+		closes := time.Date(2100, 1, 1, 20, 0, 0, 0, time.UTC)
+		event := schedule.Event{Name: "new-year", ClosesAt: closes, Bank: store.InitialData{Questions: questions, Solutions: solutions}}
+		handler, err := web.New(&web.Config{Logger: slog.Default(), Service: qservice.New(s, qservice.WithSchedule([]schedule.Event{event}))})
+		if err != nil {
+			t.Fatal(err)
+		}
+		srv := httptest.NewServer(handler)
+		defer srv.Close()
+
+		resp, err := http.PostForm(srv.URL+"/quiz", url.Values{"q1": {"2"}, "q2": {"1"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(body), "1 of 2") || !strings.Contains(string(body), "January 1, 2100 at 20:00 UTC") {
+			t.Errorf("expected the score and when the solutions are published, got %s", body)
+		}
+		if strings.Contains(string(body), "Paris has been the capital of France since 987.") {
+			t.Error("expected the solutions to be withheld")
+		}
+	})
+}
+
+// wrappingService adds context to the errors of the service.
+type wrappingService struct {
+	qservice.QService
+}
+
+func (s *wrappingService) Questions(ctx context.Context) (map[store.QuestionID]store.Question, error) {
+	qsts, err := s.QService.Questions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing the questions: %w", err)
+	}
+	return qsts, nil
+}
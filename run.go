@@ -6,18 +6,49 @@ import (
 	"io"
 	"log/slog"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/mateopresacastro/qstnnr/pkg/bank"
+	"github.com/mateopresacastro/qstnnr/pkg/bugs"
+	"github.com/mateopresacastro/qstnnr/pkg/cohorts"
+	"github.com/mateopresacastro/qstnnr/pkg/metrics"
 	"github.com/mateopresacastro/qstnnr/pkg/qservice"
+	"github.com/mateopresacastro/qstnnr/pkg/ratelimit"
+	"github.com/mateopresacastro/qstnnr/pkg/schedule"
 	"github.com/mateopresacastro/qstnnr/pkg/server"
 	"github.com/mateopresacastro/qstnnr/pkg/store"
+	"github.com/mateopresacastro/qstnnr/pkg/tracing"
+	"github.com/mateopresacastro/qstnnr/pkg/web"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"google.golang.org/grpc"
 )
 
 const DefaultPort = "5974"
 
+// Version is the version of the server. It is set at build time with
+// -ldflags "-X github.com/mateopresacastro/qstnnr.Version=<version>".
+var Version = "dev"
+
+// shutdownTimeout is how long the servers have to finish the pending requests
+// before they are stopped forcefully.
+const shutdownTimeout = 10 * time.Second
+
+// janitorInterval is how often the data past its retention, such as the
+// expired idempotency keys and idle adaptive quizzes, is deleted from the store.
+const janitorInterval = time.Minute
+
+// defaultRateLimits are the rate limits used when RATE_LIMITS is not set:
+// generous for reading the quiz, stricter for submitting answers and starting
+// adaptive quizzes.
+const defaultRateLimits = "GetQuestions=5:20,GetSolutions=5:20,SaveProgress=2:20,GetProgress=5:20,SubmitAnswers=0.1:5,StartAdaptiveQuiz=0.1:5,AnswerAdaptiveQuestion=2:20,GetStats=2:20,GetLeaderboard=2:20,ListEvents=5:20"
+
 func Run(
 	ctx context.Context,
 	getenv func(string) string,
@@ -26,20 +57,157 @@ func Run(
 	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt)
 	defer cancel()
 
-	data := getInitialData()
-	store, err := store.NewInMemory(data)
+	keyRetention := store.DefaultKeyRetention
+	if v := getenv("IDEMPOTENCY_KEY_RETENTION"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid IDEMPOTENCY_KEY_RETENTION %q: must be a positive duration, e.g. 1h", v)
+		}
+		keyRetention = d
+	}
+	adaptiveRetention := store.DefaultAdaptiveRetention
+	if v := getenv("ADAPTIVE_ATTEMPT_RETENTION"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid ADAPTIVE_ATTEMPT_RETENTION %q: must be a positive duration, e.g. 1h", v)
+		}
+		adaptiveRetention = d
+	}
+
+	data, err := bank.Default()
+	if err != nil {
+		return err
+	}
+	store, err := store.NewInMemory(
+		data,
+		store.WithKeyRetention(keyRetention),
+		store.WithAdaptiveRetention(adaptiveRetention),
+	)
 	if err != nil {
 		return err
 	}
 
-	service := qservice.New(store)
 	logger := slog.New(slog.NewJSONHandler(output, &slog.HandlerOptions{
 		Level: parseLogLevel(getenv("LOG_LEVEL")),
 	}))
 
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Config{
+		ServiceName: "qstnnr-server",
+		Version:     Version,
+		Exporter:    getenv("OTEL_TRACES_EXPORTER"),
+		Endpoint:    getenv("OTEL_EXPORTER_OTLP_ENDPOINT"),
+		// Spans go apart from the JSON logs, so both can be parsed.
+		Output: os.Stderr,
+	})
+	if err != nil {
+		return fmt.Errorf("setting up tracing: %w", err)
+	}
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := shutdownTracing(shutdownCtx); err != nil {
+			fmt.Fprintf(os.Stderr, "error shutting down tracing: %s\n", err)
+		}
+	}()
+
+	// Metrics are optional and only collected when METRICS_PORT is set.
+	var m *metrics.Metrics
+	registry := prometheus.NewRegistry()
+	metricsPort := getenv("METRICS_PORT")
+	if metricsPort != "" {
+		registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+		m, err = metrics.New(registry)
+		if err != nil {
+			return fmt.Errorf("creating metrics: %w", err)
+		}
+	}
+
+	bugReporter, err := newBugReporter(getenv, logger)
+	if err != nil {
+		return fmt.Errorf("creating bug reporter: %w", err)
+	}
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := bugReporter.Close(shutdownCtx); err != nil {
+			fmt.Fprintf(os.Stderr, "error sending the pending bugs: %s\n", err)
+		}
+	}()
+
+	rateLimits := getenv("RATE_LIMITS")
+	if rateLimits == "" {
+		rateLimits = defaultRateLimits
+	}
+	limits, err := ratelimit.Parse(rateLimits)
+	if err != nil {
+		return fmt.Errorf("parsing RATE_LIMITS: %w", err)
+	}
+	// The web UI shares the limiter, so it can't be used to get around it.
+	limiter := ratelimit.New(limits)
+
+	// Submissions per address are unlimited unless DAILY_ATTEMPT_LIMIT is set.
+	dailyAttempts := 0
+	if v := getenv("DAILY_ATTEMPT_LIMIT"); v != "" {
+		dailyAttempts, err = strconv.Atoi(v)
+		if err != nil || dailyAttempts < 0 {
+			return fmt.Errorf("invalid DAILY_ATTEMPT_LIMIT %q: must be a non-negative integer", v)
+		}
+	}
+
+	rankMethod := qservice.MidRank
+	if v := getenv("RANK_METHOD"); v != "" {
+		rankMethod, err = qservice.ParseRankMethod(v)
+		if err != nil {
+			return fmt.Errorf("parsing RANK_METHOD: %w", err)
+		}
+	}
+
+	// ADMIN_TOKENS is a comma separated list of the bearer tokens allowed to
+	// use the admin RPCs, such as GetItemStats.
+	var adminTokens []string
+	for _, t := range strings.Split(getenv("ADMIN_TOKENS"), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			adminTokens = append(adminTokens, t)
+		}
+	}
+
+	// The questionnaire is always open unless QUIZ_SCHEDULE has its events.
+	var events []schedule.Event
+	if path := getenv("QUIZ_SCHEDULE"); path != "" {
+		events, err = schedule.Load(path)
+		if err != nil {
+			return fmt.Errorf("loading QUIZ_SCHEDULE: %w", err)
+		}
+	}
+
+	// Nobody is in a cohort unless COHORTS has the participants, with their
+	// tokens, and their cohorts.
+	roster := &cohorts.Roster{}
+	if path := getenv("COHORTS"); path != "" {
+		roster, err = cohorts.Load(path)
+		if err != nil {
+			return fmt.Errorf("loading COHORTS: %w", err)
+		}
+	}
+
+	service := qservice.New(store,
+		qservice.WithMetrics(m),
+		qservice.WithLogger(logger),
+		qservice.WithDailyAttemptLimit(dailyAttempts),
+		qservice.WithRankMethod(rankMethod),
+		qservice.WithSchedule(events),
+		qservice.WithCohorts(roster.Members),
+	)
+
 	cfg := &server.Config{
-		Logger:  logger,
-		Service: service,
+		Logger:       logger,
+		Service:      service,
+		Version:      Version,
+		Metrics:      m,
+		RateLimiter:  limiter,
+		BugReporter:  bugReporter,
+		AdminTokens:  adminTokens,
+		Participants: roster.Participants,
 	}
 
 	server, err := server.New(cfg)
@@ -70,13 +238,123 @@ func Run(
 		defer wg.Done()
 		<-ctx.Done()
 		logger.Info("shutting down server")
-		server.GracefulStop()
+		stopped := make(chan struct{})
+		go func() {
+			server.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-time.After(shutdownTimeout):
+			// Health watchers keep their streams open, so don't wait for them forever.
+			server.Stop()
+		}
 	}()
 
+	runJanitor(ctx, &wg, logger, store, janitorInterval)
+
+	// The servers are running from here on, so an error stops them before it
+	// is returned.
+	fail := func(err error) error {
+		cancel()
+		wg.Wait()
+		return err
+	}
+
+	// The web UI is optional and only served when WEB_PORT is set.
+	if webPort := getenv("WEB_PORT"); webPort != "" {
+		handler, err := web.New(&web.Config{Logger: logger, Service: service, BugReporter: bugReporter, RateLimiter: limiter})
+		if err != nil {
+			return fail(fmt.Errorf("creating web UI: %w", err))
+		}
+		if err := serveHTTP(ctx, &wg, logger, "web UI", webPort, handler); err != nil {
+			return fail(err)
+		}
+	}
+
+	if metricsPort != "" {
+		if err := serveHTTP(ctx, &wg, logger, "metrics", metricsPort, metrics.Handler(registry)); err != nil {
+			return fail(err)
+		}
+	}
+
 	wg.Wait()
 	return nil
 }
 
+// serveHTTP serves handler on the given port until ctx is done.
+func serveHTTP(
+	ctx context.Context,
+	wg *sync.WaitGroup,
+	logger *slog.Logger,
+	name string,
+	port string,
+	handler http.Handler,
+) error {
+	ln, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		return fmt.Errorf("listening on port %s: %w", port, err)
+	}
+
+	httpServer := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		logger.Info("serving "+name, "port", port)
+		if err := httpServer.Serve(ln); err != nil && err != http.ErrServerClosed {
+			fmt.Fprintf(os.Stderr, "error serving %s: %s\n", name, err)
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			fmt.Fprintf(os.Stderr, "error shutting down %s: %s\n", name, err)
+		}
+	}()
+	return nil
+}
+
+// runJanitor deletes the expired data of the store every interval until ctx is
+// done, so it doesn't slow down the requests.
+func runJanitor(ctx context.Context, wg *sync.WaitGroup, logger *slog.Logger, s store.Store, interval time.Duration) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.DeleteExpired(ctx); err != nil && ctx.Err() == nil {
+					logger.ErrorContext(ctx, "failed to delete expired data", "err", err)
+				}
+			}
+		}
+	}()
+}
+
+// newBugReporter always logs bugs and, when configured, also writes them to
+// BUG_REPORT_FILE and posts them to BUG_REPORT_WEBHOOK_URL.
+func newBugReporter(getenv func(string) string, logger *slog.Logger) (bugs.Multi, error) {
+	reporters := bugs.Multi{bugs.NewLogReporter(logger)}
+	if path := getenv("BUG_REPORT_FILE"); path != "" {
+		file, err := bugs.NewFileReporter(path)
+		if err != nil {
+			return nil, err
+		}
+		reporters = append(reporters, file)
+	}
+	if url := getenv("BUG_REPORT_WEBHOOK_URL"); url != "" {
+		reporters = append(reporters, bugs.NewWebhookReporter(url, logger))
+	}
+	return reporters, nil
+}
+
 func parseLogLevel(level string) slog.Level {
 	switch level {
 	case "DEBUG":
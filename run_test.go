@@ -3,6 +3,7 @@ package qstnnr_test
 import (
 	"bytes"
 	"context"
+	"net"
 	"testing"
 	"time"
 
@@ -77,8 +78,8 @@ func TestE2E(t *testing.T) {
 		if result.Correct != 10 {
 			t.Errorf("expected 10 correct answers, got %d", result.Correct)
 		}
-		if result.BetterThan != 100 {
-			t.Errorf("expected to be better than 100%% of users, got %d%%", result.BetterThan)
+		if result.BetterThan != 50 {
+			t.Errorf("expected the only user to rank 50%%, got %d%%", result.BetterThan)
 		}
 
 		// 3. Submit some wrong answers
@@ -105,11 +106,11 @@ func TestE2E(t *testing.T) {
 		if result.Correct != 4 {
 			t.Errorf("expected 4 correct answers, got %d", result.Correct)
 		}
-		if result.BetterThan != 0 {
-			t.Errorf("expected to be better than 0%% of users, got %d%%", result.BetterThan)
+		if result.BetterThan != 25 {
+			t.Errorf("expected the lowest of 2 users to rank 25%%, got %d%%", result.BetterThan)
 		}
 
-		solutions, err := client.GetSolutions(ctx, &emptypb.Empty{})
+		solutions, err := client.GetSolutions(ctx, &api.GetSolutionsRequest{})
 		if err != nil {
 			t.Fatalf("failed to get solutions: %v", err)
 		}
@@ -163,3 +164,35 @@ func TestE2E(t *testing.T) {
 		t.Fatalf("server error: %v", err)
 	}
 }
+
+func TestRunStopsOnError(t *testing.T) {
+	// Taken, so serving the metrics fails after the gRPC server started.
+	taken, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer taken.Close()
+	_, metricsPort, _ := net.SplitHostPort(taken.Addr().String())
+
+	port := "4001"
+	getenv := func(key string) string {
+		switch key {
+		case "PORT":
+			return port
+		case "METRICS_PORT":
+			return metricsPort
+		}
+		return ""
+	}
+	var buf bytes.Buffer
+	if err := qstnnr.Run(context.Background(), getenv, &buf); err == nil {
+		t.Fatal("expected an error serving the metrics")
+	}
+
+	// The gRPC server was stopped, so its port is free again.
+	ln, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		t.Fatalf("expected the gRPC server to be stopped: %v", err)
+	}
+	ln.Close()
+}